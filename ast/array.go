@@ -17,6 +17,10 @@ func (al *ArrayLiteral) TokenLiteral() string {
 	return al.Token.Literal
 }
 
+func (al *ArrayLiteral) Pos() token.Position {
+	return al.Token.Pos
+}
+
 func (al *ArrayLiteral) String() string {
 	var out bytes.Buffer
 
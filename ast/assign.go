@@ -0,0 +1,44 @@
+package ast
+
+import (
+	"bytes"
+	"crabscript.rs/token"
+)
+
+// AssignExpression rebinds an existing name or sets an element of an
+// array or dict, with an optional compound operator such as `+=`
+type AssignExpression struct {
+	Token    token.Token // the assignment operator token
+	Target   Expression  // *Identifier or *IndexExpression
+	Operator string      // "=" or a compound operator such as "+=" or "<<="
+	Value    Expression
+}
+
+func (ae *AssignExpression) expressionNode() {}
+
+func (ae *AssignExpression) TokenLiteral() string {
+	return ae.Token.Literal
+}
+
+func (ae *AssignExpression) Pos() token.Position {
+	return ae.Token.Pos
+}
+
+// BinaryOperator returns the infix operator applied by a compound
+// assignment, or "" for plain assignment
+func (ae *AssignExpression) BinaryOperator() string {
+	if ae.Operator == "=" {
+		return ""
+	}
+	return ae.Operator[:len(ae.Operator)-1]
+}
+
+func (ae *AssignExpression) String() string {
+	var out bytes.Buffer
+	out.WriteString("(")
+	out.WriteString(ae.Target.String())
+	out.WriteString(" " + ae.Operator + " ")
+	out.WriteString(ae.Value.String())
+	out.WriteString(")")
+	return out.String()
+}
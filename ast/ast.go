@@ -1,7 +1,10 @@
 package ast
 
+import "crabscript.rs/token"
+
 type Node interface {
 	TokenLiteral() string
+	Pos() token.Position // position of the node's first token
 	String() string
 }
 
@@ -25,3 +25,51 @@ func TestString(t *testing.T) {
 		t.Errorf("program.String() wrong. got=%q", program.String())
 	}
 }
+
+func TestValidate(t *testing.T) {
+	pos := token.Position{Line: 2, Column: 3}
+	ident := &Identifier{Token: token.Token{Type: token.Ident, Literal: "a", Pos: pos}, Value: "a"}
+
+	tests := []struct {
+		node     Node
+		expected string
+	}{
+		{&Program{Statements: []Statement{&ExpressionStatement{Expression: ident}}}, ""},
+		{&ReturnStatement{}, ""},
+		{&IfExpression{Condition: ident, Consequence: &BlockStatement{}}, ""},
+		{&SliceExpression{Left: ident}, ""},
+		{&SliceExpression{Token: token.Token{Pos: pos}, Left: ident, End: &BadExpression{Token: token.Token{Pos: pos}}}, "2:3: bad expression"},
+		{&LetStatement{Pattern: &ArrayPattern{Elements: []*Identifier{ident}, Rest: ident}, Value: ident}, ""},
+		{&LetStatement{Token: token.Token{Pos: pos}, Pattern: &DictPattern{Token: token.Token{Pos: pos}, Keys: []*Identifier{nil}}, Value: ident}, "2:3: missing node in *ast.DictPattern"},
+		{&MatchExpression{Subject: ident, Arms: []*MatchArm{{Pattern: &ArrayShape{Elements: []Expression{ident}, Rest: ident}, Guard: ident, Body: ident}}}, ""},
+		{&MatchExpression{Token: token.Token{Pos: pos}, Subject: ident, Arms: []*MatchArm{nil}}, "2:3: missing arm in *ast.MatchExpression"},
+		{&DictShape{Token: token.Token{Pos: pos}, Keys: []string{"a"}}, "2:3: keys and values differ in *ast.DictShape"},
+		{&TryExpression{Body: &BlockStatement{}, Param: ident, Catch: &BlockStatement{}}, ""},
+		{&TryExpression{Token: token.Token{Pos: pos}, Body: &BlockStatement{}}, "2:3: missing catch or finally in *ast.TryExpression"},
+		{&TryExpression{Token: token.Token{Pos: pos}, Body: &BlockStatement{}, Catch: &BlockStatement{}}, "2:3: missing node in *ast.TryExpression"},
+		{&ThrowStatement{Token: token.Token{Pos: pos}}, "2:3: missing node in *ast.ThrowStatement"},
+		{&ImportStatement{Path: "lib.crab", Name: ident}, ""},
+		{&ImportStatement{Token: token.Token{Pos: pos}, Path: "lib.crab"}, "2:3: missing node in *ast.ImportStatement"},
+		{&MemberExpression{Token: token.Token{Pos: pos}, Left: ident}, "2:3: missing node in *ast.MemberExpression"},
+		{nil, "missing node"},
+		{&BadStatement{Token: token.Token{Pos: pos}}, "2:3: bad statement"},
+		{&InfixExpression{Token: token.Token{Pos: pos}, Left: ident}, "2:3: missing node in *ast.InfixExpression"},
+		{&LetStatement{Name: ident, Value: (*Identifier)(nil)}, "missing node in *ast.LetStatement"},
+		{&Program{Statements: []Statement{&ExpressionStatement{
+			Expression: &CallExpression{Function: ident, Arguments: []Expression{&BadExpression{Token: token.Token{Pos: pos}}}},
+		}}}, "2:3: bad expression"},
+	}
+
+	for _, tt := range tests {
+		err := Validate(tt.node)
+		if tt.expected == "" {
+			if err != nil {
+				t.Errorf("unexpected error for %v: %s", tt.node, err)
+			}
+			continue
+		}
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %#v. want %q, got %v", tt.node, tt.expected, err)
+		}
+	}
+}
@@ -0,0 +1,41 @@
+package ast
+
+import "crabscript.rs/token"
+
+// BadExpression stands in for an expression that failed to parse
+type BadExpression struct {
+	Token token.Token // first token of the expression
+}
+
+func (be *BadExpression) expressionNode() {}
+
+func (be *BadExpression) TokenLiteral() string {
+	return be.Token.Literal
+}
+
+func (be *BadExpression) Pos() token.Position {
+	return be.Token.Pos
+}
+
+func (be *BadExpression) String() string {
+	return "<bad expression>"
+}
+
+// BadStatement stands in for a statement that failed to parse
+type BadStatement struct {
+	Token token.Token // first token of the statement
+}
+
+func (bs *BadStatement) statementNode() {}
+
+func (bs *BadStatement) TokenLiteral() string {
+	return bs.Token.Literal
+}
+
+func (bs *BadStatement) Pos() token.Position {
+	return bs.Token.Pos
+}
+
+func (bs *BadStatement) String() string {
+	return "<bad statement>"
+}
@@ -8,9 +8,11 @@ import (
 )
 
 type CallExpression struct {
-	Token     token.Token // "(" token
+	Token     token.Token // "(" token, or "|>" when piped without parens
 	Function  Expression
 	Arguments []Expression
+	Named     []*NamedArgument // `name: value` arguments following the positional ones
+	Piped     bool             // written as `x |> f(a)`, with x as the first argument
 }
 
 func (ce *CallExpression) expressionNode() {}
@@ -19,6 +21,10 @@ func (ce *CallExpression) TokenLiteral() string {
 	return ce.Token.Literal
 }
 
+func (ce *CallExpression) Pos() token.Position {
+	return ce.Token.Pos
+}
+
 func (ce *CallExpression) String() string {
 	var out bytes.Buffer
 
@@ -27,11 +33,45 @@ func (ce *CallExpression) String() string {
 	for _, a := range ce.Arguments {
 		args = append(args, a.String())
 	}
+	for _, a := range ce.Named {
+		args = append(args, a.String())
+	}
+
+	if ce.Piped && len(args) > 0 {
+		out.WriteString("(" + args[0] + " |> ")
+		args = args[1:]
+	}
 
 	out.WriteString(ce.Function.String())
 	out.WriteString("(")
 	out.WriteString(strings.Join(args, ", "))
 	out.WriteString(")")
 
+	if ce.Piped {
+		out.WriteString(")")
+	}
+
 	return out.String()
 }
+
+// NamedArgument is a `name: value` argument of a call, bound to the
+// parameter of the same name
+type NamedArgument struct {
+	Token token.Token // the name's token
+	Name  *Identifier
+	Value Expression
+}
+
+func (na *NamedArgument) expressionNode() {}
+
+func (na *NamedArgument) TokenLiteral() string {
+	return na.Token.Literal
+}
+
+func (na *NamedArgument) Pos() token.Position {
+	return na.Token.Pos
+}
+
+func (na *NamedArgument) String() string {
+	return na.Name.String() + ": " + na.Value.String()
+}
@@ -17,6 +17,10 @@ func (dl *DictLiteral) TokenLiteral() string {
 	return dl.Token.Literal
 }
 
+func (dl *DictLiteral) Pos() token.Position {
+	return dl.Token.Pos
+}
+
 func (dl *DictLiteral) String() string {
 	var out bytes.Buffer
 
@@ -0,0 +1,37 @@
+package ast
+
+import (
+	"bytes"
+	"crabscript.rs/token"
+)
+
+// ForInStatement binds Variable to each element of Iterable in turn
+type ForInStatement struct {
+	Token    token.Token
+	Variable *Identifier
+	Iterable Expression
+	Body     *BlockStatement
+}
+
+func (fs *ForInStatement) statementNode() {}
+
+func (fs *ForInStatement) TokenLiteral() string {
+	return fs.Token.Literal
+}
+
+func (fs *ForInStatement) Pos() token.Position {
+	return fs.Token.Pos
+}
+
+func (fs *ForInStatement) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("for (")
+	out.WriteString(fs.Variable.String())
+	out.WriteString(" in ")
+	out.WriteString(fs.Iterable.String())
+	out.WriteString(") ")
+	out.WriteString(fs.Body.String())
+
+	return out.String()
+}
@@ -9,7 +9,18 @@ import (
 type FunctionLiteral struct {
 	Token      token.Token
 	Parameters []*Identifier
+	Defaults   []Expression // default of each parameter, nil where there is none
+	Rest       *Identifier  // collects extra arguments into an array, if any
 	Body       *BlockStatement
+	Name       string // name the fn is bound to by a let statement, if any
+}
+
+// Default returns the default value of the i-th parameter, or nil
+func (fl *FunctionLiteral) Default(i int) Expression {
+	if i < len(fl.Defaults) {
+		return fl.Defaults[i]
+	}
+	return nil
 }
 
 func (fl *FunctionLiteral) expressionNode() {}
@@ -18,13 +29,24 @@ func (fl *FunctionLiteral) TokenLiteral() string {
 	return fl.Token.Literal
 }
 
+func (fl *FunctionLiteral) Pos() token.Position {
+	return fl.Token.Pos
+}
+
 func (fl *FunctionLiteral) String() string {
 	var out bytes.Buffer
 
 	// getting params as strings
 	params := []string{}
-	for _, p := range fl.Parameters {
-		params = append(params, p.String())
+	for i, p := range fl.Parameters {
+		if def := fl.Default(i); def != nil {
+			params = append(params, p.String()+" = "+def.String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if fl.Rest != nil {
+		params = append(params, "..."+fl.Rest.String())
 	}
 
 	out.WriteString(fl.TokenLiteral())
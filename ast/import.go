@@ -0,0 +1,50 @@
+package ast
+
+import (
+	"strconv"
+
+	"crabscript.rs/token"
+)
+
+// ImportStatement binds the module at Path to Name, `import "lib.crab" as m`,
+// whose exports are then read as `m.name`
+type ImportStatement struct {
+	Token token.Token // the 'import' token
+	Path  string
+	Name  *Identifier
+}
+
+func (is *ImportStatement) statementNode() {}
+
+func (is *ImportStatement) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *ImportStatement) Pos() token.Position {
+	return is.Token.Pos
+}
+
+func (is *ImportStatement) String() string {
+	return "import " + strconv.Quote(is.Path) + " as " + is.Name.String() + ";"
+}
+
+// MemberExpression reads the export Name of the module bound to Left
+type MemberExpression struct {
+	Token token.Token // the '.' token
+	Left  Expression
+	Name  *Identifier
+}
+
+func (me *MemberExpression) expressionNode() {}
+
+func (me *MemberExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MemberExpression) Pos() token.Position {
+	return me.Token.Pos
+}
+
+func (me *MemberExpression) String() string {
+	return "(" + me.Left.String() + "." + me.Name.String() + ")"
+}
@@ -0,0 +1,38 @@
+package ast
+
+import (
+	"bytes"
+
+	"crabscript.rs/token"
+)
+
+// InterpolatedString is a string literal with embedded expressions such as
+// "user ${name} has ${len(items)} items"
+type InterpolatedString struct {
+	Token token.Token  // the first StringPart token
+	Parts []Expression // *StringLiteral for the text around the expressions
+}
+
+func (is *InterpolatedString) expressionNode() {}
+
+func (is *InterpolatedString) TokenLiteral() string {
+	return is.Token.Literal
+}
+
+func (is *InterpolatedString) Pos() token.Position {
+	return is.Token.Pos
+}
+
+func (is *InterpolatedString) String() string {
+	var out bytes.Buffer
+
+	for _, part := range is.Parts {
+		if text, ok := part.(*StringLiteral); ok {
+			out.WriteString(text.Value)
+		} else {
+			out.WriteString("${" + part.String() + "}")
+		}
+	}
+
+	return out.String()
+}
@@ -6,9 +6,11 @@ import (
 )
 
 type LetStatement struct {
-	Token token.Token
-	Name  *Identifier
-	Value Expression
+	Token   token.Token
+	Name    *Identifier
+	Pattern Expression // *ArrayPattern or *DictPattern in place of Name when destructuring
+	Value   Expression
+	Export  bool // `export let`, readable by importers of the module
 }
 
 func (ls *LetStatement) statementNode() {}
@@ -17,11 +19,22 @@ func (ls *LetStatement) TokenLiteral() string {
 	return ls.Token.Literal
 }
 
+func (ls *LetStatement) Pos() token.Position {
+	return ls.Token.Pos
+}
+
 func (ls *LetStatement) String() string {
 	var out bytes.Buffer
 
+	if ls.Export {
+		out.WriteString("export ")
+	}
 	out.WriteString(ls.TokenLiteral() + " ")
-	out.WriteString(ls.Name.String())
+	if ls.Pattern != nil {
+		out.WriteString(ls.Pattern.String())
+	} else {
+		out.WriteString(ls.Name.String())
+	}
 	out.WriteString(" = ")
 
 	if ls.Value != nil {
@@ -29,4 +42,19 @@ func (ls *LetStatement) String() string {
 	}
 	out.WriteString(";")
 	return out.String()
-}
\ No newline at end of file
+}
+
+// Names returns the identifiers bound by the statement
+func (ls *LetStatement) Names() []*Identifier {
+	switch pattern := ls.Pattern.(type) {
+	case *ArrayPattern:
+		names := append([]*Identifier{}, pattern.Elements...)
+		if pattern.Rest != nil {
+			names = append(names, pattern.Rest)
+		}
+		return names
+	case *DictPattern:
+		return pattern.Keys
+	}
+	return []*Identifier{ls.Name}
+}
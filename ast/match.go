@@ -0,0 +1,136 @@
+package ast
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+
+	"crabscript.rs/token"
+)
+
+// MatchExpression is `match (subject) { pattern if guard => body, ... }`,
+// which evaluates to the body of the first arm whose pattern matches
+type MatchExpression struct {
+	Token   token.Token // "match" token
+	Subject Expression
+	Arms    []*MatchArm
+}
+
+// MatchArm is one `pattern if guard => body` of a match, the guard is nil
+// when there is none. Patterns are literals, identifiers binding the value,
+// `_` matching anything, and array and dict shapes.
+type MatchArm struct {
+	Pattern Expression
+	Guard   Expression
+	Body    Expression
+}
+
+func (me *MatchExpression) expressionNode() {}
+
+func (me *MatchExpression) TokenLiteral() string {
+	return me.Token.Literal
+}
+
+func (me *MatchExpression) Pos() token.Position {
+	return me.Token.Pos
+}
+
+func (me *MatchExpression) String() string {
+	var out bytes.Buffer
+
+	arms := []string{}
+	for _, arm := range me.Arms {
+		arms = append(arms, arm.String())
+	}
+
+	out.WriteString("match (")
+	out.WriteString(me.Subject.String())
+	out.WriteString(") { ")
+	out.WriteString(strings.Join(arms, ", "))
+	out.WriteString(" }")
+
+	return out.String()
+}
+
+func (ma *MatchArm) String() string {
+	var out bytes.Buffer
+
+	out.WriteString(ma.Pattern.String())
+	if ma.Guard != nil {
+		out.WriteString(" if ")
+		out.WriteString(ma.Guard.String())
+	}
+	out.WriteString(" => ")
+	out.WriteString(ma.Body.String())
+
+	return out.String()
+}
+
+// ArrayShape matches arrays of as many elements as it has patterns, or of
+// at least as many when there is a Rest to bind the remaining ones to
+type ArrayShape struct {
+	Token    token.Token // "[" token
+	Elements []Expression
+	Rest     *Identifier
+}
+
+func (as *ArrayShape) expressionNode() {}
+
+func (as *ArrayShape) TokenLiteral() string {
+	return as.Token.Literal
+}
+
+func (as *ArrayShape) Pos() token.Position {
+	return as.Token.Pos
+}
+
+func (as *ArrayShape) String() string {
+	var out bytes.Buffer
+
+	elements := []string{}
+	for _, el := range as.Elements {
+		elements = append(elements, el.String())
+	}
+	if as.Rest != nil {
+		elements = append(elements, "..."+as.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(elements, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// DictShape matches dicts holding each of its string keys, with a value
+// matching the pattern for the key. Other keys are ignored.
+type DictShape struct {
+	Token  token.Token // "{" token
+	Keys   []string
+	Values []Expression
+}
+
+func (ds *DictShape) expressionNode() {}
+
+func (ds *DictShape) TokenLiteral() string {
+	return ds.Token.Literal
+}
+
+func (ds *DictShape) Pos() token.Position {
+	return ds.Token.Pos
+}
+
+func (ds *DictShape) String() string {
+	var out bytes.Buffer
+
+	pairs := []string{}
+	for i, key := range ds.Keys {
+		pairs = append(pairs, fmt.Sprintf("%q: %s", key, ds.Values[i]))
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(pairs, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -0,0 +1,76 @@
+package ast
+
+import (
+	"bytes"
+	"strings"
+
+	"crabscript.rs/token"
+)
+
+// ArrayPattern destructures an array in `let [a, b, ...rest] = xs`, binding
+// the remaining elements to Rest when it is given
+type ArrayPattern struct {
+	Token    token.Token // "[" token
+	Elements []*Identifier
+	Rest     *Identifier
+}
+
+func (ap *ArrayPattern) expressionNode() {}
+
+func (ap *ArrayPattern) TokenLiteral() string {
+	return ap.Token.Literal
+}
+
+func (ap *ArrayPattern) Pos() token.Position {
+	return ap.Token.Pos
+}
+
+func (ap *ArrayPattern) String() string {
+	var out bytes.Buffer
+
+	names := []string{}
+	for _, el := range ap.Elements {
+		names = append(names, el.String())
+	}
+	if ap.Rest != nil {
+		names = append(names, "..."+ap.Rest.String())
+	}
+
+	out.WriteString("[")
+	out.WriteString(strings.Join(names, ", "))
+	out.WriteString("]")
+
+	return out.String()
+}
+
+// DictPattern destructures a dict in `let {name, age} = person`, binding
+// each name to the value under the string key of the same name
+type DictPattern struct {
+	Token token.Token // "{" token
+	Keys  []*Identifier
+}
+
+func (dp *DictPattern) expressionNode() {}
+
+func (dp *DictPattern) TokenLiteral() string {
+	return dp.Token.Literal
+}
+
+func (dp *DictPattern) Pos() token.Position {
+	return dp.Token.Pos
+}
+
+func (dp *DictPattern) String() string {
+	var out bytes.Buffer
+
+	keys := []string{}
+	for _, key := range dp.Keys {
+		keys = append(keys, key.String())
+	}
+
+	out.WriteString("{")
+	out.WriteString(strings.Join(keys, ", "))
+	out.WriteString("}")
+
+	return out.String()
+}
@@ -2,6 +2,8 @@ package ast
 
 import (
 	"bytes"
+
+	"crabscript.rs/token"
 )
 
 type Program struct {
@@ -16,6 +18,13 @@ func (p *Program) TokenLiteral() string {
 	}
 }
 
+func (p *Program) Pos() token.Position {
+	if len(p.Statements) > 0 {
+		return p.Statements[0].Pos()
+	}
+	return token.Position{}
+}
+
 func (p *Program) String() string {
 	var out bytes.Buffer
 
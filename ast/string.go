@@ -13,6 +13,10 @@ func (sl *StringLiteral) TokenLiteral() string {
   return sl.Token.Literal
 }
 
+func (sl *StringLiteral) Pos() token.Position {
+  return sl.Token.Pos
+}
+
 func (sl *StringLiteral) String() string {
   return sl.Token.Literal
 }
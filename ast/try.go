@@ -0,0 +1,68 @@
+package ast
+
+import (
+	"bytes"
+
+	"crabscript.rs/token"
+)
+
+// TryExpression runs Body, handing an error raised in it to Catch and then
+// running Finally however the two ended. At least one of Catch and Finally
+// is set.
+type TryExpression struct {
+	Token   token.Token
+	Body    *BlockStatement
+	Param   *Identifier     // bound to the caught error, set with Catch
+	Catch   *BlockStatement // body of 'catch', if any
+	Finally *BlockStatement // body of 'finally', if any
+}
+
+func (te *TryExpression) expressionNode() {}
+
+func (te *TryExpression) TokenLiteral() string {
+	return te.Token.Literal
+}
+
+func (te *TryExpression) Pos() token.Position {
+	return te.Token.Pos
+}
+
+func (te *TryExpression) String() string {
+	var out bytes.Buffer
+
+	out.WriteString("try { ")
+	out.WriteString(te.Body.String())
+	out.WriteString(" }")
+
+	if te.Catch != nil {
+		out.WriteString(" catch (" + te.Param.String() + ") { ")
+		out.WriteString(te.Catch.String())
+		out.WriteString(" }")
+	}
+	if te.Finally != nil {
+		out.WriteString(" finally { ")
+		out.WriteString(te.Finally.String())
+		out.WriteString(" }")
+	}
+	return out.String()
+}
+
+// ThrowStatement raises its value as an error
+type ThrowStatement struct {
+	Token token.Token
+	Value Expression
+}
+
+func (ts *ThrowStatement) statementNode() {}
+
+func (ts *ThrowStatement) TokenLiteral() string {
+	return ts.Token.Literal
+}
+
+func (ts *ThrowStatement) Pos() token.Position {
+	return ts.Token.Pos
+}
+
+func (ts *ThrowStatement) String() string {
+	return ts.TokenLiteral() + " " + ts.Value.String() + ";"
+}
@@ -0,0 +1,242 @@
+package ast
+
+import (
+	"fmt"
+	"reflect"
+
+	"crabscript.rs/token"
+)
+
+// BadNodeError reports a node that failed to parse or is missing from the
+// tree, which the engines refuse to run
+type BadNodeError struct {
+	Pos token.Position // position of the bad node, or of its parent if missing
+	Msg string
+}
+
+func (e *BadNodeError) Error() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+	}
+	return e.Msg
+}
+
+// Validate returns the first bad or missing node in the tree rooted at node,
+// or nil when the tree is well-formed
+func Validate(node Node) error {
+	if isNil(node) {
+		return &BadNodeError{Msg: "missing node"}
+	}
+
+	switch node := node.(type) {
+	case *BadStatement:
+		return &BadNodeError{Pos: node.Pos(), Msg: "bad statement"}
+	case *BadExpression:
+		return &BadNodeError{Pos: node.Pos(), Msg: "bad expression"}
+
+	case *Program:
+		for _, stmt := range node.Statements {
+			if err := validateChild(node, stmt); err != nil {
+				return err
+			}
+		}
+	case *BlockStatement:
+		for _, stmt := range node.Statements {
+			if err := validateChild(node, stmt); err != nil {
+				return err
+			}
+		}
+	case *ExpressionStatement:
+		return validateChild(node, node.Expression)
+	case *LetStatement:
+		if node.Pattern != nil {
+			return validateChildren(node, node.Pattern, node.Value)
+		}
+		return validateChildren(node, node.Name, node.Value)
+	case *ReturnStatement:
+		if node.ReturnValue != nil {
+			return validateChild(node, node.ReturnValue)
+		}
+	case *ThrowStatement:
+		return validateChild(node, node.Value)
+	case *ImportStatement:
+		return validateChild(node, node.Name)
+	case *WhileStatement:
+		return validateChildren(node, node.Condition, node.Body)
+	case *ForInStatement:
+		return validateChildren(node, node.Variable, node.Iterable, node.Body)
+
+	case *PrefixExpression:
+		return validateChild(node, node.Right)
+	case *InfixExpression:
+		return validateChildren(node, node.Left, node.Right)
+	case *AssignExpression:
+		return validateChildren(node, node.Target, node.Value)
+	case *MemberExpression:
+		return validateChildren(node, node.Left, node.Name)
+	case *IfExpression:
+		if err := validateChildren(node, node.Condition, node.Consequence); err != nil {
+			return err
+		}
+		if node.Alternative != nil {
+			return validateChild(node, node.Alternative)
+		}
+	case *TryExpression:
+		if err := validateChild(node, node.Body); err != nil {
+			return err
+		}
+		if node.Catch == nil && node.Finally == nil {
+			return &BadNodeError{Pos: node.Pos(), Msg: "missing catch or finally in *ast.TryExpression"}
+		}
+		if node.Catch != nil {
+			if err := validateChildren(node, node.Param, node.Catch); err != nil {
+				return err
+			}
+		}
+		if node.Finally != nil {
+			return validateChild(node, node.Finally)
+		}
+	case *FunctionLiteral:
+		for i, param := range node.Parameters {
+			if err := validateChild(node, param); err != nil {
+				return err
+			}
+			if def := node.Default(i); def != nil {
+				if err := validateChild(node, def); err != nil {
+					return err
+				}
+			}
+		}
+		if node.Rest != nil {
+			if err := validateChild(node, node.Rest); err != nil {
+				return err
+			}
+		}
+		return validateChild(node, node.Body)
+	case *CallExpression:
+		if err := validateChild(node, node.Function); err != nil {
+			return err
+		}
+		for _, arg := range node.Arguments {
+			if err := validateChild(node, arg); err != nil {
+				return err
+			}
+		}
+		for _, arg := range node.Named {
+			if err := validateChild(node, arg); err != nil {
+				return err
+			}
+		}
+	case *NamedArgument:
+		return validateChildren(node, node.Name, node.Value)
+	case *IndexExpression:
+		return validateChildren(node, node.Left, node.Index)
+	case *SliceExpression:
+		if err := validateChild(node, node.Left); err != nil {
+			return err
+		}
+		for _, bound := range []Expression{node.Start, node.End, node.Step} {
+			if bound != nil {
+				if err := validateChild(node, bound); err != nil {
+					return err
+				}
+			}
+		}
+	case *ArrayPattern:
+		for _, el := range node.Elements {
+			if err := validateChild(node, el); err != nil {
+				return err
+			}
+		}
+		if node.Rest != nil {
+			return validateChild(node, node.Rest)
+		}
+	case *DictPattern:
+		for _, key := range node.Keys {
+			if err := validateChild(node, key); err != nil {
+				return err
+			}
+		}
+	case *MatchExpression:
+		if err := validateChild(node, node.Subject); err != nil {
+			return err
+		}
+		for _, arm := range node.Arms {
+			if arm == nil {
+				return &BadNodeError{Pos: node.Pos(), Msg: "missing arm in *ast.MatchExpression"}
+			}
+			if err := validateChildren(node, arm.Pattern, arm.Body); err != nil {
+				return err
+			}
+			if arm.Guard != nil {
+				if err := validateChild(node, arm.Guard); err != nil {
+					return err
+				}
+			}
+		}
+	case *ArrayShape:
+		for _, el := range node.Elements {
+			if err := validateChild(node, el); err != nil {
+				return err
+			}
+		}
+		if node.Rest != nil {
+			return validateChild(node, node.Rest)
+		}
+	case *DictShape:
+		if len(node.Keys) != len(node.Values) {
+			return &BadNodeError{Pos: node.Pos(), Msg: "keys and values differ in *ast.DictShape"}
+		}
+		for _, value := range node.Values {
+			if err := validateChild(node, value); err != nil {
+				return err
+			}
+		}
+	case *InterpolatedString:
+		for _, part := range node.Parts {
+			if err := validateChild(node, part); err != nil {
+				return err
+			}
+		}
+	case *ArrayLiteral:
+		for _, el := range node.Elements {
+			if err := validateChild(node, el); err != nil {
+				return err
+			}
+		}
+	case *DictLiteral:
+		for key, value := range node.Pairs {
+			if err := validateChildren(node, key, value); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func validateChildren(parent Node, children ...Node) error {
+	for _, child := range children {
+		if err := validateChild(parent, child); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// missing children are reported at the position of their parent
+func validateChild(parent Node, child Node) error {
+	if isNil(child) {
+		return &BadNodeError{Pos: parent.Pos(), Msg: fmt.Sprintf("missing node in %T", parent)}
+	}
+	return Validate(child)
+}
+
+// reports whether node is nil, including nil pointers to nodes
+func isNil(node Node) bool {
+	if node == nil {
+		return true
+	}
+	v := reflect.ValueOf(node)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
@@ -22,6 +22,8 @@ replace crabscript.rs/code => ../code
 
 replace crabscript.rs/vm => ../vm
 
+replace crabscript.rs/module => ../module
+
 require (
 	crabscript.rs/compiler v0.0.0-00010101000000-000000000000
 	crabscript.rs/evaluator v0.0.0-00010101000000-000000000000
@@ -34,5 +36,6 @@ require (
 require (
 	crabscript.rs/ast v0.0.0-00010101000000-000000000000 // indirect
 	crabscript.rs/code v0.0.0-00010101000000-000000000000 // indirect
+	crabscript.rs/module v0.0.0-00010101000000-000000000000 // indirect
 	crabscript.rs/token v0.0.0-00010101000000-000000000000 // indirect
 )
@@ -43,6 +43,38 @@ const (
 	OpGetBIn                // getting built in fns
 	OpClosure               // anonymous functions
 	OpGetFree               // getting variables from closures
+	OpCurCsr                // push the executing closure, for self reference
+	OpSetFree               // assign to variables captured by closures
+	OpBoxLcl                // box a local in a cell for capture by a closure
+	OpBoxFree               // push the cell of a free variable for capture
+	OpSetIdx                // assign to an index or subscript
+	OpDup                   // duplicate values at the top of the stack
+	OpIter                  // replace an iterable with an iterator over it
+	OpNext                  // push the next element or jump when exhausted
+	OpMod                   // remainder of the topmost 2 elem of stack
+	OpPow                   // raise to the power of the topmost elem of stack
+	OpBitAnd                // bitwise and of the topmost 2 elem of stack
+	OpBitOr                 // bitwise or of the topmost 2 elem of stack
+	OpBitXor                // bitwise xor of the topmost 2 elem of stack
+	OpShl                   // shift left by the topmost elem of stack
+	OpShr                   // shift right by the topmost elem of stack
+	OpLt                    // less than comparator
+	OpLe                    // less than or equal comparator
+	OpGe                    // greater than or equal comparator
+	OpConcat                // join the display forms of values into a string
+	OpSlice                 // slice an array or string
+	OpUnpkArr               // replace an array with its first n elements
+	OpUnpkDct               // replace a dict and n keys with their values
+	OpCallKw                // call fn with named arguments
+	OpJmpSet                // jump when a param was given
+	OpMatchEq               // value equality for match patterns
+	OpIsArr                 // test an array's length for a match pattern
+	OpHasKeys               // test a dict's keys for a match pattern
+	OpNoMatch               // error when no match arm applies
+	OpTry                   // enter a try, marking the stack to unwind to
+	OpThrow                 // raise the value on top of the stack
+	OpEndFin                // end of a finally block
+	OpGetExp                // read an export of a module
 )
 
 // Definition - debugging info and humand readable opcode for the operation
@@ -84,6 +116,53 @@ var definitions = map[Opcode]*Definition{
 	//be moved with the closure
 	OpClosure: {"OpClosure", []int{2, 1}},
 	OpGetFree: {"OpGetFree", []int{1}}, // getting variables from closures
+	OpCurCsr:  {"OpCurCsr", []int{}},   // push the executing closure, for self reference
+	OpSetFree: {"OpSetFree", []int{1}}, // assign to variables captured by closures
+	OpBoxLcl:  {"OpBoxLcl", []int{2}},  // box a local in a cell for capture by a closure
+	OpBoxFree: {"OpBoxFree", []int{1}}, // push the cell of a free variable for capture
+	OpSetIdx:  {"OpSetIdx", []int{}},   // assign to an index, pushes the assigned value
+	OpDup:     {"OpDup", []int{1}},     // duplicate the top n values of the stack
+	OpIter:    {"OpIter", []int{}},     // replace an iterable with an iterator over it
+	OpNext:    {"OpNext", []int{2}},    // push the next element of the iterator or jump when exhausted
+	OpMod:     {"OpMod", []int{}},      // remainder of the topmost 2 elem of stack
+	OpPow:     {"OpPow", []int{}},      // raise to the power of the topmost elem of stack
+	OpBitAnd:  {"OpBitAnd", []int{}},   // bitwise and of the topmost 2 elem of stack
+	OpBitOr:   {"OpBitOr", []int{}},    // bitwise or of the topmost 2 elem of stack
+	OpBitXor:  {"OpBitXor", []int{}},   // bitwise xor of the topmost 2 elem of stack
+	OpShl:     {"OpShl", []int{}},      // shift left by the topmost elem of stack
+	OpShr:     {"OpShr", []int{}},      // arithmetic shift right by the topmost elem of stack
+	OpLt:      {"OpLt", []int{}},       // less than comparator
+	OpLe:      {"OpLe", []int{}},       // less than or equal comparator
+	OpGe:      {"OpGe", []int{}},       // greater than or equal comparator
+	OpConcat:  {"OpConcat", []int{2}},  // join the display forms of the top n values into a string
+	OpSlice:   {"OpSlice", []int{}},    // slice an array or string by the start, end and step on the stack
+	// destructuring, op 1 is the number of elements or keys to push,
+	// op 2 of OpUnpkArr is 1 to also push an array of the rest
+	OpUnpkArr: {"OpUnpkArr", []int{2, 1}},
+	OpUnpkDct: {"OpUnpkDct", []int{2}},
+	// call fn with op 1 positional arguments followed by
+	// op 2 pairs of a name and a named argument (max 255 each)
+	OpCallKw: {"OpCallKw", []int{1, 1}},
+	// jump to op 2 when the param in local op 1 was given
+	// by the call, skipping the code for its default
+	OpJmpSet: {"OpJmpSet", []int{2, 2}},
+	// match patterns. OpIsArr tests for an array of length op 1,
+	// or of at least op 1 when op 2 is 1. OpHasKeys tests for a
+	// dict with all of the op 1 keys on the stack above it
+	OpMatchEq: {"OpMatchEq", []int{}},
+	OpIsArr:   {"OpIsArr", []int{2, 1}},
+	OpHasKeys: {"OpHasKeys", []int{2}},
+	OpNoMatch: {"OpNoMatch", []int{}},
+	// op 1 of OpTry and OpEndFin is the try's slot in the fn's
+	// handler table, OpEndFin raises the error that ran the
+	// finally block again, if any
+	OpTry:    {"OpTry", []int{2}},
+	OpThrow:  {"OpThrow", []int{}},
+	OpEndFin: {"OpEndFin", []int{2}},
+	// read the export of a module in global op 1, op 2 is the
+	// constant naming it as module.export for the error when a
+	// return left the module before setting it
+	OpGetExp: {"OpGetExp", []int{2, 2}},
 }
 
 // Lookup returns relevant debugging info for op if available
@@ -95,6 +174,22 @@ func Lookup(op byte) (*Definition, error) {
 	return def, nil
 }
 
+// operandBytes holds the total width of the operands of each opcode
+var operandBytes [256]int
+
+func init() {
+	for op, def := range definitions {
+		for _, w := range def.OperandWidths {
+			operandBytes[op] += w
+		}
+	}
+}
+
+// OperandBytes returns the number of bytes of operands following op
+func OperandBytes(op Opcode) int {
+	return operandBytes[op]
+}
+
 func (instructions Instructions) String() string {
 	var out bytes.Buffer
 
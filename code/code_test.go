@@ -98,3 +98,23 @@ func TestReadOperands(t *testing.T) {
 		}
 	}
 }
+
+func TestOperandBytes(t *testing.T) {
+	tests := []struct {
+		op       Opcode
+		expected int
+	}{
+		{OpAdd, 0},
+		{OpCall, 1},
+		{OpConst, 2},
+		{OpClosure, 3},
+		{OpJmpSet, 4},
+		{Opcode(255), 0},
+	}
+
+	for _, tt := range tests {
+		if n := OperandBytes(tt.op); n != tt.expected {
+			t.Errorf("wrong operand bytes for %d. want=%d, got=%d", tt.op, tt.expected, n)
+		}
+	}
+}
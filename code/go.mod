@@ -1,3 +1,7 @@
 module crabscript.rs/code
 
 go 1.21.0
+
+replace crabscript.rs/token => ../token
+
+require crabscript.rs/token v0.0.0-00010101000000-000000000000
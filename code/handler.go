@@ -0,0 +1,26 @@
+package code
+
+// Handler is an entry of the exception table of a fn. An error raised by
+// an instruction in [Start, End) cuts the stack back to where it was when
+// the fn entered the try numbered Slot, and jumps to Target.
+type Handler struct {
+	Start   int
+	End     int
+	Target  int  // offset of the catch or finally block
+	Slot    int  // try the handler belongs to, numbered within the fn
+	Finally bool // Target is a finally block, which raises the error again at its end
+}
+
+// HandlerTable lists the handlers of a fn, nested handlers before the
+// handlers enclosing them
+type HandlerTable []Handler
+
+// Lookup returns the innermost handler covering the instruction at ip
+func (ht HandlerTable) Lookup(ip int) (Handler, bool) {
+	for _, h := range ht {
+		if ip >= h.Start && ip < h.End {
+			return h, true
+		}
+	}
+	return Handler{}, false
+}
@@ -0,0 +1,18 @@
+package code
+
+import "crabscript.rs/token"
+
+// SourceMap maps the offset of an instruction to the position of the
+// source that emitted it
+type SourceMap map[int]token.Position
+
+// Lookup returns the source position for the instruction at ip, falling
+// back to the closest instruction before it
+func (sm SourceMap) Lookup(ip int) token.Position {
+	for ; ip >= 0; ip-- {
+		if pos, ok := sm[ip]; ok {
+			return pos
+		}
+	}
+	return token.Position{}
+}
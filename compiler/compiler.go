@@ -6,25 +6,91 @@ import (
 
 	"crabscript.rs/ast"
 	"crabscript.rs/code"
+	"crabscript.rs/module"
 	"crabscript.rs/object"
+	"crabscript.rs/token"
 )
 
+// opcodes of the arithmetic and bitwise operators usable in compound
+// assignment
+var binaryOps = map[string]code.Opcode{
+	"+":  code.OpAdd,
+	"-":  code.OpSub,
+	"*":  code.OpMul,
+	"/":  code.OpDiv,
+	"%":  code.OpMod,
+	"**": code.OpPow,
+	"&":  code.OpBitAnd,
+	"|":  code.OpBitOr,
+	"^":  code.OpBitXor,
+	"<<": code.OpShl,
+	">>": code.OpShr,
+}
+
 type Compiler struct {
 	constants   []object.Object
 	symbolTable *SymbolTable // storing variables
 
 	scopes     []CompilationScope // stack of function scopes active
 	scopeIndex int
+
+	pos token.Position // position of the node being compiled
+
+	loader    *module.Loader // loads the modules imported by the program
+	moduleEnd *[]int         // jumps out of the module being compiled, see compileModuleReturn
 }
 
 type CompilationScope struct {
 	instructions        code.Instructions
+	sourceMap           code.SourceMap // instruction offset -> source position
 	lastInstruction     EmittedInstruction
 	previousInstruction EmittedInstruction
+	loops               []*loopScope // loops being compiled, innermost last
+	tries               []*tryScope  // tries being compiled, innermost last
+	numTries            int          // tries compiled so far, numbering their slots
+	handlers            code.HandlerTable
+}
+
+// jumps of the break and continue statements of a loop, back patched once
+// the loop has been compiled
+type loopScope struct {
+	breaks    []int
+	continues []int
+	tries     int // number of tries the loop is nested in
+}
+
+// a try being compiled, with the guards of its handlers that are still
+// open: both while compiling the body, the finally guard alone while
+// compiling the catch block
+type tryScope struct {
+	slot    int
+	finally *ast.BlockStatement
+	guards  []*guard
+}
+
+// the instruction ranges covered by a handler. A guard is paused around the
+// finally blocks run on the way out of its try by a return, break or
+// continue, so errors raised by them aren't caught by the try being left.
+type guard struct {
+	start  int // start of the open range, -1 while paused
+	ranges [][2]int
+}
+
+func (g *guard) pause(pos int) {
+	if g.start >= 0 && pos > g.start {
+		g.ranges = append(g.ranges, [2]int{g.start, pos})
+	}
+	g.start = -1
+}
+
+func (g *guard) resume(pos int) {
+	g.start = pos
 }
 
 type Bytecode struct {
 	Instructions code.Instructions
+	SourceMap    code.SourceMap
+	Handlers     code.HandlerTable
 	Constants    []object.Object
 }
 
@@ -37,6 +103,7 @@ type EmittedInstruction struct {
 func New() *Compiler {
 	mainScope := CompilationScope{
 		instructions:        code.Instructions{},
+		sourceMap:           code.SourceMap{},
 		lastInstruction:     EmittedInstruction{},
 		previousInstruction: EmittedInstruction{},
 	}
@@ -52,6 +119,7 @@ func New() *Compiler {
 		symbolTable: st,
 		scopes:      []CompilationScope{mainScope},
 		scopeIndex:  0,
+		loader:      module.NewLoader(),
 	}
 }
 
@@ -64,8 +132,24 @@ func NewWithState(s *SymbolTable, constants []object.Object) *Compiler {
 
 // TODO: Write compiler... lol
 func (c *Compiler) Compile(node ast.Node) error {
+	if node == nil {
+		return fmt.Errorf("cannot compile missing node")
+	}
+
+	// track the position of the node so emitted instructions can be mapped
+	// back to the source
+	if node.Pos().IsValid() {
+		outerPos := c.pos
+		c.pos = node.Pos()
+		defer func() { c.pos = outerPos }()
+	}
+
 	switch node := node.(type) {
 	case *ast.Program:
+		// refuse programs that failed to parse before emitting anything
+		if err := ast.Validate(node); err != nil {
+			return err
+		}
 		for _, st := range node.Statements {
 			err := c.Compile(st)
 			if err != nil {
@@ -73,6 +157,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
+	case *ast.BadStatement, *ast.BadExpression:
+		return fmt.Errorf("%s: cannot compile %s", node.Pos(), node)
+
 	case *ast.ExpressionStatement:
 		err := c.Compile(node.Expression)
 		if err != nil {
@@ -91,22 +178,12 @@ func (c *Compiler) Compile(node ast.Node) error {
 		case "-":
 			c.emit(code.OpNeg)
 		default:
-			return fmt.Errorf("unknown operator: %s", node.Operator)
+			return fmt.Errorf("%s: unknown operator: %s", node.Pos(), node.Operator)
 		}
 
 	case *ast.InfixExpression:
-		// reorder left and right values to reuse OpGt
-		if node.Operator == "<" {
-			err := c.Compile(node.Right)
-			if err != nil {
-				return err
-			}
-			err = c.Compile(node.Left)
-			if err != nil {
-				return err
-			}
-			c.emit(code.OpGt)
-			return nil
+		if node.Operator == "&&" || node.Operator == "||" {
+			return c.compileLogical(node)
 		}
 
 		err := c.Compile(node.Left)
@@ -127,20 +204,44 @@ func (c *Compiler) Compile(node ast.Node) error {
 			c.emit(code.OpMul)
 		case "/":
 			c.emit(code.OpDiv)
+		case "%":
+			c.emit(code.OpMod)
+		case "**":
+			c.emit(code.OpPow)
+		case "&":
+			c.emit(code.OpBitAnd)
+		case "|":
+			c.emit(code.OpBitOr)
+		case "^":
+			c.emit(code.OpBitXor)
+		case "<<":
+			c.emit(code.OpShl)
+		case ">>":
+			c.emit(code.OpShr)
 		case ">":
 			c.emit(code.OpGt)
+		case "<":
+			c.emit(code.OpLt)
+		case ">=":
+			c.emit(code.OpGe)
+		case "<=":
+			c.emit(code.OpLe)
 		case "==":
 			c.emit(code.OpEq)
 		case "!=":
 			c.emit(code.OpNe)
 		default:
-			return fmt.Errorf("unknown operator: %s", node.Operator)
+			return fmt.Errorf("%s: unknown operator: %s", node.Pos(), node.Operator)
 		}
 
 	case *ast.IntegerLiteral:
 		integer := &object.Integer{Value: node.Value}
 		c.emit(code.OpConst, c.addConstant(integer))
 
+	case *ast.FloatLiteral:
+		float := &object.Float{Value: node.Value}
+		c.emit(code.OpConst, c.addConstant(float))
+
 	case *ast.Boolean:
 		if node.Value {
 			c.emit(code.OpTrue)
@@ -160,9 +261,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			return err
 		}
 		// remove extra pop so that if blocks can be used for assignment
-		if c.lastInstructionIs(code.OpPop) {
-			c.removeLastPop()
-		}
+		c.keepBlockValue(node.Consequence)
 		// yet another number fresh from my ass
 		jmpPos := c.emit(code.OpJmp, 9999)
 		// get point to jmp to if condition is not true
@@ -177,9 +276,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 			if err != nil {
 				return err
 			}
-			if c.lastInstructionIs(code.OpPop) {
-				c.removeLastPop()
-			}
+			c.keepBlockValue(node.Alternative)
 		}
 		afterAlternativePos := len(c.currentInstructions())
 		c.changeOperand(jmpPos, afterAlternativePos)
@@ -192,8 +289,82 @@ func (c *Compiler) Compile(node ast.Node) error {
 			}
 		}
 
+	case *ast.WhileStatement:
+		loopStart := len(c.currentInstructions())
+		if err := c.Compile(node.Condition); err != nil {
+			return err
+		}
+		jmpNtPos := c.emit(code.OpJmpNt, 9999)
+
+		c.enterLoop()
+		if err := c.Compile(node.Body); err != nil {
+			return err
+		}
+		c.emit(code.OpJmp, loopStart)
+
+		afterLoopPos := len(c.currentInstructions())
+		c.changeOperand(jmpNtPos, afterLoopPos)
+		c.leaveLoop(loopStart, afterLoopPos)
+		c.emitLoopResult()
+
+	case *ast.ForInStatement:
+		if err := c.Compile(node.Iterable); err != nil {
+			return err
+		}
+		// the iterator stays on the stack for the duration of the loop
+		c.emit(code.OpIter)
+
+		loopStart := len(c.currentInstructions())
+		nextPos := c.emit(code.OpNext, 9999)
+		symbol := c.symbolTable.Define(node.Variable.Value)
+		if symbol.Scope == LocalScope {
+			c.emit(code.OpSetLcl, symbol.Index)
+		} else {
+			c.emit(code.OpSetGbl, symbol.Index)
+		}
+
+		c.enterLoop()
+		if err := c.Compile(node.Body); err != nil {
+			return err
+		}
+		c.emit(code.OpJmp, loopStart)
+
+		// breaking out of the loop jumps here to drop the iterator
+		afterLoopPos := len(c.currentInstructions())
+		c.changeOperand(nextPos, afterLoopPos)
+		c.emit(code.OpPop)
+		c.leaveLoop(loopStart, afterLoopPos)
+		c.emitLoopResult()
+
+	case *ast.BreakStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: break outside loop", node.Pos())
+		}
+		resume, err := c.leaveTries(loop.tries)
+		if err != nil {
+			return err
+		}
+		loop.breaks = append(loop.breaks, c.emit(code.OpJmp, 9999))
+		resume()
+
+	case *ast.ContinueStatement:
+		loop := c.currentLoop()
+		if loop == nil {
+			return fmt.Errorf("%s: continue outside loop", node.Pos())
+		}
+		resume, err := c.leaveTries(loop.tries)
+		if err != nil {
+			return err
+		}
+		loop.continues = append(loop.continues, c.emit(code.OpJmp, 9999))
+		resume()
+
 		// binding a variable
 	case *ast.LetStatement:
+		if node.Pattern != nil {
+			return c.compileDestructure(node)
+		}
 		symbol := c.symbolTable.Define(node.Name.Value)
 		if err := c.Compile(node.Value); err != nil {
 			return err
@@ -208,7 +379,10 @@ func (c *Compiler) Compile(node ast.Node) error {
 	case *ast.Identifier:
 		symbol, ok := c.symbolTable.Resolve(node.Value)
 		if !ok {
-			return fmt.Errorf("unresolved symbol: %v", node.Value)
+			return fmt.Errorf("%s: unresolved symbol: %v", node.Pos(), node.Value)
+		}
+		if symbol.Scope == ModuleScope {
+			return fmt.Errorf("%s: module %s can only be used to read its exports, as %s.name", node.Pos(), node.Value, node.Value)
 		}
 		c.resolveSymbol(symbol)
 
@@ -216,6 +390,14 @@ func (c *Compiler) Compile(node ast.Node) error {
 		str := &object.String{Value: node.Value}
 		c.emit(code.OpConst, c.addConstant(str))
 
+	case *ast.InterpolatedString:
+		for _, part := range node.Parts {
+			if err := c.Compile(part); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpConcat, len(node.Parts))
+
 	case *ast.ArrayLiteral:
 		for _, e := range node.Elements {
 			err := c.Compile(e)
@@ -245,6 +427,9 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.emit(code.OpDict, len(node.Pairs)*2)
 
+	case *ast.AssignExpression:
+		return c.compileAssign(node)
+
 	case *ast.IndexExpression:
 		// get expression of subscript and item
 		if err := c.Compile(node.Left); err != nil {
@@ -255,12 +440,57 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 		c.emit(code.OpIdx)
 
+	case *ast.SliceExpression:
+		if err := c.Compile(node.Left); err != nil {
+			return err
+		}
+		// missing bounds are passed as null
+		for _, bound := range []ast.Expression{node.Start, node.End, node.Step} {
+			if bound == nil {
+				c.emit(code.OpNull)
+				continue
+			}
+			if err := c.Compile(bound); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpSlice)
+
+	case *ast.MatchExpression:
+		return c.compileMatch(node)
+
 	case *ast.FunctionLiteral:
 		// go into new scope for our fn
 		c.enterScope()
 
-		for _, p := range node.Parameters {
+		if node.Name != "" {
+			c.symbolTable.DefineFunctionName(node.Name)
+		}
+
+		sig := object.Signature{Variadic: node.Rest != nil}
+		for i, p := range node.Parameters {
 			c.symbolTable.Define(p.Value)
+			sig.Params = append(sig.Params, p.Value)
+			if sig.Required == i && node.Default(i) == nil {
+				sig.Required++
+			}
+		}
+		if node.Rest != nil {
+			c.symbolTable.Define(node.Rest.Value)
+		}
+
+		// params left out of a call are nil until their default is set
+		for i := range node.Parameters {
+			def := node.Default(i)
+			if def == nil {
+				continue
+			}
+			jmpPos := c.emit(code.OpJmpSet, i, 9999)
+			if err := c.Compile(def); err != nil {
+				return err
+			}
+			c.emit(code.OpSetLcl, i)
+			c.replaceInstruction(jmpPos, code.Make(code.OpJmpSet, i, len(c.currentInstructions())))
 		}
 
 		if err := c.Compile(node.Body); err != nil {
@@ -268,7 +498,7 @@ func (c *Compiler) Compile(node ast.Node) error {
 		}
 
 		// returning value instead of pop if needed
-		if c.lastInstructionIs(code.OpPop) {
+		if endsInExpression(node.Body) && c.lastInstructionIs(code.OpPop) {
 			c.replaceLastPopWithRet()
 		}
 
@@ -279,28 +509,61 @@ func (c *Compiler) Compile(node ast.Node) error {
 
 		numLocals := c.symbolTable.numDefinitions
 		freeSym := c.symbolTable.FreeSymbols
+		sourceMap := c.currentScope().sourceMap
+		handlers := c.currentScope().handlers
 
 		// return instructions once e finish compiling to put onto the const heap
 		instructions := c.leaveScope()
 
 		for _, s := range freeSym {
-			c.resolveSymbol(s)
+			c.captureSymbol(s)
 		}
 
 		compiledFn := &object.CompFn{
 			Instructions:  instructions,
 			LocalVarCount: numLocals,
 			ParamCount:    len(node.Parameters),
+			SourceMap:     sourceMap,
+			Handlers:      handlers,
+			Name:          node.Name,
+			Signature:     sig,
 		}
 		fnIdx := c.addConstant(compiledFn)
 		c.emit(code.OpClosure, fnIdx, len(freeSym))
 
 		// return to branch point with our return value at top of stack
 	case *ast.ReturnStatement:
-		if err := c.Compile(node.ReturnValue); err != nil {
+		if c.moduleEnd != nil && c.scopeIndex == 0 {
+			return c.compileModuleReturn(node)
+		}
+		op := code.OpRet
+		if node.ReturnValue != nil {
+			if err := c.Compile(node.ReturnValue); err != nil {
+				return err
+			}
+			op = code.OpRetVal
+		}
+		resume, err := c.leaveTries(0)
+		if err != nil {
+			return err
+		}
+		c.emit(op)
+		resume()
+
+	case *ast.ThrowStatement:
+		if err := c.Compile(node.Value); err != nil {
 			return err
 		}
-		c.emit(code.OpRetVal)
+		c.emit(code.OpThrow)
+
+	case *ast.TryExpression:
+		return c.compileTry(node)
+
+	case *ast.ImportStatement:
+		return c.compileImport(node)
+
+	case *ast.MemberExpression:
+		return c.compileMember(node)
 
 		// running a compiled fn in the const pool
 	case *ast.CallExpression:
@@ -312,7 +575,18 @@ func (c *Compiler) Compile(node ast.Node) error {
 				return err
 			}
 		}
-		c.emit(code.OpCall, len(node.Arguments))
+		if len(node.Named) == 0 {
+			c.emit(code.OpCall, len(node.Arguments))
+			return nil
+		}
+
+		for _, arg := range node.Named {
+			c.emit(code.OpConst, c.addConstant(&object.String{Value: arg.Name.Value}))
+			if err := c.Compile(arg.Value); err != nil {
+				return err
+			}
+		}
+		c.emit(code.OpCallKw, len(node.Arguments), len(node.Named))
 	}
 
 	return nil
@@ -321,6 +595,8 @@ func (c *Compiler) Compile(node ast.Node) error {
 func (c *Compiler) Bytecode() *Bytecode {
 	return &Bytecode{
 		Instructions: c.currentInstructions(),
+		SourceMap:    c.currentScope().sourceMap,
+		Handlers:     c.currentScope().handlers,
 		Constants:    c.constants,
 	}
 }
@@ -337,6 +613,9 @@ func (c *Compiler) addConstant(obj object.Object) int {
 func (c *Compiler) emit(op code.Opcode, operands ...int) int {
 	in := code.Make(op, operands...)
 	pos := c.addInstruction(in)
+	if c.pos.IsValid() {
+		c.scopes[c.scopeIndex].sourceMap[pos] = c.pos
+	}
 
 	c.setLastInstruction(op, pos)
 	return pos
@@ -397,6 +676,7 @@ func (c *Compiler) currentScope() CompilationScope {
 func (c *Compiler) enterScope() {
 	scope := CompilationScope{
 		instructions:        code.Instructions{},
+		sourceMap:           code.SourceMap{},
 		lastInstruction:     EmittedInstruction{},
 		previousInstruction: EmittedInstruction{},
 	}
@@ -420,6 +700,180 @@ func (c *Compiler) leaveScope() code.Instructions {
 	return inst
 }
 
+// leave the value of a block on the stack, blocks that don't end in an
+// expression have a null value
+func (c *Compiler) keepBlockValue(block *ast.BlockStatement) {
+	if endsInExpression(block) && c.lastInstructionIs(code.OpPop) {
+		c.removeLastPop()
+	} else {
+		c.emit(code.OpNull)
+	}
+}
+
+// reports whether the last statement of block is an expression, whose
+// value is popped unless the caller keeps it
+func endsInExpression(block *ast.BlockStatement) bool {
+	if len(block.Statements) == 0 {
+		return false
+	}
+	_, ok := block.Statements[len(block.Statements)-1].(*ast.ExpressionStatement)
+	return ok
+}
+
+func (c *Compiler) enterLoop() {
+	loop := &loopScope{tries: len(c.scopes[c.scopeIndex].tries)}
+	c.scopes[c.scopeIndex].loops = append(c.scopes[c.scopeIndex].loops, loop)
+}
+
+// patch the jumps of the innermost loop's break and continue statements
+func (c *Compiler) leaveLoop(continuePos int, breakPos int) {
+	loops := c.scopes[c.scopeIndex].loops
+	loop := loops[len(loops)-1]
+	c.scopes[c.scopeIndex].loops = loops[:len(loops)-1]
+
+	for _, pos := range loop.breaks {
+		c.changeOperand(pos, breakPos)
+	}
+	for _, pos := range loop.continues {
+		c.changeOperand(pos, continuePos)
+	}
+}
+
+// loops are statements, leave null as the last value popped rather than
+// the condition or iterator, as the repl shows it
+func (c *Compiler) emitLoopResult() {
+	c.emit(code.OpNull)
+	c.emit(code.OpPop)
+}
+
+// innermost loop of the current fn, nil outside loops
+func (c *Compiler) currentLoop() *loopScope {
+	loops := c.scopes[c.scopeIndex].loops
+	if len(loops) == 0 {
+		return nil
+	}
+	return loops[len(loops)-1]
+}
+
+// compiles a try to
+//
+//	OpTry slot
+//	<body>              guarded by the catch and finally handlers
+//	OpJmp finally
+//	catch:
+//	OpSetGbl param      the error is pushed by the vm
+//	<catch block>       guarded by the finally handler, in a scope of its own
+//	finally:
+//	<finally block>
+//	OpEndFin slot
+//
+// The value of the body or catch block stays on the stack while the finally
+// block runs. An error jumps to the finally block with the stack cut back to
+// where it was at OpTry, and OpEndFin raises it again.
+func (c *Compiler) compileTry(node *ast.TryExpression) error {
+	try := &tryScope{slot: c.scopes[c.scopeIndex].numTries, finally: node.Finally}
+	c.scopes[c.scopeIndex].numTries++
+
+	c.emit(code.OpTry, try.slot)
+	start := len(c.currentInstructions())
+	catchGuard := &guard{start: start}
+	finallyGuard := &guard{start: start}
+	if node.Catch != nil {
+		try.guards = append(try.guards, catchGuard)
+	}
+	if node.Finally != nil {
+		try.guards = append(try.guards, finallyGuard)
+	}
+
+	tries := c.scopes[c.scopeIndex].tries
+	c.scopes[c.scopeIndex].tries = append(tries, try)
+
+	if err := c.Compile(node.Body); err != nil {
+		return err
+	}
+	c.keepBlockValue(node.Body)
+
+	if node.Catch != nil {
+		catchGuard.pause(len(c.currentInstructions()))
+		try.guards = try.guards[1:]
+		jmpPos := c.emit(code.OpJmp, 9999)
+
+		// the param is bound in a scope of its own, the catch block, so it
+		// shadows a name of the enclosing scope rather than assigning it
+		catchPos := len(c.currentInstructions())
+		c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+		c.setSymbol(c.symbolTable.Define(node.Param.Value))
+		err := c.Compile(node.Catch)
+		c.symbolTable = c.symbolTable.Outer
+		if err != nil {
+			return err
+		}
+		c.keepBlockValue(node.Catch)
+		c.addHandlers(catchGuard, catchPos, try.slot, false)
+
+		c.changeOperand(jmpPos, len(c.currentInstructions()))
+	}
+
+	// return, break and continue in the finally block leave the try
+	// without running it again
+	c.scopes[c.scopeIndex].tries = tries
+
+	if node.Finally != nil {
+		finallyPos := len(c.currentInstructions())
+		finallyGuard.pause(finallyPos)
+		if err := c.Compile(node.Finally); err != nil {
+			return err
+		}
+		c.emit(code.OpEndFin, try.slot)
+		c.addHandlers(finallyGuard, finallyPos, try.slot, true)
+	}
+
+	return nil
+}
+
+// adds handlers jumping to target for the ranges covered by a guard
+func (c *Compiler) addHandlers(g *guard, target int, slot int, finally bool) {
+	for _, r := range g.ranges {
+		c.scopes[c.scopeIndex].handlers = append(c.scopes[c.scopeIndex].handlers, code.Handler{
+			Start: r[0], End: r[1], Target: target, Slot: slot, Finally: finally,
+		})
+	}
+}
+
+// runs the finally blocks of the tries left by a return, break or continue,
+// innermost first, down to the first depth tries of the fn. The guards of
+// the tries left are paused, the returned func resumes them once the jump
+// out has been emitted.
+func (c *Compiler) leaveTries(depth int) (func(), error) {
+	tries := c.scopes[c.scopeIndex].tries
+
+	for i := len(tries) - 1; i >= depth; i-- {
+		for _, g := range tries[i].guards {
+			g.pause(len(c.currentInstructions()))
+		}
+		if tries[i].finally == nil {
+			continue
+		}
+
+		// leaving the finally block only runs those of the tries around it
+		c.scopes[c.scopeIndex].tries = tries[:i]
+		err := c.Compile(tries[i].finally)
+		c.scopes[c.scopeIndex].tries = tries
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return func() {
+		pos := len(c.currentInstructions())
+		for _, try := range tries[depth:] {
+			for _, g := range try.guards {
+				g.resume(pos)
+			}
+		}
+	}, nil
+}
+
 // adds return values code in place of pop
 func (c *Compiler) replaceLastPopWithRet() {
 	lastPos := c.scopes[c.scopeIndex].lastInstruction.Position
@@ -439,5 +893,299 @@ func (c *Compiler) resolveSymbol(s Symbol) {
 		c.emit(code.OpGetBIn, s.Index)
 	case FreeScope:
 		c.emit(code.OpGetFree, s.Index)
+	case FunctionScope:
+		c.emit(code.OpCurCsr)
+	}
+}
+
+// push a symbol captured by a closure, locals and free variables are
+// pushed as cells so that the closure shares them with their scope
+func (c *Compiler) captureSymbol(s Symbol) {
+	switch s.Scope {
+	case LocalScope:
+		c.emit(code.OpBoxLcl, s.Index)
+	case FreeScope:
+		c.emit(code.OpBoxFree, s.Index)
+	default:
+		c.resolveSymbol(s)
+	}
+}
+
+// unpacks the value of a let onto the stack and pops it into the names of
+// its pattern. The names are defined after compiling the value, so that
+// `let [a, b] = [b, a]` reads the old bindings.
+func (c *Compiler) compileDestructure(node *ast.LetStatement) error {
+	if err := c.Compile(node.Value); err != nil {
+		return err
+	}
+
+	var names []*ast.Identifier
+	switch pattern := node.Pattern.(type) {
+	case *ast.ArrayPattern:
+		names = append(names, pattern.Elements...)
+		rest := 0
+		if pattern.Rest != nil {
+			names = append(names, pattern.Rest)
+			rest = 1
+		}
+		c.emit(code.OpUnpkArr, len(pattern.Elements), rest)
+
+	case *ast.DictPattern:
+		names = pattern.Keys
+		for _, key := range pattern.Keys {
+			c.emit(code.OpConst, c.addConstant(&object.String{Value: key.Value}))
+		}
+		c.emit(code.OpUnpkDct, len(pattern.Keys))
+
+	default:
+		return fmt.Errorf("%s: unknown pattern: %T", node.Pos(), node.Pattern)
+	}
+
+	symbols := make([]Symbol, len(names))
+	for i, name := range names {
+		symbols[i] = c.symbolTable.Define(name.Value)
+	}
+
+	// the last value unpacked is on top of the stack
+	for i := len(symbols) - 1; i >= 0; i-- {
+		c.setSymbol(symbols[i])
+	}
+
+	return nil
+}
+
+// pops the top of the stack into a symbol defined in the current scope
+func (c *Compiler) setSymbol(s Symbol) {
+	if s.Scope == LocalScope {
+		c.emit(code.OpSetLcl, s.Index)
+	} else {
+		c.emit(code.OpSetGbl, s.Index)
+	}
+}
+
+// lowers a match to a chain of tests that jump to the next arm when they
+// fail. The subject is kept in a hidden symbol, and each test loads the part
+// of it the pattern is looking at by indexing down from there.
+func (c *Compiler) compileMatch(node *ast.MatchExpression) error {
+	if err := c.Compile(node.Subject); err != nil {
+		return err
+	}
+	// the subject is held in a temp the script can't name
+	subject := c.symbolTable.DefineTemp()
+	defer c.symbolTable.ReleaseTemp(subject)
+	c.setSymbol(subject)
+
+	var endJumps []int
+	for _, arm := range node.Arms {
+		// each arm has a scope of its own, so the names in its pattern
+		// shadow those around the match rather than assigning them
+		c.symbolTable = NewBlockSymbolTable(c.symbolTable)
+		var failJumps []int
+		err := c.compileArm(arm, subject, &failJumps)
+		c.symbolTable = c.symbolTable.Outer
+		if err != nil {
+			return err
+		}
+		endJumps = append(endJumps, c.emit(code.OpJmp, 9999))
+
+		nextArmPos := len(c.currentInstructions())
+		for _, pos := range failJumps {
+			c.changeOperand(pos, nextArmPos)
+		}
+	}
+
+	c.loadMatchPath(subject, nil)
+	c.emit(code.OpNoMatch)
+
+	afterMatchPos := len(c.currentInstructions())
+	for _, pos := range endJumps {
+		c.changeOperand(pos, afterMatchPos)
+	}
+
+	return nil
+}
+
+// emits the pattern, guard and body of a match arm, adding the positions of
+// the jumps to the next arm to failJumps
+func (c *Compiler) compileArm(arm *ast.MatchArm, subject Symbol, failJumps *[]int) error {
+	if err := c.compilePattern(arm.Pattern, subject, nil, failJumps); err != nil {
+		return err
+	}
+
+	if arm.Guard != nil {
+		if err := c.Compile(arm.Guard); err != nil {
+			return err
+		}
+		*failJumps = append(*failJumps, c.emit(code.OpJmpNt, 9999))
 	}
+
+	return c.Compile(arm.Body)
+}
+
+// emits the tests and bindings of a pattern matched against the part of the
+// subject found by indexing it with path. The positions of the jumps taken
+// when a test fails are added to failJumps.
+func (c *Compiler) compilePattern(pattern ast.Expression, subject Symbol, path []object.Object, failJumps *[]int) error {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value == "_" {
+			return nil
+		}
+		c.loadMatchPath(subject, path)
+		c.setSymbol(c.symbolTable.Define(pattern.Value))
+
+	case *ast.ArrayShape:
+		n := len(pattern.Elements)
+		atLeast := 0
+		if pattern.Rest != nil {
+			atLeast = 1
+		}
+		c.loadMatchPath(subject, path)
+		c.emit(code.OpIsArr, n, atLeast)
+		*failJumps = append(*failJumps, c.emit(code.OpJmpNt, 9999))
+
+		for i, el := range pattern.Elements {
+			step := &object.Integer{Value: int64(i)}
+			if err := c.compilePattern(el, subject, append(path[:len(path):len(path)], step), failJumps); err != nil {
+				return err
+			}
+		}
+
+		if pattern.Rest != nil && pattern.Rest.Value != "_" {
+			c.loadMatchPath(subject, path)
+			c.emit(code.OpConst, c.addConstant(&object.Integer{Value: int64(n)}))
+			c.emit(code.OpNull)
+			c.emit(code.OpNull)
+			c.emit(code.OpSlice)
+			c.setSymbol(c.symbolTable.Define(pattern.Rest.Value))
+		}
+
+	case *ast.DictShape:
+		c.loadMatchPath(subject, path)
+		for _, key := range pattern.Keys {
+			c.emit(code.OpConst, c.addConstant(&object.String{Value: key}))
+		}
+		c.emit(code.OpHasKeys, len(pattern.Keys))
+		*failJumps = append(*failJumps, c.emit(code.OpJmpNt, 9999))
+
+		for i, value := range pattern.Values {
+			step := &object.String{Value: pattern.Keys[i]}
+			if err := c.compilePattern(value, subject, append(path[:len(path):len(path)], step), failJumps); err != nil {
+				return err
+			}
+		}
+
+	default:
+		// a literal
+		c.loadMatchPath(subject, path)
+		if err := c.Compile(pattern); err != nil {
+			return err
+		}
+		c.emit(code.OpMatchEq)
+		*failJumps = append(*failJumps, c.emit(code.OpJmpNt, 9999))
+	}
+
+	return nil
+}
+
+// pushes the part of the match subject found by indexing it with path
+func (c *Compiler) loadMatchPath(subject Symbol, path []object.Object) {
+	c.resolveSymbol(subject)
+	for _, step := range path {
+		c.emit(code.OpConst, c.addConstant(step))
+		c.emit(code.OpIdx)
+	}
+}
+
+func (c *Compiler) compileAssign(node *ast.AssignExpression) error {
+	op := node.BinaryOperator()
+	binOp, ok := binaryOps[op]
+	if op != "" && !ok {
+		return fmt.Errorf("%s: unknown operator: %s", node.Pos(), node.Operator)
+	}
+
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		symbol, ok := c.symbolTable.Resolve(target.Value)
+		if !ok {
+			return fmt.Errorf("%s: unresolved symbol: %v", target.Pos(), target.Value)
+		}
+
+		if op != "" {
+			c.resolveSymbol(symbol)
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		if op != "" {
+			c.emit(binOp)
+		}
+
+		// assignment is an expression, leave the new value on the stack
+		switch symbol.Scope {
+		case GlobalScope:
+			c.emit(code.OpSetGbl, symbol.Index)
+		case LocalScope:
+			c.emit(code.OpSetLcl, symbol.Index)
+		case FreeScope:
+			c.emit(code.OpSetFree, symbol.Index)
+		case BuiltinScope:
+			return fmt.Errorf("%s: cannot assign to builtin %s", target.Pos(), target.Value)
+		case FunctionScope:
+			return fmt.Errorf("%s: cannot assign to %s inside its own definition", target.Pos(), target.Value)
+		case ModuleScope:
+			return fmt.Errorf("%s: cannot assign to module %s", target.Pos(), target.Value)
+		}
+		c.resolveSymbol(symbol)
+
+	case *ast.IndexExpression:
+		if err := c.Compile(target.Left); err != nil {
+			return err
+		}
+		if err := c.Compile(target.Index); err != nil {
+			return err
+		}
+
+		if op != "" {
+			// keep the collection and index for OpSetIdx
+			c.emit(code.OpDup, 2)
+			c.emit(code.OpIdx)
+		}
+		if err := c.Compile(node.Value); err != nil {
+			return err
+		}
+		if op != "" {
+			c.emit(binOp)
+		}
+		c.emit(code.OpSetIdx)
+
+	default:
+		return fmt.Errorf("%s: cannot assign to %s", node.Pos(), node.Target)
+	}
+	return nil
+}
+
+// short-circuit && and || with jumps, the left value is kept when it decides
+// the result and popped otherwise so that the right side takes its place
+func (c *Compiler) compileLogical(node *ast.InfixExpression) error {
+	if err := c.Compile(node.Left); err != nil {
+		return err
+	}
+	c.emit(code.OpDup, 1)
+
+	var endJmp int
+	if node.Operator == "&&" {
+		endJmp = c.emit(code.OpJmpNt, 9999)
+	} else {
+		rightJmp := c.emit(code.OpJmpNt, 9999)
+		endJmp = c.emit(code.OpJmp, 9999)
+		c.changeOperand(rightJmp, len(c.currentInstructions()))
+	}
+
+	c.emit(code.OpPop)
+	if err := c.Compile(node.Right); err != nil {
+		return err
+	}
+	c.changeOperand(endJmp, len(c.currentInstructions()))
+	return nil
 }
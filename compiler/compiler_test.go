@@ -9,6 +9,7 @@ import (
 	"crabscript.rs/lexer"
 	"crabscript.rs/object"
 	"crabscript.rs/parser"
+	"crabscript.rs/token"
 )
 
 type compilerTestCase struct {
@@ -69,6 +70,86 @@ func TestIntArithmetic(t *testing.T) {
 				code.Make(code.OpPop),
 			},
 		},
+		{
+			input:             "5 % 2",
+			expectedConstants: []interface{}{5, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpMod),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "2 ** 3",
+			expectedConstants: []interface{}{2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpPow),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "6 & 3",
+			expectedConstants: []interface{}{6, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpBitAnd),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "6 | 3",
+			expectedConstants: []interface{}{6, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpBitOr),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "6 ^ 3",
+			expectedConstants: []interface{}{6, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpBitXor),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "6 << 3",
+			expectedConstants: []interface{}{6, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpShl),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "6 >> 3",
+			expectedConstants: []interface{}{6, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpShr),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "1.5 * 2",
+			expectedConstants: []interface{}{1.5, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpMul),
+				code.Make(code.OpPop),
+			},
+		},
 		{
 			input:             "-1",
 			expectedConstants: []interface{}{1},
@@ -109,10 +190,26 @@ func TestBooleanExpresions(t *testing.T) {
 			},
 		},
 		{
-			input: "1 < 2", expectedConstants: []interface{}{2, 1}, expectedInstructions: []code.Instructions{
+			input: "1 < 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{
 				code.Make(code.OpConst, 0),
 				code.Make(code.OpConst, 1),
-				code.Make(code.OpGt),
+				code.Make(code.OpLt),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "1 <= 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpLe),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "1 >= 2", expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpGe),
 				code.Make(code.OpPop),
 			},
 		},
@@ -196,6 +293,39 @@ func TestIndexExperssions(t *testing.T) {
 	runCompilerTests(t, tests)
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             "[1, 2][1:2:3]",
+			expectedConstants: []interface{}{1, 2, 1, 2, 3},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpArray, 2),
+				code.Make(code.OpConst, 2),
+				code.Make(code.OpConst, 3),
+				code.Make(code.OpConst, 4),
+				code.Make(code.OpSlice),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"ab"[:1]`,
+			expectedConstants: []interface{}{"ab", 1},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpNull),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpNull),
+				code.Make(code.OpSlice),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
 func TestLetStatementScope(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -284,7 +414,7 @@ fn(a) {
 					code.Make(code.OpRetVal),
 				},
 				[]code.Instructions{
-					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpBoxLcl, 0),
 					code.Make(code.OpClosure, 0, 1),
 					code.Make(code.OpRetVal),
 				},
@@ -312,13 +442,13 @@ fn(c) { a+b+c
 					code.Make(code.OpRetVal),
 				},
 				[]code.Instructions{
-					code.Make(code.OpGetFree, 0),
-					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpBoxFree, 0),
+					code.Make(code.OpBoxLcl, 0),
 					code.Make(code.OpClosure, 0, 2),
 					code.Make(code.OpRetVal),
 				},
 				[]code.Instructions{
-					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpBoxLcl, 0),
 					code.Make(code.OpClosure, 1, 1),
 					code.Make(code.OpRetVal),
 				}},
@@ -368,6 +498,12 @@ func testConstants(t *testing.T, expected []interface{}, actual []object.Object)
 				return fmt.Errorf("constant %d - testIntegerObject failed: %s", i, err)
 			}
 
+		case float64:
+			err := testFloatObject(constant, actual[i])
+			if err != nil {
+				return fmt.Errorf("constant %d - testFloatObject failed: %s", i, err)
+			}
+
 		case string:
 			err := testStringObject(constant, actual[i])
 			if err != nil {
@@ -387,6 +523,74 @@ func testConstants(t *testing.T, expected []interface{}, actual []object.Object)
 	return nil
 }
 
+func TestRecursiveFns(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+let countDown = fn(x) { countDown(x - 1); };
+countDown(1);
+`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpCurCsr),
+					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpConst, 0),
+					code.Make(code.OpSub),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpRetVal),
+				},
+				1,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpConst, 2),
+				code.Make(code.OpCall, 1),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+let wrapper = fn() {
+	let countDown = fn(x) { countDown(x - 1); };
+	countDown(1);
+};
+wrapper();
+`,
+			expectedConstants: []interface{}{
+				1,
+				[]code.Instructions{
+					code.Make(code.OpCurCsr),
+					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpConst, 0),
+					code.Make(code.OpSub),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpRetVal),
+				},
+				1,
+				[]code.Instructions{
+					code.Make(code.OpClosure, 1, 0),
+					code.Make(code.OpSetLcl, 0),
+					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpConst, 2),
+					code.Make(code.OpCall, 1),
+					code.Make(code.OpRetVal),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpCall, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
 func TestCompilerScopes(t *testing.T) {
 	compiler := New()
 	if compiler.scopeIndex != 0 {
@@ -456,6 +660,17 @@ func testStringObject(expected string, o object.Object) interface{} {
 	return nil
 }
 
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got %T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got %v, want %v", result.Value, expected)
+	}
+	return nil
+}
+
 func testIntegerObject(expected int64, actual object.Object) error {
 	result, ok := actual.(*object.Integer)
 	if !ok {
@@ -534,80 +749,627 @@ if (true) { 10 }; 3333;
 	runCompilerTests(t, tests)
 }
 
-func TestGlobalLetStatements(t *testing.T) {
+func TestLogicalOperators(t *testing.T) {
 	tests := []compilerTestCase{
 		{
-			input: `
-let one = 1;
-let two = 2;
-`,
-			expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{
-				code.Make(code.OpConst, 0),
-				code.Make(code.OpSetGbl, 0),
-				code.Make(code.OpConst, 1),
-				code.Make(code.OpSetGbl, 1),
-			}},
-		{
-			input: `
-let one = 1;
-one;
-`,
-			expectedConstants: []interface{}{1}, expectedInstructions: []code.Instructions{
+			input:             `true && 1;`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpDup, 1),
+				// 0003
+				code.Make(code.OpJmpNt, 10),
+				// 0006
+				code.Make(code.OpPop),
+				// 0007
 				code.Make(code.OpConst, 0),
-				code.Make(code.OpSetGbl, 0),
-				code.Make(code.OpGetGbl, 0),
+				// 0010
 				code.Make(code.OpPop),
 			},
 		},
 		{
-			input: `
-let one = 1;
-let two = one;
-two;
-`,
-			expectedConstants: []interface{}{1}, expectedInstructions: []code.Instructions{
+			input:             `false || 1;`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpFalse),
+				// 0001
+				code.Make(code.OpDup, 1),
+				// 0003
+				code.Make(code.OpJmpNt, 9),
+				// 0006
+				code.Make(code.OpJmp, 13),
+				// 0009
+				code.Make(code.OpPop),
+				// 0010
 				code.Make(code.OpConst, 0),
-				code.Make(code.OpSetGbl, 0),
-				code.Make(code.OpGetGbl, 0),
-				code.Make(code.OpSetGbl, 1),
-				code.Make(code.OpGetGbl, 1),
+				// 0013
 				code.Make(code.OpPop),
 			},
 		},
 	}
+
 	runCompilerTests(t, tests)
 }
 
-func TestStringExpressions(t *testing.T) {
+func TestDestructuringLetStatements(t *testing.T) {
 	tests := []compilerTestCase{
 		{
-			input: `"monkey"`, expectedConstants: []interface{}{"monkey"}, expectedInstructions: []code.Instructions{
+			input:             "let [a, ...b] = [1];",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
 				code.Make(code.OpConst, 0),
-				code.Make(code.OpPop),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpUnpkArr, 1, 1),
+				code.Make(code.OpSetGbl, 1),
+				code.Make(code.OpSetGbl, 0),
 			},
 		},
 		{
-			input: `"mon" + "key"`, expectedConstants: []interface{}{"mon", "key"}, expectedInstructions: []code.Instructions{
+			input:             "let [a, b] = [1]; a",
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
 				code.Make(code.OpConst, 0),
-				code.Make(code.OpConst, 1),
-				code.Make(code.OpAdd),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpUnpkArr, 2, 0),
+				code.Make(code.OpSetGbl, 1),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "fn(d) { let {x, y} = d; }",
+			expectedConstants: []interface{}{
+				"x",
+				"y",
+				[]code.Instructions{
+					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpConst, 0),
+					code.Make(code.OpConst, 1),
+					code.Make(code.OpUnpkDct, 2),
+					code.Make(code.OpSetLcl, 2),
+					code.Make(code.OpSetLcl, 1),
+					code.Make(code.OpRet),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
 				code.Make(code.OpPop),
 			},
 		},
 	}
+
 	runCompilerTests(t, tests)
 }
 
-func TestArrayLiterals(t *testing.T) {
+func TestMatchExpressions(t *testing.T) {
 	tests := []compilerTestCase{
 		{
-			input: "[]", expectedConstants: []interface{}{}, expectedInstructions: []code.Instructions{
-				code.Make(code.OpArray, 0),
+			input:             "match (5) { 1 => 2, n => n }",
+			expectedConstants: []interface{}{5, 1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConst, 0),
+				// 0003
+				code.Make(code.OpSetGbl, 0),
+				// 0006
+				code.Make(code.OpGetGbl, 0),
+				// 0009
+				code.Make(code.OpConst, 1),
+				// 0012
+				code.Make(code.OpMatchEq),
+				// 0013
+				code.Make(code.OpJmpNt, 22),
+				// 0016
+				code.Make(code.OpConst, 2),
+				// 0019
+				code.Make(code.OpJmp, 38),
+				// 0022
+				code.Make(code.OpGetGbl, 0),
+				// 0025
+				code.Make(code.OpSetGbl, 1),
+				// 0028
+				code.Make(code.OpGetGbl, 1),
+				// 0031
+				code.Make(code.OpJmp, 38),
+				// 0034
+				code.Make(code.OpGetGbl, 0),
+				// 0037
+				code.Make(code.OpNoMatch),
+				// 0038
 				code.Make(code.OpPop),
 			},
 		},
 		{
-			input: "[1, 2, 3]", expectedConstants: []interface{}{1, 2, 3}, expectedInstructions: []code.Instructions{
+			input:             "match ([1]) { [a, ..._] => a }",
+			expectedConstants: []interface{}{1, 0},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConst, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpSetGbl, 0),
+				// 0009
+				code.Make(code.OpGetGbl, 0),
+				// 0012
+				code.Make(code.OpIsArr, 1, 1),
+				// 0016
+				code.Make(code.OpJmpNt, 35),
+				// 0019
+				code.Make(code.OpGetGbl, 0),
+				// 0022
+				code.Make(code.OpConst, 1),
+				// 0025
+				code.Make(code.OpIdx),
+				// 0026
+				code.Make(code.OpSetGbl, 1),
+				// 0029
+				code.Make(code.OpGetGbl, 1),
+				// 0032
+				code.Make(code.OpJmp, 39),
+				// 0035
+				code.Make(code.OpGetGbl, 0),
+				// 0038
+				code.Make(code.OpNoMatch),
+				// 0039
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `throw "oops"`,
+			expectedConstants: []interface{}{"oops"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpThrow),
+			},
+		},
+		{
+			input:             "try { 1 } catch (e) { 2 }",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTry, 0),
+				// 0003
+				code.Make(code.OpConst, 0),
+				// 0006
+				code.Make(code.OpJmp, 15),
+				// 0009
+				code.Make(code.OpSetGbl, 0),
+				// 0012
+				code.Make(code.OpConst, 1),
+				// 0015
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             "try { 1 } finally { 2 }",
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTry, 0),
+				// 0003
+				code.Make(code.OpConst, 0),
+				// 0006
+				code.Make(code.OpConst, 1),
+				// 0009
+				code.Make(code.OpPop),
+				// 0010
+				code.Make(code.OpEndFin, 0),
+				// 0013
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			// the finally block also runs on the way out by return
+			input: "fn() { try { return 1 } finally { 2 } }",
+			expectedConstants: []interface{}{
+				1,
+				2,
+				2,
+				[]code.Instructions{
+					// 0000
+					code.Make(code.OpTry, 0),
+					// 0003
+					code.Make(code.OpConst, 0),
+					// 0006
+					code.Make(code.OpConst, 1),
+					// 0009
+					code.Make(code.OpPop),
+					// 0010
+					code.Make(code.OpRetVal),
+					// 0011
+					code.Make(code.OpNull),
+					// 0012
+					code.Make(code.OpConst, 2),
+					// 0015
+					code.Make(code.OpPop),
+					// 0016
+					code.Make(code.OpEndFin, 0),
+					// 0019
+					code.Make(code.OpRetVal),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 3, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	handlerTests := []struct {
+		input    string
+		expected code.HandlerTable // of the main program, or of the last fn when there is one
+	}{
+		{"try { 1 } catch (e) { 2 }", code.HandlerTable{
+			{Start: 3, End: 6, Target: 9},
+		}},
+		{"try { 1 } finally { 2 }", code.HandlerTable{
+			{Start: 3, End: 6, Target: 6, Finally: true},
+		}},
+		{"try { 1 } catch (e) { 2 } finally { 3 }", code.HandlerTable{
+			{Start: 3, End: 6, Target: 9},
+			{Start: 3, End: 15, Target: 15, Finally: true},
+		}},
+		{"try { try { 1 } catch (e) { 2 } } catch (e) { 3 }", code.HandlerTable{
+			{Start: 6, End: 9, Target: 12, Slot: 1},
+			{Start: 3, End: 18, Target: 21},
+		}},
+		{"fn() { try { return 1 } finally { 2 } }", code.HandlerTable{
+			{Start: 3, End: 6, Target: 12, Finally: true},
+			{Start: 11, End: 12, Target: 12, Finally: true},
+		}},
+	}
+
+	for _, tt := range handlerTests {
+		compiler := New()
+		if err := compiler.Compile(parse(tt.input)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		bytecode := compiler.Bytecode()
+		handlers := bytecode.Handlers
+		if fn, ok := bytecode.Constants[len(bytecode.Constants)-1].(*object.CompFn); ok {
+			handlers = fn.Handlers
+		}
+		if fmt.Sprint(handlers) != fmt.Sprint(tt.expected) {
+			t.Errorf("wrong handlers for %q. want %v, got %v", tt.input, tt.expected, handlers)
+		}
+	}
+}
+
+func TestGlobalLetStatements(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+let one = 1;
+let two = 2;
+`,
+			expectedConstants: []interface{}{1, 2}, expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpSetGbl, 1),
+			}},
+		{
+			input: `
+let one = 1;
+one;
+`,
+			expectedConstants: []interface{}{1}, expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+let one = 1;
+let two = one;
+two;
+`,
+			expectedConstants: []interface{}{1}, expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpSetGbl, 1),
+				code.Make(code.OpGetGbl, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+let a = 1;
+a = 2;
+`,
+			expectedConstants: []interface{}{1, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+fn() { let a = 1; a += 2 }
+`,
+			expectedConstants: []interface{}{
+				1,
+				2,
+				[]code.Instructions{
+					code.Make(code.OpConst, 0),
+					code.Make(code.OpSetLcl, 0),
+					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpConst, 1),
+					code.Make(code.OpAdd),
+					code.Make(code.OpSetLcl, 0),
+					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpRetVal),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+fn(a) { fn() { a = 3 } }
+`,
+			expectedConstants: []interface{}{
+				3,
+				[]code.Instructions{
+					code.Make(code.OpConst, 0),
+					code.Make(code.OpSetFree, 0),
+					code.Make(code.OpGetFree, 0),
+					code.Make(code.OpRetVal),
+				},
+				[]code.Instructions{
+					code.Make(code.OpBoxLcl, 0),
+					code.Make(code.OpClosure, 1, 1),
+					code.Make(code.OpRetVal),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 2, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+let a = [1];
+a[0] *= 2;
+`,
+			expectedConstants: []interface{}{1, 0, 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpArray, 1),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpDup, 2),
+				code.Make(code.OpIdx),
+				code.Make(code.OpConst, 2),
+				code.Make(code.OpMul),
+				code.Make(code.OpSetIdx),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `
+while (true) { 10; }
+`,
+			expectedConstants: []interface{}{10},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJmpNt, 11),
+				// 0004
+				code.Make(code.OpConst, 0),
+				// 0007
+				code.Make(code.OpPop),
+				// 0008
+				code.Make(code.OpJmp, 0),
+				// 0011
+				code.Make(code.OpNull),
+				// 0012
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+while (true) { break; continue; }
+`,
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpTrue),
+				// 0001
+				code.Make(code.OpJmpNt, 13),
+				// 0004
+				code.Make(code.OpJmp, 13),
+				// 0007
+				code.Make(code.OpJmp, 0),
+				// 0010
+				code.Make(code.OpJmp, 0),
+				// 0013
+				code.Make(code.OpNull),
+				// 0014
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+for (x in [1]) { x; break; }
+`,
+			expectedConstants: []interface{}{1},
+			expectedInstructions: []code.Instructions{
+				// 0000
+				code.Make(code.OpConst, 0),
+				// 0003
+				code.Make(code.OpArray, 1),
+				// 0006
+				code.Make(code.OpIter),
+				// 0007
+				code.Make(code.OpNext, 23),
+				// 0010
+				code.Make(code.OpSetGbl, 0),
+				// 0013
+				code.Make(code.OpGetGbl, 0),
+				// 0016
+				code.Make(code.OpPop),
+				// 0017
+				code.Make(code.OpJmp, 23),
+				// 0020
+				code.Make(code.OpJmp, 7),
+				// 0023
+				code.Make(code.OpPop),
+				// 0024
+				code.Make(code.OpNull),
+				// 0025
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `
+fn() { for (x in []) { } }
+`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpArray, 0),
+					code.Make(code.OpIter),
+					code.Make(code.OpNext, 13),
+					code.Make(code.OpSetLcl, 0),
+					code.Make(code.OpJmp, 4),
+					code.Make(code.OpPop),
+					code.Make(code.OpNull),
+					code.Make(code.OpPop),
+					code.Make(code.OpRet),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestStringExpressions(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `"monkey"`, expectedConstants: []interface{}{"monkey"}, expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `"mon" + "key"`, expectedConstants: []interface{}{"mon", "key"}, expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+	runCompilerTests(t, tests)
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input:             `"a ${1} b ${2}"`,
+			expectedConstants: []interface{}{"a ", 1, " b ", 2},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpConst, 2),
+				code.Make(code.OpConst, 3),
+				code.Make(code.OpConcat, 4),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input:             `"${true}"`,
+			expectedConstants: []interface{}{},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpTrue),
+				code.Make(code.OpConcat, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
+func TestPipeExpressions(t *testing.T) {
+	tests := []struct {
+		piped    string
+		expected string
+	}{
+		{"let f = fn(a, b) { a - b }; 1 |> f(2)", "let f = fn(a, b) { a - b }; f(1, 2)"},
+		{"len([1]) |> puts", "puts(len([1]))"},
+		{"1 |> fn(a) { a }", "fn(a) { a }(1)"},
+	}
+
+	for _, tt := range tests {
+		piped := New()
+		if err := piped.Compile(parse(tt.piped)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+		expected := New()
+		if err := expected.Compile(parse(tt.expected)); err != nil {
+			t.Fatalf("compiler error: %s", err)
+		}
+
+		err := testInstructions([]code.Instructions{expected.Bytecode().Instructions}, piped.Bytecode().Instructions)
+		if err != nil {
+			t.Errorf("%q: %s", tt.piped, err)
+		}
+	}
+}
+
+func TestArrayLiterals(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: "[]", expectedConstants: []interface{}{}, expectedInstructions: []code.Instructions{
+				code.Make(code.OpArray, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: "[1, 2, 3]", expectedConstants: []interface{}{1, 2, 3}, expectedInstructions: []code.Instructions{
 				code.Make(code.OpConst, 0),
 				code.Make(code.OpConst, 1),
 				code.Make(code.OpConst, 2),
@@ -837,6 +1599,65 @@ polyArgs(1, 2, 3, 4);
 	runCompilerTests(t, tests)
 }
 
+func TestFnParameters(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			input: `fn(a, b = 2, ...c) { b }`,
+			expectedConstants: []interface{}{
+				2,
+				[]code.Instructions{
+					code.Make(code.OpJmpSet, 1, 11),
+					code.Make(code.OpConst, 0),
+					code.Make(code.OpSetLcl, 1),
+					code.Make(code.OpGetLcl, 1),
+					code.Make(code.OpRetVal),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 1, 0),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `let f = fn(a, b) { a }; f(1, b: 2)`,
+			expectedConstants: []interface{}{
+				[]code.Instructions{
+					code.Make(code.OpGetLcl, 0),
+					code.Make(code.OpRetVal),
+				},
+				1,
+				"b",
+				2,
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpClosure, 0, 0),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpConst, 2),
+				code.Make(code.OpConst, 3),
+				code.Make(code.OpCallKw, 1, 1),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+
+	comp := New()
+	if err := comp.Compile(parse(`fn(a, b = 2, ...c) { b }`)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	fn := comp.Bytecode().Constants[1].(*object.CompFn)
+	if fn.LocalVarCount != 3 || fn.ParamCount != 2 {
+		t.Errorf("wrong counts, got %d locals and %d params", fn.LocalVarCount, fn.ParamCount)
+	}
+	sig := fn.Signature
+	if len(sig.Params) != 2 || sig.Params[0] != "a" || sig.Params[1] != "b" || sig.Required != 1 || !sig.Variadic {
+		t.Errorf("wrong signature, got %+v", sig)
+	}
+}
+
 func TestBuiltInFn(t *testing.T) {
 	tests := []compilerTestCase{
 		{
@@ -886,9 +1707,127 @@ func concatInstructions(s []code.Instructions) code.Instructions {
 	return out
 }
 
+func TestImports(t *testing.T) {
+	tests := []compilerTestCase{
+		{
+			// the module is compiled in place of its first import, with
+			// globals of its own after those of the importer
+			input: `let a = 5
+import "testdata/lib.crab" as lib
+lib.x + a
+import "testdata/lib.crab" as again
+again.x`,
+			expectedConstants: []interface{}{5, 1, 2, "lib.x", "again.x"},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpSetGbl, 1),
+				code.Make(code.OpGetGbl, 1),
+				code.Make(code.OpConst, 2),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGbl, 2),
+				code.Make(code.OpGetExp, 2, 3),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpAdd),
+				code.Make(code.OpPop),
+				code.Make(code.OpGetExp, 2, 4),
+				code.Make(code.OpPop),
+			},
+		},
+		{
+			input: `import "testdata/lib.crab" as lib
+let b = 1
+fn() { lib.x + b }`,
+			expectedConstants: []interface{}{
+				1, 2, 1, "lib.x",
+				[]code.Instructions{
+					code.Make(code.OpGetExp, 1, 3),
+					code.Make(code.OpGetGbl, 2),
+					code.Make(code.OpAdd),
+					code.Make(code.OpRetVal),
+				},
+			},
+			expectedInstructions: []code.Instructions{
+				code.Make(code.OpConst, 0),
+				code.Make(code.OpSetGbl, 0),
+				code.Make(code.OpGetGbl, 0),
+				code.Make(code.OpConst, 1),
+				code.Make(code.OpAdd),
+				code.Make(code.OpSetGbl, 1),
+				code.Make(code.OpConst, 2),
+				code.Make(code.OpSetGbl, 2),
+				code.Make(code.OpClosure, 4, 0),
+				code.Make(code.OpPop),
+			},
+		},
+	}
+
+	runCompilerTests(t, tests)
+}
+
 func parse(input string) *ast.Program {
 	l := lexer.New(input)
 	p := parser.New(l)
 
 	return p.ParseProgram()
 }
+
+func TestCompilerErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"foo", "1:1: unresolved symbol: foo"},
+		{"let a = 1;\nfn() { a + b }", "2:12: unresolved symbol: b"},
+		{"b = 1", "1:1: unresolved symbol: b"},
+		{"break;", "1:1: break outside loop"},
+		{"while (true) { fn() { continue; } }", "1:23: continue outside loop"},
+		{"len = 1", "1:1: cannot assign to builtin len"},
+		{"let f = fn() { f = 1 };", "1:16: cannot assign to f inside its own definition"},
+		{"puts(1);\nlet a = ;", "2:1: bad statement"},
+		{`puts("\q")`, "1:1: bad statement"},
+		{"try { throw 1 } catch (e) { let inner = 2 }; inner", "1:46: unresolved symbol: inner"},
+		{"let f = fn() {\n  1 +\n}", "2:3: bad statement"},
+		{"match (1) { n => n }; n", "1:23: unresolved symbol: n"},
+		{`import "testdata/missing.crab" as m`, `1:1: cannot find module "testdata/missing.crab" in .`},
+		{`import "testdata/broken.crab" as b`, "1:1: testdata/broken.crab:1:5: expected next token Ident, got ="},
+		{`import "testdata/cycle_a.crab" as a`, "testdata/cycle_b.crab:1:1: import cycle testdata/cycle_a.crab -> testdata/cycle_b.crab -> testdata/cycle_a.crab"},
+		{`import "testdata/lib.crab" as lib; lib.hidden`, "1:39: module lib has no export hidden"},
+		{`import "testdata/lib.crab" as lib; fn() { lib }`, "1:43: module lib can only be used to read its exports, as lib.name"},
+		{`import "testdata/lib.crab" as lib; lib = 1`, "1:36: cannot assign to module lib"},
+		{"let a = 1; a.b", "1:13: a is not a module"},
+	}
+
+	for _, tt := range tests {
+		err := New().Compile(parse(tt.input))
+		if err == nil {
+			t.Fatalf("expected compiler error for %q", tt.input)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want %q, got %q", tt.expected, err)
+		}
+	}
+}
+
+func TestCompileBadNodes(t *testing.T) {
+	tests := []struct {
+		node     ast.Node
+		expected string
+	}{
+		{nil, "cannot compile missing node"},
+		{&ast.ExpressionStatement{}, "cannot compile missing node"},
+		{&ast.BadExpression{Token: token.Token{Pos: token.Position{Line: 1, Column: 5}}}, "1:5: cannot compile <bad expression>"},
+		{&ast.Program{Statements: []ast.Statement{&ast.LetStatement{}}}, "missing node in *ast.LetStatement"},
+	}
+
+	for _, tt := range tests {
+		err := New().Compile(tt.node)
+		if err == nil {
+			t.Fatalf("expected compiler error for %v", tt.node)
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("wrong error. want %q, got %q", tt.expected, err)
+		}
+	}
+}
@@ -6,6 +6,7 @@ replace (
 	crabscript.rs/ast => ../ast
 	crabscript.rs/code => ../code
 	crabscript.rs/lexer => ../lexer
+	crabscript.rs/module => ../module
 	crabscript.rs/object => ../object
 	crabscript.rs/parser => ../parser
 	crabscript.rs/token => ../token
@@ -19,4 +20,6 @@ require (
 	crabscript.rs/parser v0.0.0-00010101000000-000000000000
 )
 
-require crabscript.rs/token v0.0.0-00010101000000-000000000000 // indirect
+require crabscript.rs/token v0.0.0-00010101000000-000000000000
+
+require crabscript.rs/module v0.0.0-00010101000000-000000000000
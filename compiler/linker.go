@@ -0,0 +1,113 @@
+package compiler
+
+import (
+	"errors"
+	"fmt"
+
+	"crabscript.rs/ast"
+	"crabscript.rs/code"
+	"crabscript.rs/module"
+	"crabscript.rs/object"
+)
+
+// The compiler links the modules of a program as it imports them: the code
+// of a module is compiled in place of its first import, adding to the same
+// constant pool, with a symbol table of its own whose globals are numbered
+// after those of the modules compiled before it. Later imports of the module
+// only bind its exports, so it runs once, and the program remains a single
+// set of instructions, constants and globals for the vm.
+
+// SetLoader sets the loader of the modules imported by the program, which
+// should outlive the compiler when the symbol table does, as in the repl
+func (c *Compiler) SetLoader(l *module.Loader) {
+	c.loader = l
+}
+
+func (c *Compiler) compileImport(node *ast.ImportStatement) error {
+	exports, err := c.loader.Load(node.Pos().Filename, node.Path, c.compileModule)
+
+	var importErr *module.Error
+	if errors.As(err, &importErr) {
+		return fmt.Errorf("%s: %s", node.Pos(), err)
+	}
+	if err != nil {
+		return err
+	}
+
+	c.symbolTable.DefineModule(node.Name.Value, exports.(map[string]Symbol))
+	return nil
+}
+
+// compiles the module at the end of the instructions of the current scope,
+// returning the symbols of its exports
+func (c *Compiler) compileModule(program *ast.Program) (interface{}, error) {
+	importer, importerEnd := c.symbolTable, c.moduleEnd
+	defer func() { c.symbolTable, c.moduleEnd = importer, importerEnd }()
+
+	c.symbolTable = NewModuleSymbolTable(importer)
+	for i, v := range object.Builtins {
+		c.symbolTable.DefineBuiltin(i, v.Name)
+	}
+	c.moduleEnd = &[]int{}
+
+	if err := c.Compile(program); err != nil {
+		return nil, err
+	}
+
+	// a return at the top level of a module leaves the module
+	for _, pos := range *c.moduleEnd {
+		c.changeOperand(pos, len(c.currentInstructions()))
+	}
+
+	exports := map[string]Symbol{}
+	for _, st := range program.Statements {
+		let, ok := st.(*ast.LetStatement)
+		if !ok || !let.Export {
+			continue
+		}
+		for _, name := range let.Names() {
+			exports[name.Value], _ = c.symbolTable.Resolve(name.Value)
+		}
+	}
+	return exports, nil
+}
+
+// compiles `return` at the top level of a module into a jump past its end,
+// dropping the returned value
+func (c *Compiler) compileModuleReturn(node *ast.ReturnStatement) error {
+	if node.ReturnValue != nil {
+		if err := c.Compile(node.ReturnValue); err != nil {
+			return err
+		}
+		c.emit(code.OpPop)
+	}
+
+	resume, err := c.leaveTries(0)
+	if err != nil {
+		return err
+	}
+	*c.moduleEnd = append(*c.moduleEnd, c.emit(code.OpJmp, 9999))
+	resume()
+	return nil
+}
+
+// compiles `m.name` into a read of the global the module exports as name,
+// which is unset when a top level return left the module before it
+func (c *Compiler) compileMember(node *ast.MemberExpression) error {
+	mod, ok := node.Left.(*ast.Identifier)
+	if ok {
+		symbol, found := c.symbolTable.Resolve(mod.Value)
+		ok = found && symbol.Scope == ModuleScope
+	}
+	if !ok {
+		return fmt.Errorf("%s: %s is not a module", node.Pos(), node.Left)
+	}
+
+	name := mod.Value + "." + node.Name.Value
+	symbol, ok := c.symbolTable.Resolve(name)
+	if !ok {
+		return fmt.Errorf("%s: module %s has no export %s", node.Pos(), mod.Value, node.Name.Value)
+	}
+	c.emit(code.OpGetExp, symbol.Index, c.addConstant(&object.String{Value: name}))
+	return nil
+}
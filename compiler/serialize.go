@@ -0,0 +1,335 @@
+package compiler
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"hash/crc32"
+	"io"
+	"math"
+	"sort"
+
+	"crabscript.rs/code"
+	"crabscript.rs/object"
+	"crabscript.rs/token"
+)
+
+// On-disk bytecode layout, all integers big endian:
+//
+//	magic    [4]byte  "CRBC"
+//	version  uint16
+//	payload  instructions, source map, handler table, constant pool
+//	checksum uint32   crc32 (IEEE) of the payload
+//
+// Bump BytecodeVersion whenever the payload encoding changes.
+const BytecodeVersion uint16 = 4
+
+var bytecodeMagic = [4]byte{'C', 'R', 'B', 'C'}
+
+// tags identifying the type of each constant in the pool
+const (
+	constInteger byte = iota + 1
+	constString
+	constCompFn
+	constFloat
+)
+
+var ErrNotBytecode = errors.New("not a crabscript bytecode file")
+
+// IsBytecode reports whether data starts with the bytecode magic header
+func IsBytecode(data []byte) bool {
+	return len(data) >= len(bytecodeMagic) && bytes.Equal(data[:len(bytecodeMagic)], bytecodeMagic[:])
+}
+
+// WriteTo serialises the bytecode and its constant pool to w
+func (b *Bytecode) WriteTo(w io.Writer) (int64, error) {
+	payload := &bytes.Buffer{}
+	writeInstructions(payload, b.Instructions)
+	writeSourceMap(payload, b.SourceMap)
+	writeHandlers(payload, b.Handlers)
+
+	writeUint32(payload, uint32(len(b.Constants)))
+	for i, c := range b.Constants {
+		if err := writeConstant(payload, c); err != nil {
+			return 0, fmt.Errorf("constant %d: %w", i, err)
+		}
+	}
+
+	out := &bytes.Buffer{}
+	out.Write(bytecodeMagic[:])
+	_ = binary.Write(out, binary.BigEndian, BytecodeVersion)
+	out.Write(payload.Bytes())
+	writeUint32(out, crc32.ChecksumIEEE(payload.Bytes()))
+
+	return out.WriteTo(w)
+}
+
+// ReadBytecode loads bytecode written by Bytecode.WriteTo, verifying the
+// header, version and checksum
+func ReadBytecode(r io.Reader) (*Bytecode, error) {
+	data, err := io.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+
+	headerLen := len(bytecodeMagic) + 2
+	if !IsBytecode(data) || len(data) < headerLen+4 {
+		return nil, ErrNotBytecode
+	}
+
+	version := binary.BigEndian.Uint16(data[len(bytecodeMagic):])
+	if version != BytecodeVersion {
+		return nil, fmt.Errorf("unsupported bytecode version %d, want %d", version, BytecodeVersion)
+	}
+
+	payload := data[headerLen : len(data)-4]
+	checksum := binary.BigEndian.Uint32(data[len(data)-4:])
+	if crc32.ChecksumIEEE(payload) != checksum {
+		return nil, fmt.Errorf("bytecode checksum mismatch")
+	}
+
+	d := &decoder{buf: payload}
+	bytecode := &Bytecode{
+		Instructions: d.instructions(),
+		SourceMap:    d.sourceMap(),
+	}
+	bytecode.Handlers = d.handlers(bytecode.Instructions)
+
+	numConstants := d.uint32()
+	for i := uint32(0); i < numConstants && d.err == nil; i++ {
+		bytecode.Constants = append(bytecode.Constants, d.constant())
+	}
+
+	if d.err == nil && len(d.buf) != 0 {
+		d.err = fmt.Errorf("%d trailing bytes", len(d.buf))
+	}
+	if d.err != nil {
+		return nil, fmt.Errorf("malformed bytecode: %w", d.err)
+	}
+	return bytecode, nil
+}
+
+func writeConstant(buf *bytes.Buffer, obj object.Object) error {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		buf.WriteByte(constInteger)
+		writeUint64(buf, uint64(obj.Value))
+
+	case *object.Float:
+		buf.WriteByte(constFloat)
+		writeUint64(buf, math.Float64bits(obj.Value))
+
+	case *object.String:
+		buf.WriteByte(constString)
+		writeString(buf, obj.Value)
+
+	case *object.CompFn:
+		buf.WriteByte(constCompFn)
+		writeInstructions(buf, obj.Instructions)
+		writeSourceMap(buf, obj.SourceMap)
+		writeHandlers(buf, obj.Handlers)
+		writeUint32(buf, uint32(obj.LocalVarCount))
+		writeUint32(buf, uint32(obj.ParamCount))
+		writeString(buf, obj.Name)
+		writeSignature(buf, obj.Signature)
+
+	default:
+		return fmt.Errorf("cannot serialise constant of type %s", obj.Type())
+	}
+	return nil
+}
+
+func writeInstructions(buf *bytes.Buffer, ins code.Instructions) {
+	writeUint32(buf, uint32(len(ins)))
+	buf.Write(ins)
+}
+
+func writeSourceMap(buf *bytes.Buffer, sm code.SourceMap) {
+	// write entries in instruction order so output is deterministic
+	offsets := make([]int, 0, len(sm))
+	for ip := range sm {
+		offsets = append(offsets, ip)
+	}
+	sort.Ints(offsets)
+
+	writeUint32(buf, uint32(len(offsets)))
+	for _, ip := range offsets {
+		pos := sm[ip]
+		writeUint32(buf, uint32(ip))
+		writeString(buf, pos.Filename)
+		writeUint32(buf, uint32(pos.Offset))
+		writeUint32(buf, uint32(pos.Line))
+		writeUint32(buf, uint32(pos.Column))
+	}
+}
+
+func writeHandlers(buf *bytes.Buffer, handlers code.HandlerTable) {
+	writeUint32(buf, uint32(len(handlers)))
+	for _, h := range handlers {
+		writeUint32(buf, uint32(h.Start))
+		writeUint32(buf, uint32(h.End))
+		writeUint32(buf, uint32(h.Target))
+		writeUint32(buf, uint32(h.Slot))
+		if h.Finally {
+			buf.WriteByte(1)
+		} else {
+			buf.WriteByte(0)
+		}
+	}
+}
+
+func writeSignature(buf *bytes.Buffer, sig object.Signature) {
+	writeUint32(buf, uint32(len(sig.Params)))
+	for _, param := range sig.Params {
+		writeString(buf, param)
+	}
+	writeUint32(buf, uint32(sig.Required))
+	if sig.Variadic {
+		buf.WriteByte(1)
+	} else {
+		buf.WriteByte(0)
+	}
+}
+
+func writeString(buf *bytes.Buffer, s string) {
+	writeUint32(buf, uint32(len(s)))
+	buf.WriteString(s)
+}
+
+func writeUint32(buf *bytes.Buffer, v uint32) {
+	_ = binary.Write(buf, binary.BigEndian, v)
+}
+
+func writeUint64(buf *bytes.Buffer, v uint64) {
+	_ = binary.Write(buf, binary.BigEndian, v)
+}
+
+// decoder reads the payload, remembering the first error so callers only
+// need to check once
+type decoder struct {
+	buf []byte
+	err error
+}
+
+func (d *decoder) take(n int) []byte {
+	if d.err != nil {
+		return nil
+	}
+	if n < 0 || n > len(d.buf) {
+		d.err = io.ErrUnexpectedEOF
+		return nil
+	}
+	b := d.buf[:n]
+	d.buf = d.buf[n:]
+	return b
+}
+
+func (d *decoder) byte() byte {
+	if b := d.take(1); b != nil {
+		return b[0]
+	}
+	return 0
+}
+
+func (d *decoder) uint32() uint32 {
+	if b := d.take(4); b != nil {
+		return binary.BigEndian.Uint32(b)
+	}
+	return 0
+}
+
+func (d *decoder) uint64() uint64 {
+	if b := d.take(8); b != nil {
+		return binary.BigEndian.Uint64(b)
+	}
+	return 0
+}
+
+func (d *decoder) string() string {
+	return string(d.take(int(d.uint32())))
+}
+
+func (d *decoder) instructions() code.Instructions {
+	ins := d.take(int(d.uint32()))
+	return append(code.Instructions{}, ins...)
+}
+
+func (d *decoder) sourceMap() code.SourceMap {
+	n := d.uint32()
+	sm := code.SourceMap{}
+	for i := uint32(0); i < n && d.err == nil; i++ {
+		ip := int(d.uint32())
+		pos := token.Position{Filename: d.string()}
+		pos.Offset = int(d.uint32())
+		pos.Line = int(d.uint32())
+		pos.Column = int(d.uint32())
+		sm[ip] = pos
+	}
+	return sm
+}
+
+// handlers reads the handler table of the instructions ins, which the range
+// and target of every handler must fall inside
+func (d *decoder) handlers(ins code.Instructions) code.HandlerTable {
+	var handlers code.HandlerTable
+	n := d.uint32()
+	for i := uint32(0); i < n && d.err == nil; i++ {
+		h := code.Handler{}
+		h.Start = int(d.uint32())
+		h.End = int(d.uint32())
+		h.Target = int(d.uint32())
+		h.Slot = int(d.uint32())
+		h.Finally = d.byte() == 1
+		if d.err == nil && (h.Start < 0 || h.Start > h.End || h.End > len(ins) || h.Target < 0 || h.Target >= len(ins)) {
+			d.err = fmt.Errorf("handler [%d, %d) -> %d outside %d bytes of instructions", h.Start, h.End, h.Target, len(ins))
+		}
+		handlers = append(handlers, h)
+	}
+	return handlers
+}
+
+func (d *decoder) signature() object.Signature {
+	sig := object.Signature{}
+	n := d.uint32()
+	for i := uint32(0); i < n && d.err == nil; i++ {
+		sig.Params = append(sig.Params, d.string())
+	}
+	sig.Required = int(d.uint32())
+	sig.Variadic = d.byte() == 1
+	if d.err == nil && (sig.Required < 0 || sig.Required > len(sig.Params)) {
+		d.err = fmt.Errorf("signature requires %d of %d params", sig.Required, len(sig.Params))
+	}
+	return sig
+}
+
+func (d *decoder) constant() object.Object {
+	switch tag := d.byte(); tag {
+	case constInteger:
+		return &object.Integer{Value: int64(d.uint64())}
+
+	case constFloat:
+		return &object.Float{Value: math.Float64frombits(d.uint64())}
+
+	case constString:
+		return &object.String{Value: d.string()}
+
+	case constCompFn:
+		fn := &object.CompFn{
+			Instructions: d.instructions(),
+			SourceMap:    d.sourceMap(),
+		}
+		fn.Handlers = d.handlers(fn.Instructions)
+		fn.LocalVarCount = int(d.uint32())
+		fn.ParamCount = int(d.uint32())
+		fn.Name = d.string()
+		fn.Signature = d.signature()
+		return fn
+
+	default:
+		if d.err == nil {
+			d.err = fmt.Errorf("unknown constant tag %d", tag)
+		}
+		return nil
+	}
+}
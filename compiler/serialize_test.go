@@ -0,0 +1,168 @@
+package compiler
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"crabscript.rs/code"
+	"crabscript.rs/object"
+)
+
+func compileBytecode(t *testing.T, input string) *Bytecode {
+	t.Helper()
+
+	comp := New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+	return comp.Bytecode()
+}
+
+func TestBytecodeRoundTrip(t *testing.T) {
+	input := `
+let greeting = "hello";
+let ratio = 0.75;
+let adder = fn(a, b) { let c = a + b; fn(d) { c + d } };
+adder(1, -2)(3);
+let opts = fn(a, b = 2, ...rest) { a + b };
+opts(1, b: 3);
+let safe = fn(f) { try { f() } catch (e) { e["message"] } finally { puts("done") } };
+try { throw "oops" } catch (e) { safe(e) };
+`
+	original := compileBytecode(t, input)
+
+	var buf bytes.Buffer
+	if _, err := original.WriteTo(&buf); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+
+	if !IsBytecode(buf.Bytes()) {
+		t.Fatalf("written bytecode has no magic header")
+	}
+
+	loaded, err := ReadBytecode(&buf)
+	if err != nil {
+		t.Fatalf("read failed: %s", err)
+	}
+
+	if err := testInstructions([]code.Instructions{original.Instructions}, loaded.Instructions); err != nil {
+		t.Fatalf("instructions differ: %s", err)
+	}
+
+	if len(loaded.SourceMap) != len(original.SourceMap) {
+		t.Fatalf("source map has wrong length, got %d want %d", len(loaded.SourceMap), len(original.SourceMap))
+	}
+	for ip, pos := range original.SourceMap {
+		if loaded.SourceMap[ip] != pos {
+			t.Errorf("source map entry %d wrong, got %s want %s", ip, loaded.SourceMap[ip], pos)
+		}
+	}
+
+	if fmt.Sprint(loaded.Handlers) != fmt.Sprint(original.Handlers) {
+		t.Errorf("handlers wrong, got %v want %v", loaded.Handlers, original.Handlers)
+	}
+
+	if len(loaded.Constants) != len(original.Constants) {
+		t.Fatalf("wrong number of constants, got %d want %d", len(loaded.Constants), len(original.Constants))
+	}
+	for i, want := range original.Constants {
+		got := loaded.Constants[i]
+		if got.Type() != want.Type() {
+			t.Fatalf("constant %d has wrong type, got %s want %s", i, got.Type(), want.Type())
+		}
+
+		switch want := want.(type) {
+		case *object.CompFn:
+			fn := got.(*object.CompFn)
+			if fn.LocalVarCount != want.LocalVarCount || fn.ParamCount != want.ParamCount {
+				t.Errorf("constant %d has wrong counts, got %d/%d want %d/%d",
+					i, fn.LocalVarCount, fn.ParamCount, want.LocalVarCount, want.ParamCount)
+			}
+			if fn.Name != want.Name {
+				t.Errorf("constant %d has wrong name, got %q want %q", i, fn.Name, want.Name)
+			}
+			if fmt.Sprint(fn.Handlers) != fmt.Sprint(want.Handlers) {
+				t.Errorf("constant %d has wrong handlers, got %v want %v", i, fn.Handlers, want.Handlers)
+			}
+			if fmt.Sprint(fn.Signature) != fmt.Sprint(want.Signature) {
+				t.Errorf("constant %d has wrong signature, got %+v want %+v", i, fn.Signature, want.Signature)
+			}
+			if fn.Instructions.String() != want.Instructions.String() {
+				t.Errorf("constant %d has wrong instructions,\ngot %s\nwant %s", i, fn.Instructions, want.Instructions)
+			}
+		default:
+			if got.Inspect() != want.Inspect() {
+				t.Errorf("constant %d wrong, got %s want %s", i, got.Inspect(), want.Inspect())
+			}
+		}
+	}
+}
+
+func TestReadBytecodeRejectsBadInput(t *testing.T) {
+	var buf bytes.Buffer
+	if _, err := compileBytecode(t, `let a = "crab"; a`).WriteTo(&buf); err != nil {
+		t.Fatalf("write failed: %s", err)
+	}
+	valid := buf.Bytes()
+
+	corrupt := append([]byte{}, valid...)
+	corrupt[len(corrupt)/2] ^= 0xff
+
+	wrongVersion := append([]byte{}, valid...)
+	wrongVersion[5]++
+
+	written := func(b *Bytecode) []byte {
+		var buf bytes.Buffer
+		if _, err := b.WriteTo(&buf); err != nil {
+			t.Fatalf("write failed: %s", err)
+		}
+		return buf.Bytes()
+	}
+	ins := code.Make(code.OpNull)
+	badSignature := written(&Bytecode{
+		Instructions: ins,
+		Constants: []object.Object{&object.CompFn{
+			Instructions: ins,
+			Signature:    object.Signature{Params: []string{"a"}, Required: 2},
+		}},
+	})
+	badRange := written(&Bytecode{
+		Instructions: ins,
+		Handlers:     code.HandlerTable{{Start: 0, End: 5, Target: 0}},
+	})
+	badTarget := written(&Bytecode{
+		Instructions: ins,
+		Constants: []object.Object{&object.CompFn{
+			Instructions: ins,
+			Handlers:     code.HandlerTable{{Start: 0, End: 1, Target: 1}},
+		}},
+	})
+
+	tests := []struct {
+		name     string
+		input    []byte
+		expected string
+	}{
+		{"empty", []byte{}, "not a crabscript bytecode file"},
+		{"source", []byte("let a = 1;"), "not a crabscript bytecode file"},
+		{"version", wrongVersion, "unsupported bytecode version 5, want 4"},
+		{"checksum", corrupt, "bytecode checksum mismatch"},
+		{"truncated", valid[:len(valid)-6], "bytecode checksum mismatch"},
+		{"signature", badSignature, "signature requires 2 of 1 params"},
+		{"handler range", badRange, "handler [0, 5) -> 0 outside 1 bytes of instructions"},
+		{"handler target", badTarget, "handler [0, 1) -> 1 outside 1 bytes of instructions"},
+	}
+
+	for _, tt := range tests {
+		_, err := ReadBytecode(bytes.NewReader(tt.input))
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: wrong error, got %q want %q", tt.name, err, tt.expected)
+		}
+	}
+}
@@ -1,12 +1,16 @@
 package compiler
 
+import "strings"
+
 type SymbolScope string
 
 const (
-	GlobalScope  SymbolScope = "Global"
-	LocalScope   SymbolScope = "Local"
-	BuiltinScope SymbolScope = "Builtin"
-	FreeScope    SymbolScope = "Free"
+	GlobalScope   SymbolScope = "Global"
+	LocalScope    SymbolScope = "Local"
+	BuiltinScope  SymbolScope = "Builtin"
+	FreeScope     SymbolScope = "Free"
+	FunctionScope SymbolScope = "Function"
+	ModuleScope   SymbolScope = "Module"
 )
 
 type Symbol struct {
@@ -20,6 +24,16 @@ type SymbolTable struct {
 	store          map[string]Symbol
 	numDefinitions int
 	FreeSymbols    []Symbol
+
+	// globals defined so far by the modules of the program, shared by their
+	// tables so that each module gets indices of its own
+	globals *int
+
+	// for the table of a block, that of the fn or program the block is in,
+	// which numbers the block's definitions among its own
+	enclosing *SymbolTable
+
+	temps []Symbol // slots given back by ReleaseTemp, for DefineTemp to reuse
 }
 
 func NewSymbolTable() *SymbolTable {
@@ -33,7 +47,68 @@ func NewEnclosedSymbolTable(outer *SymbolTable) *SymbolTable {
 	return s
 }
 
+// NewModuleSymbolTable returns the table of a module imported by the
+// program of importer, whose globals are numbered after all those defined so
+// far by the program and its other modules
+func NewModuleSymbolTable(importer *SymbolTable) *SymbolTable {
+	if importer.globals == nil {
+		n := importer.numDefinitions
+		importer.globals = &n
+	}
+
+	s := NewSymbolTable()
+	s.globals = importer.globals
+	return s
+}
+
+// NewBlockSymbolTable returns the table of a block inside the scope of
+// outer, whose definitions shadow those of outer until the block ends
+func NewBlockSymbolTable(outer *SymbolTable) *SymbolTable {
+	s := NewEnclosedSymbolTable(outer)
+	s.enclosing = outer
+	if outer.enclosing != nil {
+		s.enclosing = outer.enclosing
+	}
+	return s
+}
+
 func (s *SymbolTable) Define(name string) Symbol {
+	symbol := s.owner().allocate(name)
+	s.store[name] = symbol
+	return symbol
+}
+
+// DefineTemp returns a slot for a value the compiler keeps while compiling
+// a construct, such as the subject of a match, which scripts can't name.
+// Nothing captures a temp, so it reuses a slot given back by ReleaseTemp.
+func (s *SymbolTable) DefineTemp() Symbol {
+	owner := s.owner()
+	if n := len(owner.temps); n > 0 {
+		symbol := owner.temps[n-1]
+		owner.temps = owner.temps[:n-1]
+		return symbol
+	}
+	return owner.allocate("<temp>")
+}
+
+// ReleaseTemp gives back the slot of a temp once the construct using it is
+// compiled
+func (s *SymbolTable) ReleaseTemp(symbol Symbol) {
+	owner := s.owner()
+	owner.temps = append(owner.temps, symbol)
+}
+
+// the table of the fn or program whose slots the definitions of s take,
+// s itself unless it is the table of a block
+func (s *SymbolTable) owner() *SymbolTable {
+	if s.enclosing != nil {
+		return s.enclosing
+	}
+	return s
+}
+
+// takes the next slot of the fn or program of s
+func (s *SymbolTable) allocate(name string) Symbol {
 	symbol := Symbol{
 		Name:  name,
 		Scope: GlobalScope,
@@ -43,11 +118,12 @@ func (s *SymbolTable) Define(name string) Symbol {
 	if s.Outer != nil {
 		// we are in global (most outer scope)
 		symbol.Scope = LocalScope
+	} else if s.globals != nil {
+		symbol.Index = *s.globals
+		*s.globals++
 	}
 
-	s.store[name] = symbol
 	s.numDefinitions++
-
 	return symbol
 }
 
@@ -60,7 +136,12 @@ func (s *SymbolTable) Resolve(name string) (Symbol, bool) {
 			return obj, ok
 		}
 
-		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope {
+		// a block shares the frame of the scope around it
+		if s.enclosing != nil {
+			return obj, ok
+		}
+
+		if obj.Scope == GlobalScope || obj.Scope == BuiltinScope || obj.Scope == ModuleScope {
 			return obj, ok
 		}
 
@@ -81,6 +162,36 @@ func (s *SymbolTable) DefineBuiltin(index int, name string) Symbol {
 	return sym
 }
 
+// DefineModule binds name to an imported module, whose exports then resolve
+// as name.export
+func (s *SymbolTable) DefineModule(name string, exports map[string]Symbol) Symbol {
+	for key := range s.store {
+		if strings.HasPrefix(key, name+".") {
+			delete(s.store, key)
+		}
+	}
+
+	sym := Symbol{Name: name, Scope: ModuleScope}
+	s.store[name] = sym
+	for export, symbol := range exports {
+		s.store[name+"."+export] = symbol
+	}
+	return sym
+}
+
+// DefineFunctionName binds the name of the fn being compiled so it can
+// refer to itself, any other definition with the same name shadows it
+func (s *SymbolTable) DefineFunctionName(name string) Symbol {
+	sym := Symbol{
+		Name:  name,
+		Index: 0,
+		Scope: FunctionScope,
+	}
+
+	s.store[name] = sym
+	return sym
+}
+
 func (s *SymbolTable) DefineFree(original Symbol) Symbol {
 	s.FreeSymbols = append(s.FreeSymbols, original)
 
@@ -268,6 +268,33 @@ func TestResolveFree(t *testing.T) {
 	}
 }
 
+func TestDefineAndResolveFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+	expected := Symbol{Name: "a", Scope: FunctionScope, Index: 0}
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
+func TestShadowingFunctionName(t *testing.T) {
+	global := NewSymbolTable()
+	global.DefineFunctionName("a")
+	global.Define("a")
+	expected := Symbol{Name: "a", Scope: GlobalScope, Index: 0}
+	result, ok := global.Resolve(expected.Name)
+	if !ok {
+		t.Fatalf("function name %s not resolvable", expected.Name)
+	}
+	if result != expected {
+		t.Errorf("expected %s to resolve to %+v, got=%+v", expected.Name, expected, result)
+	}
+}
+
 func TestResolveUnresolvableFree(t *testing.T) {
 	global := NewSymbolTable()
 	global.Define("a")
@@ -303,3 +330,112 @@ func TestResolveUnresolvableFree(t *testing.T) {
 		}
 	}
 }
+
+func TestModuleSymbolTables(t *testing.T) {
+	main := NewSymbolTable()
+	main.Define("a")
+	mod := NewModuleSymbolTable(main)
+	x := mod.Define("x")
+	main.Define("b")
+	nested := NewModuleSymbolTable(mod)
+	nested.Define("y")
+
+	expected := []struct {
+		table *SymbolTable
+		sym   Symbol
+	}{
+		{main, Symbol{Name: "a", Scope: GlobalScope, Index: 0}},
+		{mod, Symbol{Name: "x", Scope: GlobalScope, Index: 1}},
+		{main, Symbol{Name: "b", Scope: GlobalScope, Index: 2}},
+		{nested, Symbol{Name: "y", Scope: GlobalScope, Index: 3}},
+	}
+	for _, tt := range expected {
+		result, ok := tt.table.Resolve(tt.sym.Name)
+		if !ok || result != tt.sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.sym.Name, tt.sym, result)
+		}
+	}
+	if _, ok := mod.Resolve("a"); ok {
+		t.Errorf("globals of the importer resolved in the module")
+	}
+
+	main.DefineModule("m", map[string]Symbol{"x": x})
+	local := NewEnclosedSymbolTable(main)
+	if result, _ := local.Resolve("m"); result != (Symbol{Name: "m", Scope: ModuleScope}) {
+		t.Errorf("expected m to resolve to the module, got=%+v", result)
+	}
+	if result, _ := local.Resolve("m.x"); result != x {
+		t.Errorf("expected m.x to resolve to %+v, got=%+v", x, result)
+	}
+	if len(local.FreeSymbols) != 0 {
+		t.Errorf("module captured as free symbols %+v", local.FreeSymbols)
+	}
+
+	main.DefineModule("m", map[string]Symbol{})
+	if _, ok := main.Resolve("m.x"); ok {
+		t.Errorf("export of a module rebound to m resolved")
+	}
+}
+
+func TestBlockSymbolTables(t *testing.T) {
+	global := NewSymbolTable()
+	global.Define("e")
+	block := NewBlockSymbolTable(global)
+	block.Define("e")
+	global.Define("a")
+
+	local := NewEnclosedSymbolTable(global)
+	local.Define("x")
+	inner := NewBlockSymbolTable(NewBlockSymbolTable(local))
+	inner.Define("x")
+
+	expected := []struct {
+		table *SymbolTable
+		sym   Symbol
+	}{
+		{global, Symbol{Name: "e", Scope: GlobalScope, Index: 0}},
+		{block, Symbol{Name: "e", Scope: GlobalScope, Index: 1}},
+		{block, Symbol{Name: "a", Scope: GlobalScope, Index: 2}},
+		{local, Symbol{Name: "x", Scope: LocalScope, Index: 0}},
+		{inner, Symbol{Name: "x", Scope: LocalScope, Index: 1}},
+	}
+	for _, tt := range expected {
+		result, ok := tt.table.Resolve(tt.sym.Name)
+		if !ok || result != tt.sym {
+			t.Errorf("expected %s to resolve to %+v, got=%+v", tt.sym.Name, tt.sym, result)
+		}
+	}
+
+	if local.numDefinitions != 2 {
+		t.Errorf("wrong number of locals. want 2, got=%d", local.numDefinitions)
+	}
+	if result, _ := inner.Resolve("e"); result != (Symbol{Name: "e", Scope: GlobalScope, Index: 0}) {
+		t.Errorf("expected e to resolve to the global outside the block, got=%+v", result)
+	}
+	if len(inner.FreeSymbols) != 0 {
+		t.Errorf("block captured as free symbols %+v", inner.FreeSymbols)
+	}
+}
+
+func TestTempSymbols(t *testing.T) {
+	local := NewEnclosedSymbolTable(NewSymbolTable())
+	first := NewBlockSymbolTable(local).DefineTemp()
+	nested := local.DefineTemp()
+	local.ReleaseTemp(nested)
+	local.ReleaseTemp(first)
+	local.Define("x")
+	reused := NewBlockSymbolTable(local).DefineTemp()
+
+	if first != (Symbol{Name: "<temp>", Scope: LocalScope, Index: 0}) {
+		t.Errorf("wrong first temp. got=%+v", first)
+	}
+	if nested.Index != 1 {
+		t.Errorf("temp in use was handed out again. got=%+v", nested)
+	}
+	if reused != first {
+		t.Errorf("released temp not reused. want %+v, got=%+v", first, reused)
+	}
+	if local.numDefinitions != 3 {
+		t.Errorf("wrong number of locals. want 3, got=%d", local.numDefinitions)
+	}
+}
@@ -3,35 +3,85 @@ package evaluator
 import (
 	"crabscript.rs/ast"
 	"crabscript.rs/object"
+	"crabscript.rs/token"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 )
 
 func Eval(node ast.Node, env *object.Environment) object.Object {
+	if node == nil {
+		return newError("cannot evaluate missing node")
+	}
+
+	obj := eval(node, env)
+
+	// tag errors with the innermost node they came out of
+	if err, ok := obj.(*object.Error); ok && !err.Pos.IsValid() {
+		err.Pos = node.Pos()
+	}
+	return obj
+}
+
+func eval(node ast.Node, env *object.Environment) object.Object {
 	switch node := node.(type) {
 	// Statements
 	case *ast.Program:
+		// refuse programs that failed to parse before running anything
+		var badNode *ast.BadNodeError
+		if err := ast.Validate(node); errors.As(err, &badNode) {
+			return &object.Error{Message: badNode.Msg, Pos: badNode.Pos}
+		}
 		return evalProgram(node, env)
+	case *ast.BadStatement, *ast.BadExpression:
+		return newError("cannot evaluate %s", node)
 	case *ast.ExpressionStatement:
 		return Eval(node.Expression, env)
 	case *ast.BlockStatement:
 		return evalBlockStatement(node, env)
 	case *ast.ReturnStatement:
+		if node.ReturnValue == nil {
+			return &object.ReturnValue{Value: Null}
+		}
 		eval := Eval(node.ReturnValue, env)
 		if isError(eval) {
 			return eval
 		}
 		return &object.ReturnValue{Value: eval}
+	case *ast.ThrowStatement:
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+		return object.Thrown(val)
+	case *ast.WhileStatement:
+		return evalWhileStatement(node, env)
+	case *ast.ForInStatement:
+		return evalForInStatement(node, env)
+	case *ast.BreakStatement:
+		return &object.Break{Pos: node.Pos()}
+	case *ast.ContinueStatement:
+		return &object.Continue{Pos: node.Pos()}
 	case *ast.LetStatement:
 		val := Eval(node.Value, env)
 		if isError(val) {
 			return val
 		}
+		if node.Pattern != nil {
+			if err := evalDestructure(node.Pattern, val, env); err != nil {
+				return err
+			}
+			break
+		}
 		// adding / modifying val on heap
 		env.Set(node.Name.String(), val)
 
 	// Expressions
 	case *ast.IntegerLiteral:
 		return &object.Integer{Value: node.Value}
+	case *ast.FloatLiteral:
+		return &object.Float{Value: node.Value}
 	case *ast.Boolean:
 		return boolToObject(node.Value)
 	case *ast.PrefixExpression:
@@ -45,10 +95,17 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if isError(left) {
 			return left
 		}
+		// short-circuit, the right side only runs when it decides the result
+		if node.Operator == "&&" && !isTruthy(left) || node.Operator == "||" && isTruthy(left) {
+			return left
+		}
 		right := Eval(node.Right, env)
 		if isError(right) {
 			return right
 		}
+		if node.Operator == "&&" || node.Operator == "||" {
+			return right
+		}
 		return evalInfixExpression(node.Operator, left, right)
 	case *ast.IfExpression:
 		return evalIfExpression(node, env)
@@ -57,7 +114,7 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 	case *ast.FunctionLiteral:
 		params := node.Parameters
 		body := node.Body
-		return &object.Function{Parameters: params, Body: body, Env: env}
+		return &object.Function{Parameters: params, Defaults: node.Defaults, Rest: node.Rest, Body: body, Env: env}
 	case *ast.CallExpression:
 		function := Eval(node.Function, env)
 		if isError(function) {
@@ -68,9 +125,27 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 		if len(args) == 1 && isError(args[0]) {
 			return args[0]
 		}
-		return callFunction(function, args)
+
+		names := make([]string, len(node.Named))
+		named := make([]object.Object, len(node.Named))
+		for i, arg := range node.Named {
+			names[i] = arg.Name.Value
+			named[i] = Eval(arg.Value, env)
+			if isError(named[i]) {
+				return named[i]
+			}
+		}
+		result := callFunction(function, args, names, named)
+		// errors from inside the fn already have a position, record the
+		// call they unwound through
+		if err, ok := result.(*object.Error); ok && err.Pos.IsValid() {
+			err.Trace = append(err.Trace, node.Pos())
+		}
+		return result
 	case *ast.StringLiteral:
 		return &object.String{Value: node.Value}
+	case *ast.InterpolatedString:
+		return evalInterpolatedString(node, env)
 	case *ast.ArrayLiteral:
 		elements := evalExpressions(node.Elements, env)
 		if len(elements) == 1 && isError(elements[0]) {
@@ -87,13 +162,189 @@ func Eval(node ast.Node, env *object.Environment) object.Object {
 			return index
 		}
 		return evalIndexExpression(left, index)
+	case *ast.SliceExpression:
+		return evalSliceExpression(node, env)
+	case *ast.MatchExpression:
+		return evalMatchExpression(node, env)
+	case *ast.TryExpression:
+		return evalTryExpression(node, env)
+	case *ast.ImportStatement:
+		return evalImportStatement(node, env)
+	case *ast.MemberExpression:
+		return evalMemberExpression(node, env)
 	case *ast.DictLiteral:
 		return evalDictLiteral(node, env)
+	case *ast.AssignExpression:
+		return evalAssignExpression(node, env)
 	}
 
 	return nil
 }
 
+// binds the names of a let pattern to the parts of val, missing elements
+// and keys are bound to null
+func evalDestructure(pattern ast.Expression, val object.Object, env *object.Environment) object.Object {
+	switch pattern := pattern.(type) {
+	case *ast.ArrayPattern:
+		arr, ok := val.(*object.Array)
+		if !ok {
+			return newError("cannot destructure %s as an array", val.Type())
+		}
+		for i, name := range pattern.Elements {
+			if i < len(arr.Elements) {
+				env.Set(name.Value, arr.Elements[i])
+			} else {
+				env.Set(name.Value, Null)
+			}
+		}
+		if pattern.Rest != nil {
+			rest := []object.Object{}
+			if len(arr.Elements) > len(pattern.Elements) {
+				rest = append(rest, arr.Elements[len(pattern.Elements):]...)
+			}
+			env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+		}
+
+	case *ast.DictPattern:
+		dict, ok := val.(*object.Dict)
+		if !ok {
+			return newError("cannot destructure %s as a dict", val.Type())
+		}
+		for _, name := range pattern.Keys {
+			key := &object.String{Value: name.Value}
+			if pair, ok := dict.Pairs[key.DictKey()]; ok {
+				env.Set(name.Value, pair.Value)
+			} else {
+				env.Set(name.Value, Null)
+			}
+		}
+
+	default:
+		return newError("unknown pattern: %T", pattern)
+	}
+
+	return nil
+}
+
+// evaluates the body, and the catch block if the body raised an error, and
+// then the finally block. A finally block that returns, breaks, continues or
+// raises an error takes over from the try.
+func evalTryExpression(node *ast.TryExpression, env *object.Environment) object.Object {
+	result := Eval(node.Body, env)
+
+	if err, ok := result.(*object.Error); ok && node.Catch != nil {
+		// the catch block has a scope of its own holding the param, which
+		// shadows a name of the enclosing scope rather than assigning it
+		trace := append([]token.Position{err.Pos}, err.Trace...)
+		catchEnv := object.NewEnclosedEnvironment(env)
+		catchEnv.Set(node.Param.Value, err.Dict(trace))
+		result = Eval(node.Catch, catchEnv)
+	}
+
+	if node.Finally != nil {
+		switch finally := Eval(node.Finally, env); finally.(type) {
+		case *object.ReturnValue, *object.Error, *object.Exit, *object.Break, *object.Continue:
+			return finally
+		}
+	}
+
+	return blockValue(result)
+}
+
+// evaluates the body of the first arm whose pattern matches the subject and
+// whose guard holds. Each arm has a scope of its own, where the names in its
+// pattern are bound as they match and its guard and body are evaluated, so
+// they shadow names of env rather than assigning them.
+func evalMatchExpression(node *ast.MatchExpression, env *object.Environment) object.Object {
+	subject := Eval(node.Subject, env)
+	if isError(subject) {
+		return subject
+	}
+
+	for _, arm := range node.Arms {
+		armEnv := object.NewEnclosedEnvironment(env)
+		matched, err := matchPattern(arm.Pattern, subject, armEnv)
+		if err != nil {
+			return err
+		}
+		if !matched {
+			continue
+		}
+
+		if arm.Guard != nil {
+			guard := Eval(arm.Guard, armEnv)
+			if isError(guard) {
+				return guard
+			}
+			if !isTruthy(guard) {
+				continue
+			}
+		}
+
+		return Eval(arm.Body, armEnv)
+	}
+
+	return newError("no match arm for %s", subject.Inspect())
+}
+
+func matchPattern(pattern ast.Expression, val object.Object, env *object.Environment) (bool, object.Object) {
+	switch pattern := pattern.(type) {
+	case *ast.Identifier:
+		if pattern.Value != "_" {
+			env.Set(pattern.Value, val)
+		}
+		return true, nil
+
+	case *ast.ArrayShape:
+		arr, ok := val.(*object.Array)
+		if !ok {
+			return false, nil
+		}
+		n := len(pattern.Elements)
+		if len(arr.Elements) < n || pattern.Rest == nil && len(arr.Elements) != n {
+			return false, nil
+		}
+		for i, el := range pattern.Elements {
+			if matched, err := matchPattern(el, arr.Elements[i], env); !matched || err != nil {
+				return false, err
+			}
+		}
+		if pattern.Rest != nil && pattern.Rest.Value != "_" {
+			rest := append([]object.Object{}, arr.Elements[n:]...)
+			env.Set(pattern.Rest.Value, &object.Array{Elements: rest})
+		}
+		return true, nil
+
+	case *ast.DictShape:
+		dict, ok := val.(*object.Dict)
+		if !ok {
+			return false, nil
+		}
+		values := make([]object.Object, len(pattern.Keys))
+		for i, key := range pattern.Keys {
+			pair, ok := dict.Pairs[(&object.String{Value: key}).DictKey()]
+			if !ok {
+				return false, nil
+			}
+			values[i] = pair.Value
+		}
+		for i, value := range pattern.Values {
+			if matched, err := matchPattern(value, values[i], env); !matched || err != nil {
+				return false, err
+			}
+		}
+		return true, nil
+
+	default:
+		// a literal
+		lit := Eval(pattern, env)
+		if isError(lit) {
+			return false, lit
+		}
+		return object.Equal(lit, val), nil
+	}
+}
+
 func evalDictLiteral(node *ast.DictLiteral, env *object.Environment) object.Object {
 	pairs := make(map[object.DictKey]object.DictPair)
 
@@ -131,6 +382,31 @@ func evalIndexExpression(left object.Object, index object.Object) object.Object
 	}
 }
 
+func evalSliceExpression(node *ast.SliceExpression, env *object.Environment) object.Object {
+	left := Eval(node.Left, env)
+	if isError(left) {
+		return left
+	}
+
+	// missing bounds are left as nil
+	bounds := make([]object.Object, 3)
+	for i, bound := range []ast.Expression{node.Start, node.End, node.Step} {
+		if bound == nil {
+			continue
+		}
+		bounds[i] = Eval(bound, env)
+		if isError(bounds[i]) {
+			return bounds[i]
+		}
+	}
+
+	sliced, err := object.Slice(left, bounds[0], bounds[1], bounds[2])
+	if err != nil {
+		return newError("%s", err)
+	}
+	return sliced
+}
+
 func evalDictIndexExpression(left object.Object, index object.Object) object.Object {
 	dictObject := left.(*object.Dict)
 
@@ -159,16 +435,123 @@ func evalArrayIndexExpression(left object.Object, index object.Object) object.Ob
 	return arrayObj.Elements[inx]
 }
 
-func callFunction(function object.Object, args []object.Object) object.Object {
+func evalAssignExpression(node *ast.AssignExpression, env *object.Environment) object.Object {
+	switch target := node.Target.(type) {
+	case *ast.Identifier:
+		if val, ok := env.Get(target.Value); ok {
+			if _, ok := val.(*object.Module); ok {
+				err := newError("cannot assign to module %s", target.Value)
+				err.Pos = target.Pos()
+				return err
+			}
+		}
+
+		var current object.Object
+		if node.BinaryOperator() != "" {
+			current = evalIdentifier(target, env)
+			if isError(current) {
+				return current
+			}
+		}
+
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		if current != nil {
+			val = evalInfixExpression(node.BinaryOperator(), current, val)
+			if isError(val) {
+				return val
+			}
+		}
+
+		if !env.Assign(target.Value, val) {
+			return newError("assignment to undeclared variable: %s", target.Value)
+		}
+		return val
+
+	case *ast.IndexExpression:
+		left := Eval(target.Left, env)
+		if isError(left) {
+			return left
+		}
+		index := Eval(target.Index, env)
+		if isError(index) {
+			return index
+		}
+		var current object.Object
+		if node.BinaryOperator() != "" {
+			current = evalIndexExpression(left, index)
+			if isError(current) {
+				return current
+			}
+		}
+
+		val := Eval(node.Value, env)
+		if isError(val) {
+			return val
+		}
+
+		if current != nil {
+			val = evalInfixExpression(node.BinaryOperator(), current, val)
+			if isError(val) {
+				return val
+			}
+		}
+		return evalIndexAssignment(left, index, val)
+
+	default:
+		return newError("cannot assign to %s", node.Target)
+	}
+}
+
+func evalIndexAssignment(left object.Object, index object.Object, val object.Object) object.Object {
+	switch left := left.(type) {
+	case *object.Array:
+		idx, ok := index.(*object.Integer)
+		if !ok {
+			return newError("array index must be Integer, got %s", index.Type())
+		}
+		if idx.Value < 0 || idx.Value >= int64(len(left.Elements)) {
+			return newError("index out of range: %d with length %d", idx.Value, len(left.Elements))
+		}
+		left.Elements[idx.Value] = val
+		return val
+
+	case *object.Dict:
+		key, ok := index.(object.Hashable)
+		if !ok {
+			return newError("unusable as hash key: %s", index.Type())
+		}
+		left.Pairs[key.DictKey()] = object.DictPair{Key: index, Value: val}
+		return val
+
+	default:
+		return newError("index assignment not supported: %s", left.Type())
+	}
+}
+
+func callFunction(function object.Object, args []object.Object, names []string, named []object.Object) object.Object {
 	switch function := function.(type) {
 	// user defined fns
 	case *object.Function:
-		extendedEnv := extendFnEnv(function, args)
+		values, err := function.Signature().Bind(args, names, named)
+		if err != nil {
+			return newError("%s", err)
+		}
+		extendedEnv, errObj := extendFnEnv(function, values)
+		if errObj != nil {
+			return errObj
+		}
 		evaluated := Eval(function.Body, extendedEnv)
 		return unwrapReturnVal(evaluated)
 
 	// builtin interpreter fns
 	case *object.Builtin:
+		if len(names) > 0 {
+			return newError("builtins do not take named arguments")
+		}
 		if res := function.Fn(args...); res != nil {
 			return res
 		}
@@ -180,20 +563,56 @@ func callFunction(function object.Object, args []object.Object) object.Object {
 }
 
 func unwrapReturnVal(obj object.Object) object.Object {
-	if ret, ok := obj.(*object.ReturnValue); ok {
-		return ret.Value
+	switch obj := obj.(type) {
+	case *object.ReturnValue:
+		return obj.Value
+	case *object.Break, *object.Continue:
+		return loopSignalError(obj)
 	}
-	return obj
+	return blockValue(obj)
 }
 
-func extendFnEnv(fn *object.Function, args []object.Object) *object.Environment {
+// binds the values of the params given in the call, and then evaluates the
+// defaults of those left out in order, which can refer to the params before
+func extendFnEnv(fn *object.Function, values []object.Object) (*object.Environment, object.Object) {
 	env := object.NewEnclosedEnvironment(fn.Env)
 
 	for pi, param := range fn.Parameters {
-		env.Set(param.Value, args[pi])
+		if values[pi] != nil {
+			env.Set(param.Value, values[pi])
+		}
+	}
+	if fn.Rest != nil {
+		env.Set(fn.Rest.Value, values[len(fn.Parameters)])
+	}
+
+	for pi, param := range fn.Parameters {
+		if values[pi] != nil {
+			continue
+		}
+		def := Eval(fn.Defaults[pi], env)
+		if isError(def) {
+			return nil, def
+		}
+		env.Set(param.Value, def)
 	}
 
-	return env
+	return env, nil
+}
+
+// joins the display forms of the parts, as shown by puts
+func evalInterpolatedString(node *ast.InterpolatedString, env *object.Environment) object.Object {
+	var out strings.Builder
+
+	for _, part := range node.Parts {
+		evaluated := Eval(part, env)
+		if isError(evaluated) {
+			return evaluated
+		}
+		out.WriteString(evaluated.Inspect())
+	}
+
+	return &object.String{Value: out.String()}
 }
 
 func evalExpressions(expressions []ast.Expression, env *object.Environment) []object.Object {
@@ -213,6 +632,9 @@ func evalExpressions(expressions []ast.Expression, env *object.Environment) []ob
 func evalIdentifier(node *ast.Identifier, env *object.Environment) object.Object {
 
 	if val, ok := env.Get(node.Value); ok {
+		if _, ok := val.(*object.Module); ok {
+			return newError("module %s can only be used to read its exports, as %s.name", node.Value, node.Value)
+		}
 		return val
 	}
 
@@ -233,7 +655,7 @@ func evalBlockStatement(block *ast.BlockStatement, env *object.Environment) obje
 		// retrieve inner return value if any
 		if result != nil {
 			rt := result.Type()
-			if rt == object.ReturnObj || rt == object.ErrorObj {
+			if rt == object.ReturnObj || rt == object.ErrorObj || rt == object.ExitObj || rt == object.BreakObj || rt == object.ContinueObj {
 				return result
 			}
 		}
@@ -250,14 +672,22 @@ func evalIfExpression(node *ast.IfExpression, env *object.Environment) object.Ob
 	}
 
 	if isTruthy(condition) {
-		return Eval(node.Consequence, env)
+		return blockValue(Eval(node.Consequence, env))
 	} else if node.Alternative != nil {
-		return Eval(node.Alternative, env)
+		return blockValue(Eval(node.Alternative, env))
 	} else {
 		return Null
 	}
 }
 
+// blocks that don't end in an expression have a null value
+func blockValue(obj object.Object) object.Object {
+	if obj == nil {
+		return Null
+	}
+	return obj
+}
+
 func isTruthy(condition object.Object) bool {
 	switch condition {
 	case Null:
@@ -276,6 +706,9 @@ func evalInfixExpression(operator string, left object.Object, right object.Objec
 	// int -> int ops
 	case left.Type() == object.IntegerObj && right.Type() == object.IntegerObj:
 		return evalIntegerInfixExpression(operator, left, right)
+	// mixed int and float ops are done as floats
+	case isNumber(left) && isNumber(right):
+		return evalFloatInfixExpression(operator, left, right)
 	case left.Type() == object.StringObj && right.Type() == object.StringObj:
 		return evalStringInfixExpression(operator, left, right)
 	// any -> bool ops
@@ -316,13 +749,41 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	case "*":
 		return &object.Integer{Value: leftValue * rightValue}
 	case "/":
+		if rightValue == 0 {
+			return newError("division by zero")
+		}
 		return &object.Integer{Value: leftValue / rightValue}
+	case "%":
+		if rightValue == 0 {
+			return newError("division by zero")
+		}
+		return &object.Integer{Value: leftValue % rightValue}
+	case "**":
+		return object.IntPow(leftValue, rightValue)
+	case "&":
+		return &object.Integer{Value: leftValue & rightValue}
+	case "|":
+		return &object.Integer{Value: leftValue | rightValue}
+	case "^":
+		return &object.Integer{Value: leftValue ^ rightValue}
+	case "<<", ">>":
+		if rightValue < 0 {
+			return newError("negative shift count: %d", rightValue)
+		}
+		if operator == "<<" {
+			return &object.Integer{Value: leftValue << rightValue}
+		}
+		return &object.Integer{Value: leftValue >> rightValue}
 
 	// int ops returning bools
 	case "<":
 		return boolToObject(leftValue < rightValue)
 	case ">":
 		return boolToObject(leftValue > rightValue)
+	case "<=":
+		return boolToObject(leftValue <= rightValue)
+	case ">=":
+		return boolToObject(leftValue >= rightValue)
 	case "==":
 		return boolToObject(leftValue == rightValue)
 	case "!=":
@@ -332,6 +793,59 @@ func evalIntegerInfixExpression(operator string, left object.Object, right objec
 	}
 }
 
+func evalFloatInfixExpression(operator string, left object.Object, right object.Object) object.Object {
+	leftValue := toFloat(left)
+	rightValue := toFloat(right)
+
+	switch operator {
+
+	// float ops returning floats
+	case "+":
+		return &object.Float{Value: leftValue + rightValue}
+	case "-":
+		return &object.Float{Value: leftValue - rightValue}
+	case "*":
+		return &object.Float{Value: leftValue * rightValue}
+	case "/":
+		return &object.Float{Value: leftValue / rightValue}
+	case "%":
+		return &object.Float{Value: math.Mod(leftValue, rightValue)}
+	case "**":
+		return &object.Float{Value: math.Pow(leftValue, rightValue)}
+
+	// float ops returning bools
+	case "<":
+		return boolToObject(leftValue < rightValue)
+	case ">":
+		return boolToObject(leftValue > rightValue)
+	case "<=":
+		return boolToObject(leftValue <= rightValue)
+	case ">=":
+		return boolToObject(leftValue >= rightValue)
+	case "==":
+		return boolToObject(leftValue == rightValue)
+	case "!=":
+		return boolToObject(leftValue != rightValue)
+	default:
+		return newError("unknown operator: %s %s %s", left.Type(), operator, right.Type())
+	}
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.IntegerObj || obj.Type() == object.FloatObj
+}
+
+// widens ints to floats for mixed arithmetic
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	}
+	return 0
+}
+
 func evalProgram(pgm *ast.Program, env *object.Environment) object.Object {
 	var result object.Object
 
@@ -342,14 +856,81 @@ func evalProgram(pgm *ast.Program, env *object.Environment) object.Object {
 		switch result.(type) {
 		case *object.ReturnValue:
 			return result.(*object.ReturnValue).Value
-		case *object.Error:
+		case *object.Error, *object.Exit:
 			return result
+		case *object.Break, *object.Continue:
+			return loopSignalError(result)
 		}
 	}
 
 	return result
 }
 
+// runs the body while the condition holds, the loop itself has no value
+func evalWhileStatement(node *ast.WhileStatement, env *object.Environment) object.Object {
+	for {
+		condition := Eval(node.Condition, env)
+		if isError(condition) {
+			return condition
+		}
+		if !isTruthy(condition) {
+			return nil
+		}
+
+		result := Eval(node.Body, env)
+		if stop, val := loopResult(result); stop {
+			return val
+		}
+	}
+}
+
+func evalForInStatement(node *ast.ForInStatement, env *object.Environment) object.Object {
+	iterable := Eval(node.Iterable, env)
+	if isError(iterable) {
+		return iterable
+	}
+
+	iter, ok := object.NewIterator(iterable)
+	if !ok {
+		return newError("cannot iterate over %s", iterable.Type())
+	}
+
+	for elem, ok := iter.Next(); ok; elem, ok = iter.Next() {
+		env.Set(node.Variable.Value, elem)
+
+		result := Eval(node.Body, env)
+		if stop, val := loopResult(result); stop {
+			return val
+		}
+	}
+	return nil
+}
+
+// reports whether a loop should stop after its body produced result, and
+// what the loop should evaluate to if so
+func loopResult(result object.Object) (bool, object.Object) {
+	switch result.(type) {
+	case *object.Break:
+		return true, nil
+	case *object.ReturnValue, *object.Error, *object.Exit:
+		return true, result
+	default:
+		return false, nil
+	}
+}
+
+// break or continue that escaped every loop
+func loopSignalError(signal object.Object) *object.Error {
+	err := newError("%s outside loop", signal.Inspect())
+	switch signal := signal.(type) {
+	case *object.Break:
+		err.Pos = signal.Pos
+	case *object.Continue:
+		err.Pos = signal.Pos
+	}
+	return err
+}
+
 func evalPrefixExpression(operator string, right object.Object) object.Object {
 	switch operator {
 	case "!":
@@ -375,6 +956,10 @@ func evalBangOperatorExpression(right object.Object) object.Object {
 }
 
 func evalMinusPrefixOperatorExpression(right object.Object) object.Object {
+	if right.Type() == object.FloatObj {
+		return &object.Float{Value: -right.(*object.Float).Value}
+	}
+
 	if right.Type() != object.IntegerObj {
 		return newError("unknown operator: %s%s", "-", right.Type())
 	}
@@ -391,7 +976,8 @@ func isError(obj object.Object) bool {
 	if obj == nil {
 		return false
 	}
-	return obj.Type() == object.ErrorObj
+	// an exit unwinds like an error, but is never caught
+	return obj.Type() == object.ErrorObj || obj.Type() == object.ExitObj
 }
 
 // Cache for common simple objects
@@ -410,3 +996,59 @@ func boolToObject(input bool) *object.Boolean {
 
 	return False
 }
+
+// runs the module imported by node in an environment of its own the first
+// time it is imported, and binds it to the name it is imported as
+func evalImportStatement(node *ast.ImportStatement, env *object.Environment) object.Object {
+	loader := env.Loader()
+	mod, err := loader.Load(node.Pos().Filename, node.Path, func(program *ast.Program) (interface{}, error) {
+		modEnv := object.NewEnvironment()
+		modEnv.SetLoader(loader)
+
+		if result := Eval(program, modEnv); isError(result) {
+			return nil, result.(error)
+		}
+
+		exports := map[string]bool{}
+		for _, st := range program.Statements {
+			if let, ok := st.(*ast.LetStatement); ok && let.Export {
+				for _, name := range let.Names() {
+					exports[name.Value] = true
+				}
+			}
+		}
+		return &object.Module{Name: node.Path, Env: modEnv, Exports: exports}, nil
+	})
+
+	var errObj *object.Error
+	if errors.As(err, &errObj) {
+		return errObj
+	}
+	var exit *object.Exit
+	if errors.As(err, &exit) {
+		return exit
+	}
+	if err != nil {
+		return newError("%s", err)
+	}
+
+	env.Set(node.Name.Value, mod.(*object.Module))
+	return nil
+}
+
+func evalMemberExpression(node *ast.MemberExpression, env *object.Environment) object.Object {
+	var mod *object.Module
+	if ident, ok := node.Left.(*ast.Identifier); ok {
+		val, _ := env.Get(ident.Value)
+		mod, _ = val.(*object.Module)
+	}
+	if mod == nil {
+		return newError("%s is not a module", node.Left)
+	}
+
+	val, ok := mod.Get(node.Name.Value)
+	if !ok {
+		return newError("module %s has no export %s", node.Left, node.Name.Value)
+	}
+	return val
+}
@@ -1,6 +1,7 @@
 package evaluator
 
 import (
+	"crabscript.rs/ast"
 	"crabscript.rs/lexer"
 	"crabscript.rs/object"
 	"crabscript.rs/parser"
@@ -27,6 +28,18 @@ func TestEvalIntegerExpression(t *testing.T) {
 		{"3 * 3 * 3 + 10", 37},
 		{"3 * (3 * 3) + 10", 37},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"2 ** 0", 1},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 | 2 ^ 3 & 4 << 1", 3},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -60,6 +73,48 @@ func testIntegerObject(t *testing.T, obj object.Object, expected int64) bool {
 	return true
 }
 
+func TestEvalFloatExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5", 1.5},
+		{"-2.5", -2.5},
+		{"1.5 + 1.5", 3},
+		{"1 / 2.0", 0.5},
+		{"3 * 0.5", 1.5},
+		{"10 - 0.25", 9.75},
+		{"1e3 / 4", 250},
+		{"float(1) / 4", 0.25},
+		{"float(\"2.5\")", 2.5},
+		{"7.5 % 2", 1.5},
+		{"2.0 ** 3", 8},
+		{"4 ** 0.5", 2},
+		{"2 ** -1", 0.5},
+	}
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		testFloatObject(t, evaluated, tt.expected)
+	}
+}
+
+func testFloatObject(t *testing.T, obj object.Object, expected float64) bool {
+	result, ok := obj.(*object.Float)
+
+	if !ok {
+		t.Errorf("object is not Float. got=%T (%+v)", obj, obj)
+		return false
+	}
+
+	if result.Value != expected {
+		t.Errorf("object has wrong value. got=%v, want=%v",
+			result.Value, expected)
+		return false
+	}
+
+	return true
+}
+
 func TestEvalBooleanExpression(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -84,6 +139,18 @@ func TestEvalBooleanExpression(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1.0 == 1", true},
+		{"0.1 + 0.2 != 0.3", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"1.5 <= 1.5", true},
+		{"2 >= 2.5", false},
+		{"5 & 1 == 1", true},
 	}
 
 	for _, tt := range tests {
@@ -127,6 +194,40 @@ func TestBangOperator(t *testing.T) {
 		testBooleanObject(t, evaluated, tt.expected)
 	}
 }
+
+func TestLogicalOperators(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 && 2", 2},
+		{"0 || 2", 0},
+		{"if (false) { 1 } || 3", 3},
+		{"if (false) { 1 } && 3", nil},
+		{"let n = 0; let f = fn() { n += 1; true }; false && f(); n", 0},
+		{"let n = 0; let f = fn() { n += 1; true }; true || f(); n", 0},
+		{"let n = 0; let f = fn() { n += 1; true }; true && f() && f(); n", 2},
+		{"let n = 0; let f = fn() { n += 1; false }; f() || f() || true", true},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case bool:
+			testBooleanObject(t, evaluated, expected)
+		default:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
 func TestIfElseExpressions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -205,6 +306,18 @@ func TestErrorHandling(t *testing.T) {
 			"-true",
 			"unknown operator: -Boolean",
 		},
+		{
+			"[1, 2][::0]",
+			"slice step cannot be zero",
+		},
+		{
+			`[1, 2]["a":]`,
+			"slice indices must be Integer, got String",
+		},
+		{
+			"{1: 2}[0:1]",
+			"slice operator not supported: Dict",
+		},
 		{
 			"true + false;",
 			"unknown operator: Boolean + Boolean",
@@ -231,6 +344,66 @@ return 1; }
 			"foobar",
 			"identifier not found: foobar",
 		},
+		{
+			"1.5 + true",
+			"types not matching: Float and Boolean",
+		},
+		{
+			"10 / (5 - 5)",
+			"division by zero",
+		},
+		{
+			"7 % 0",
+			"division by zero",
+		},
+		{
+			"1 << -1",
+			"negative shift count: -1",
+		},
+		{
+			"1.5 & 1",
+			"unknown operator: Float & Integer",
+		},
+		{
+			"b = 1",
+			"assignment to undeclared variable: b",
+		},
+		{
+			"for (x in 5) { }",
+			"cannot iterate over Integer",
+		},
+		{
+			"break;",
+			"break outside loop",
+		},
+		{
+			"while (true) { fn() { continue; }(); }",
+			"continue outside loop",
+		},
+		{
+			"while (true) { 1 + true; }",
+			"types not matching: Integer and Boolean",
+		},
+		{
+			"let a = [1]; a[1] = 2",
+			"index out of range: 1 with length 1",
+		},
+		{
+			`let a = [1]; a["x"] = 2`,
+			"array index must be Integer, got String",
+		},
+		{
+			"let a = 1; a[0] = 2",
+			"index assignment not supported: Integer",
+		},
+		{
+			"let a = true; a += 1",
+			"types not matching: Boolean and Integer",
+		},
+		{
+			"fn(a) { a }()",
+			"wrong number of arguments: want 1 got 0",
+		},
 		{
 			`"Hello" - "World"`,
 			"unknown operator: String - String",
@@ -239,6 +412,14 @@ return 1; }
 			`{"name": "Monkey"}[fn(x) { x }];`,
 			"unusable as hash key: Function",
 		},
+		{
+			"let a = 1; a = ;",
+			"bad statement",
+		},
+		{
+			`let s = "a\q"; s`,
+			"bad statement",
+		},
 	}
 
 	for _, tt := range tests {
@@ -255,6 +436,30 @@ return 1; }
 		}
 	}
 }
+
+func TestBadProgramsDoNotRun(t *testing.T) {
+	env := object.NewEnvironment()
+	program := parser.New(lexer.New("let a = 1;\nlet b = ;\nlet c = 3;")).ParseProgram()
+
+	evaluated := Eval(program, env)
+	errObj, ok := evaluated.(*object.Error)
+	if !ok {
+		t.Fatalf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+	}
+	if errObj.Message != "bad statement" || errObj.Pos.String() != "2:1" {
+		t.Errorf("wrong error. got=%s", errObj.Inspect())
+	}
+	if _, ok := env.Get("a"); ok {
+		t.Errorf("statements before the bad one were run")
+	}
+
+	for _, node := range []ast.Node{nil, &ast.BadStatement{}, &ast.LetStatement{}} {
+		if _, ok := Eval(node, env).(*object.Error); !ok {
+			t.Errorf("no error object returned for %#v", node)
+		}
+	}
+}
+
 func TestLetStatements(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -264,6 +469,7 @@ func TestLetStatements(t *testing.T) {
 		{"let a = 5 * 5; a;", 25},
 		{"let a = 5; let b = a; b;", 5},
 		{"let a = 5; let b = a; let c = a + b + 5; c;", 15},
+		{"let user_id = 0x10; let v2 = 1_000; user_id + v2", 1016},
 	}
 
 	for _, tt := range tests {
@@ -271,6 +477,322 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let [a, b] = [1, 2]; a * 10 + b", int64(12)},
+		{"let [a, b] = [1]; b", nil},
+		{"let [a] = [1, 2, 3]; a", int64(1)},
+		{"let [a, ...rest] = [1, 2, 3]; len(rest) * 10 + rest[1]", int64(23)},
+		{"let [a, b, ...rest] = [1]; len(rest)", int64(0)},
+		{`let {name, age} = {"name": "crab", "age": 3}; age`, int64(3)},
+		{`let {name, missing} = {"name": "crab"}; missing`, nil},
+		{"let a = 1; let b = 2; let [a, b] = [b, a]; a * 10 + b", int64(21)},
+		{"let f = fn(pair) { let [x, y] = pair; x - y }; f([5, 3])", int64(2)},
+		{"let [a, b] = 1", "cannot destructure Integer as an array"},
+		{`let {a} = [1]`, "cannot destructure Array as a dict"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"match (2) { 1 => 10, 2 => 20, _ => 30 }", int64(20)},
+		{"match (7) { 1 => 10, _ => 30 }", int64(30)},
+		{"match (2.0) { 2 => 1 }", int64(1)},
+		{`match ("b") { "a" => 1, "b" => 2 }`, int64(2)},
+		{"match (if (false) { 1 }) { false => 1, _ => 2 }", int64(2)},
+		{"match (-1) { -1 => 1 }", int64(1)},
+		{"match ([1, 2]) { [a] => a, [a, b] => a * 10 + b }", int64(12)},
+		{"match ([1, 2, 3]) { [1, ...rest] => len(rest) }", int64(2)},
+		{"match ([1]) { [_, _, ..._] => 1, [x, ...rest] => len(rest) }", int64(0)},
+		{`match ({"k": [1, 5], "n": 2}) { {"k": [_, v], n} => v * n }`, int64(10)},
+		{`match ({"a": 1}) { {b} => 1, {a} => a + 1 }`, int64(2)},
+		{"match (5) { n if n > 9 => 1, n if n > 4 => 2, _ => 3 }", int64(2)},
+		{"match ([3]) { [x] if x > 3 => 1, [x] => x }", int64(3)},
+		{"match (1) { [] => 1, {} => 2, 1 => 3 }", int64(3)},
+		{"let f = fn(x) { match (x) { [h, ...t] => h + f(t), [] => 0 } }; f([1, 2, 3])", int64(6)},
+		{"let n = 0; match (1) { n => n }; n", int64(0)},
+		{"let y = 1; match (5) { y => y * 2 }", int64(10)},
+		{"let y = 1; match (5) { y => y * 2 }; y", int64(1)},
+		{"let x = 0; match ([3]) { [x] if x > 3 => 1, _ => 2 }; x", int64(0)},
+		{"let f = match (2) { n => fn() { n } }; f()", int64(2)},
+		{"let f = fn(x) { let n = 0; match (x) { n => n }; n }; f(4)", int64(0)},
+		{"match (1) { n => n }; n", "identifier not found: n"},
+		{"match (3) { 1 => 1, 2 => 2 }", "no match arm for 3"},
+		{`match ([1, "a"]) { [] => 1 }`, "no match arm for [1, a]"},
+		{"match (1) { _ if x => 1 }", "identifier not found: x"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`try { 1 } catch (e) { 2 }`, int64(1)},
+		{`try { throw "x"; 1 } catch (e) { 2 }`, int64(2)},
+		{`1 + try { throw "x" } catch (e) { 10 }`, int64(11)},
+		{`try { throw "boom" } catch (e) { e["message"] }`, "boom"},
+		{`try { throw "boom" } catch (e) { e["kind"] }`, "Error"},
+		{`try { throw {"kind": "Bad", "message": "no", "code": 7} } catch (e) { e["kind"] + e["message"] + str(e["code"]) }`, "Badno7"},
+		{`try { len(1) } catch (e) { e["kind"] + ": " + e["message"] }`, "RuntimeError: argument to `len` not supported, got Integer"},
+		{`try { 1 / 0 } catch (e) { e["message"] }`, "division by zero"},
+		{`let f = fn() { throw "deep" }; let g = fn() { f() }; try { g() } catch (e) { len(e["trace"]) }`, int64(3)},
+		{`try { throw 1 } catch (e) { e["trace"][0] }`, "1:7"},
+		{`try { try { throw "a" } catch (e) { throw e } } catch (e) { e["trace"][0] }`, "1:13"},
+		{`try { try { throw "a" } finally { 1 } } catch (e) { e["message"] }`, "a"},
+		{`try { try { throw "a" } catch (e) { throw "b" } finally { 1 } } catch (e) { e["message"] }`, "b"},
+		{`try { try { 1 } finally { throw "c" } } catch (e) { e["message"] }`, "c"},
+		{`let log = []; try { push(log, 1) } finally { push(log, 2) }; 3`, int64(3)},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, int64(2)},
+		{`let f = fn() { try { throw "x" } finally { return 3 } }; f()`, int64(3)},
+		{`let n = 0; let i = 0; while (i < 5) { i = i + 1; try { if (i == 2) { continue }; if (i == 4) { break } } finally { n = n + i } }; n`, int64(10)},
+		{`let f = fn(xs) { for (x in xs) { try { if (x > 1) { return x } } finally { x * 2 } } }; f([1, 2, 3])`, int64(2)},
+		{`try { let x = 1 } catch (e) { 2 }`, nil},
+		// the catch block has a scope of its own
+		{`let e = "outer"; try { throw "x" } catch (e) { e["message"] }`, "x"},
+		{`let e = "outer"; try { throw "x" } catch (e) { 1 }; e`, "outer"},
+		{`let f = fn() { let e = 1; try { throw 2 } catch (e) { e["message"] }; e }; f()`, int64(1)},
+		{`let e = 1; let f = try { throw "x" } catch (e) { fn() { e["message"] } }; f() + str(e)`, "x1"},
+		{`let n = 0; try { throw 1 } catch (e) { n = 5 }; n`, int64(5)},
+		{`let e = 1; try { try { throw 2 } catch (e) { throw 3 } } catch (err) { str(e) + err["message"] }`, "13"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("wrong value for %q. expected=%q, got=%q", tt.input, expected, str.Value)
+			}
+		case nil:
+			testNullObject(t, evaluated)
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`throw "oops"`, "1:1: oops"},
+		{`throw {"message": "bad"}`, "1:1: bad"},
+		{`let f = fn() { throw "in f" }; try { f() } finally { 1 }`, "1:16: in f"},
+		{`try { throw 1 } catch (e) { throw e["message"] + "!" }`, "1:29: 1!"},
+		{`try { throw 1 } catch (e) { let inner = 2 }; inner`, "1:46: identifier not found: inner"},
+	}
+
+	for _, tt := range errTests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("no error for %q", tt.input)
+			continue
+		}
+		if got := errObj.Pos.String() + ": " + errObj.Message; got != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let a = 5; a = 6; a;", 6},
+		{"let a = 5; a = a + 1;", 6},
+		{"let a = 1; let b = 2; a = b = 3; a + b;", 6},
+		{"let a = 5; a += 2; a;", 7},
+		{"let a = 5; a -= 2; a;", 3},
+		{"let a = 5; a *= 2; a;", 10},
+		{"let a = 6; a /= 2; a;", 3},
+		{"let a = 1.5; a += 1; a;", 2.5},
+		{"let a = 7; a %= 4; a;", 3},
+		{"let a = 2; a **= 10; a;", 1024},
+		{"let a = 6; a &= 3; a;", 2},
+		{"let a = 6; a |= 3; a;", 7},
+		{"let a = 6; a ^= 3; a;", 5},
+		{"let a = 1; a <<= 4; a;", 16},
+		{"let a = -16; a >>= 2; a;", -4},
+		{"let a = [1, 2]; a[1] **= 3; a[1];", 8},
+		{"let f = fn() { let a = 12; a %= 5; a }; f();", 2},
+		{`let s = "crab"; s += "script"; s;`, "crabscript"},
+		{"let a = [1, 2, 3]; a[1] = 5; a[1];", 5},
+		{"let a = [1, 2, 3]; a[2] *= 10; a[2];", 30},
+		{`let d = {"k": 1}; d["k"] = 2; d["k"];`, 2},
+		{`let d = {}; d["new"] = 3; d["new"];`, 3},
+		{`let d = {"k": 1}; d["k"] += 1; d["k"];`, 2},
+		{"let a = 1; let set = fn() { a = 2; }; set(); a;", 2},
+		{"let f = fn() { let a = 1; let g = fn() { a += 1; }; g(); g(); a }; f();", 3},
+		{
+			`let counter = fn() { let n = 0; fn() { n += 1; n } };
+			let c = counter();
+			c(); c(); c();`,
+			3,
+		},
+		{
+			`let make = fn() {
+				let n = 0;
+				[fn() { n += 1 }, fn() { n }]
+			};
+			let pair = make();
+			pair[0](); pair[0]();
+			pair[1]();`,
+			2,
+		},
+		{"let a = 1; let f = fn(a) { a = 10; a }; f(0) + a;", 11},
+		{"let a = [1]; let b = a; b[0] = 2; a[0];", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case float64:
+			testFloatObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q want=%q", str.Value, expected)
+			}
+		}
+	}
+}
+
+func TestLoops(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let i = 0; while (i < 5) { i += 1; } i;", 5},
+		{"let i = 0; while (false) { i += 1; } i;", 0},
+		{"let i = 0\nwhile (i < 5) {\n  i += 1\n}\ni", 5},
+		{"let f = fn() {\n  for (x in [1, 2]) {\n    return\n  }\n  1\n}\nf()", nil},
+		{"let i = 0; while (true) { i += 1; if (i == 3) { break; } } i;", 3},
+		{
+			"let i = 0; let n = 0; while (i < 10) { i += 1; if (i > 5) { continue; } n += 1; } n;",
+			5,
+		},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x; } sum;", 6},
+		{"let n = 0; for (x in []) { n += 1; } n;", 0},
+		{`let s = ""; for (c in "héllo") { s = c + s; } s;`, "olléh"},
+		{`let s = ""; for (k in {"b": 2, "a": 1, "c": 3}) { s += k; } s;`, "abc"},
+		{"let keys = 0; for (k in {2: 0, 1: 0, 3: 0}) { keys = keys * 10 + k; } keys;", 123},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { break; } sum += x; } sum;", 3},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { continue; } sum += x; } sum;", 7},
+		{
+			`let n = 0;
+			for (x in [1, 2, 3]) { for (y in [1, 2, 3]) { if (y == 2) { break; } n += 1; } }
+			n;`,
+			3,
+		},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } }; f();", 20},
+		{"let f = fn() { let i = 0; while (true) { i += 1; if (i == 4) { return i; } } }; f();", 4},
+		{"let xs = [1, 2]; for (x in xs) { xs = push(xs, x); } len(xs);", 4},
+		{"let i = 0; while (i < 100000) { i += 1; } i;", 100000},
+		{"for (x in [1, 2]) { } x;", 2},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case nil:
+			testNullObject(t, evaluated)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("String has wrong value. got=%q want=%q", str.Value, expected)
+			}
+		}
+	}
+}
+
+// a loop has no value, rather than that of its condition or iterator
+func TestLoopsHaveNoValue(t *testing.T) {
+	tests := []string{
+		"let i = 0; while (i < 2) { i += 1; }",
+		"while (true) { break; }",
+		"for (x in [1, 2]) { }",
+		"for (x in [1, 2]) { break; }",
+	}
+
+	for _, input := range tests {
+		if evaluated := testEval(input); evaluated != nil {
+			t.Errorf("%q has a value. got=%T (%+v)", input, evaluated, evaluated)
+		}
+	}
+}
+
+func TestStatementBlocksAreNull(t *testing.T) {
+	tests := []string{
+		"fn() { let a = 1; }()",
+		"fn() { while (false) { } }()",
+		"if (true) { let a = 1; }",
+	}
+
+	for _, input := range tests {
+		testNullObject(t, testEval(input))
+	}
+}
+
 func TestFunctionObject(t *testing.T) {
 	input := "fn(x) { x + 2; };"
 	evaluated := testEval(input)
@@ -314,6 +836,51 @@ func TestFunctionCall(t *testing.T) {
 		testIntegerObject(t, testEval(tt.input), tt.expected)
 	}
 }
+func TestFunctionParameters(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let f = fn(x, y = 10) { x + y }; f(1)", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2)", 3},
+		{"let f = fn(x, y = x * 2) { y }; f(4)", 8},
+		{"let n = 0; let f = fn(x = n) { x }; n = 5; f()", 5},
+		{"let f = fn(first, ...rest) { len(rest) }; f(1, 2, 3)", 2},
+		{"let f = fn(first, ...rest) { len(rest) }; f(1)", 0},
+		{"let f = fn(...rest) { rest[1] }; f(1, 2)", 2},
+		{"let f = fn(a, b) { a - b }; f(b: 1, a: 5)", 4},
+		{"let f = fn(a, b = 2, c = 3) { a * 100 + b * 10 + c }; f(1, c: 5)", 125},
+		{"let f = fn(a, b = 2, ...r) { a + b + len(r) }; f(1, 2, 3, 4)", 5},
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(b: 3)", 7},
+		{"fn(a) { a }()", "wrong number of arguments: want 1 got 0"},
+		{"fn(a, b = 1) { a }(1, 2, 3)", "wrong number of arguments: want 1 to 2 got 3"},
+		{"fn(a, ...r) { a }()", "wrong number of arguments: want at least 1 got 0"},
+		{"fn(a, b) { a }(b: 1)", "missing argument a"},
+		{"fn(a) { a }(b: 1)", "unknown argument b"},
+		{"fn(a) { a }(1, a: 2)", "argument a given twice"},
+		{"fn(a, ...r) { a }(1, r: 2)", "unknown argument r"},
+		{"fn(a = 1 + true) { a }()", "types not matching: Integer and Boolean"},
+		{"len(x: 1)", "builtins do not take named arguments"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("%q: object is not Error. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestStringLiteral(t *testing.T) {
 	input := `"Hello World!"`
 	evaluated := testEval(input)
@@ -344,6 +911,71 @@ func TestStringConcatenation(t *testing.T) {
 	}
 }
 
+func TestInterpolatedStrings(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`let name = "crab"; "hi ${name}!"`, "hi crab!"},
+		{`let items = [1, 2]; "has ${len(items)} items: ${items}"`, "has 2 items: [1, 2]"},
+		{`"${1 + 1}${true}${1.5}"`, "2true1.5"},
+		{`"${if (false) { 1 }}"`, "null"},
+		{`"a ${"b ${"c"} d"} e"`, "a b c d e"},
+		{`"${ {"k": "v"}["k"] }"`, "v"},
+		{`"cost: \${5} $5"`, "cost: ${5} $5"},
+		{`str(12) + str("ab") + str([1])`, "12ab[1]"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		str, ok := evaluated.(*object.String)
+		if !ok {
+			t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if str.Value != tt.expected {
+			t.Errorf("String has wrong value. want %q, got %q", tt.expected, str.Value)
+		}
+	}
+
+	evaluated := testEval(`"a ${1 + true} b"`)
+	if errObj, ok := evaluated.(*object.Error); !ok || errObj.Message != "types not matching: Integer and Boolean" {
+		t.Errorf("expected error from interpolated expression, got %+v", evaluated)
+	}
+}
+
+func TestPipeExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(3)", 7},
+		{"let double = fn(x) { x * 2 }; 3 |> double |> double", 12},
+		{"let add = fn(a, b) { a + b }; 1 + 2 |> add(4) |> add(5)", 12},
+		{"[1, 2, 3] |> push(4) |> len", 4},
+		{"2 |> fn(x) { x * x }", 4},
+		{"let n = 5 |> fn(x) { x + 1 }; n", 6},
+		{"1 |> len", "argument to `len` not supported, got Integer"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int:
+			testIntegerObject(t, evaluated, int64(expected))
+		case string:
+			errObj, ok := evaluated.(*object.Error)
+			if !ok {
+				t.Errorf("object is not Error. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if errObj.Message != expected {
+				t.Errorf("wrong error message. expected=%q, got=%q", expected, errObj.Message)
+			}
+		}
+	}
+}
+
 func TestBuiltinFunctions(t *testing.T) {
 	tests := []struct {
 		input    string
@@ -355,6 +987,17 @@ func TestBuiltinFunctions(t *testing.T) {
 		{`len(1)`, "argument to `len` not supported, got Integer"},
 		{`len("one", "two")`, "wrong number of arguments. got 2, want 1"},
 		{`puts("one")`, nil},
+		{`int(2.9)`, 2},
+		{`int(-2.9)`, -2},
+		{`int("0x10")`, 16},
+		{`int(true)`, 1},
+		{`int("crab")`, `cannot convert "crab" to Integer`},
+		{`int(1e20)`, "cannot convert 1e+20 to Integer"},
+		{`int(-1e19)`, "cannot convert -1e+19 to Integer"},
+		{`float([])`, "argument to `float` not supported, got Array"},
+		{`exit(1, 2)`, "wrong number of arguments. got 2, want 0 or 1"},
+		{`exit("1")`, "argument to `exit` not supported, got String"},
+		{`exit(-1)`, "exit status -1 out of range 0 to 255"},
 	}
 	for _, tt := range tests {
 		evaluated := testEval(tt.input)
@@ -383,6 +1026,31 @@ func TestBuiltinFunctions(t *testing.T) {
 	}
 }
 
+func TestExit(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int
+	}{
+		{`exit(); 1`, 0},
+		{`exit(3); 1`, 3},
+		{`let f = fn() { try { exit(3) } catch (e) { 4 } }; f(); 5`, 3},
+		{`for (x in [1, 2]) { if (x == 2) { exit(x) } }; 3`, 2},
+		{`try { 1 } finally { exit(4) }`, 4},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		exit, ok := evaluated.(*object.Exit)
+		if !ok {
+			t.Errorf("object is not Exit. got=%T (%+v)", evaluated, evaluated)
+			continue
+		}
+		if exit.Code != tt.expected {
+			t.Errorf("wrong exit status. expected=%d, got=%d", tt.expected, exit.Code)
+		}
+	}
+}
+
 func TestArrayLiterals(t *testing.T) {
 	input := "[1, 2 * 2, 3 + 3]"
 	evaluated := testEval(input)
@@ -459,6 +1127,61 @@ func TestArrayIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{"[1, 2, 3, 4][1:3]", []int64{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int64{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int64{3, 4}},
+		{"[1, 2, 3, 4][:]", []int64{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int64{3, 4}},
+		{"[1, 2, 3, 4][:-1]", []int64{1, 2, 3}},
+		{"[1, 2, 3, 4][::2]", []int64{1, 3}},
+		{"[1, 2, 3, 4][::-1]", []int64{4, 3, 2, 1}},
+		{"[1, 2, 3, 4][3:0:-2]", []int64{4, 2}},
+		{"[1, 2, 3, 4][-100:100]", []int64{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int64{}},
+		{"[1, 2, 3][1:9223372036854775807:9223372036854775807]", []int64{2}},
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 5; a[0]", int64(1)},
+		{`"hello"[1:4]`, "ell"},
+		{`"héllo🦀"[-2:]`, "o🦀"},
+		{`"héllo"[::-1]`, "olléh"},
+		{`"abc"[if (false) { 1 }:2]`, "ab"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("%q: object is not String. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("%q: wrong value. want %q, got %q", tt.input, expected, str.Value)
+			}
+		case []int64:
+			arr, ok := evaluated.(*object.Array)
+			if !ok {
+				t.Errorf("%q: object is not Array. got=%T (%+v)", tt.input, evaluated, evaluated)
+				continue
+			}
+			if len(arr.Elements) != len(expected) {
+				t.Errorf("%q: wrong number of elements. want %d, got %d", tt.input, len(expected), len(arr.Elements))
+				continue
+			}
+			for i, el := range expected {
+				testIntegerObject(t, arr.Elements[i], el)
+			}
+		}
+	}
+}
+
 func TestHashLiterals(t *testing.T) {
 	input := `let two = "two";
 {
@@ -538,3 +1261,87 @@ func TestHashIndexExpressions(t *testing.T) {
 		}
 	}
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input       string
+		expectedPos string
+	}{
+		{"5 + true;", "1:3"},
+		{"let a = 1;\nlet b = a + foobar;", "2:13"},
+		{"let f = fn() {\n  -true\n};\nf();", "2:3"},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		errObj, ok := evaluated.(*object.Error)
+		if !ok {
+			t.Errorf("no error object returned. got=%T(%+v)", evaluated, evaluated)
+			continue
+		}
+		if errObj.Pos.String() != tt.expectedPos {
+			t.Errorf("wrong error position. expected=%q, got=%q", tt.expectedPos, errObj.Pos)
+		}
+	}
+}
+
+func TestImports(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected interface{}
+	}{
+		{`import "testdata/greet.crab" as g; g.greet("bob")`, "hello bob"},
+		{`import "testdata/greet.crab" as g; g.x`, int64(3)},
+		{`import "testdata/greet.crab" as g; g.first + len(g.rest)`, int64(3)},
+		{`let hidden = 10; import "testdata/greet.crab" as g; g.x + hidden`, int64(13)},
+		{`import "testdata/counter.crab" as c; import "testdata/greet.crab" as g; g.greet("a"); g.greet("b"); c.count()`, int64(2)},
+		{`import "testdata/counter.crab" as a; import "testdata/counter.crab" as b; a.bump(); b.count()`, int64(1)},
+		{`import "testdata/counter.crab" as c; let f = fn() { c.bump() }; f(); f()`, int64(2)},
+		{`import "testdata/early.crab" as e; e.a`, int64(1)},
+		{`import "testdata/greet.crab" as g; let g = 1; g`, int64(1)},
+	}
+
+	for _, tt := range tests {
+		evaluated := testEval(tt.input)
+		switch expected := tt.expected.(type) {
+		case int64:
+			testIntegerObject(t, evaluated, expected)
+		case string:
+			str, ok := evaluated.(*object.String)
+			if !ok {
+				t.Errorf("object is not String. got=%T (%+v)", evaluated, evaluated)
+				continue
+			}
+			if str.Value != expected {
+				t.Errorf("wrong value for %q. expected=%q, got=%q", tt.input, expected, str.Value)
+			}
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "testdata/fails.crab" as f; f.f()`, "testdata/fails.crab:1:25: division by zero"},
+		{`import "testdata/greet.crab" as g; g.y`, "1:37: module g has no export y"},
+		{`import "testdata/early.crab" as e; e.b`, "1:37: module e has no export b"},
+		{`import "testdata/greet.crab" as g; g.hidden`, "1:37: module g has no export hidden"},
+		{`import "testdata/greet.crab" as g; g`, "1:36: module g can only be used to read its exports, as g.name"},
+		{`import "testdata/greet.crab" as g; g = 1`, "1:36: cannot assign to module g"},
+		{`let a = 1; a.b`, "1:13: a is not a module"},
+		{`import "testdata/missing.crab" as m`, `1:1: cannot find module "testdata/missing.crab" in .`},
+		{`import "testdata/broken.crab" as b`, "1:1: testdata/broken.crab:1:5: expected next token Ident, got ="},
+		{`import "testdata/cycle_a.crab" as a`, "testdata/cycle_b.crab:1:1: import cycle testdata/cycle_a.crab -> testdata/cycle_b.crab -> testdata/cycle_a.crab"},
+	}
+
+	for _, tt := range errTests {
+		errObj, ok := testEval(tt.input).(*object.Error)
+		if !ok {
+			t.Errorf("no error for %q", tt.input)
+			continue
+		}
+		if got := errObj.Pos.String() + ": " + errObj.Message; got != tt.expected {
+			t.Errorf("wrong error for %q. expected=%q, got=%q", tt.input, tt.expected, got)
+		}
+	}
+}
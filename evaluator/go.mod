@@ -4,6 +4,7 @@ replace (
 	crabscript.rs/ast => ../ast
 	crabscript.rs/code => ../code
 	crabscript.rs/lexer => ../lexer
+	crabscript.rs/module => ../module
 	crabscript.rs/object => ../object
 	crabscript.rs/parser => ../parser
 	crabscript.rs/token => ../token
@@ -16,9 +17,10 @@ require (
 	crabscript.rs/lexer v0.0.0-00010101000000-000000000000
 	crabscript.rs/object v0.0.0-00010101000000-000000000000
 	crabscript.rs/parser v0.0.0-00010101000000-000000000000
+	crabscript.rs/token v0.0.0-00010101000000-000000000000
 )
 
 require (
 	crabscript.rs/code v0.0.0-00010101000000-000000000000 // indirect
-	crabscript.rs/token v0.0.0-00010101000000-000000000000 // indirect
+	crabscript.rs/module v0.0.0-00010101000000-000000000000 // indirect
 )
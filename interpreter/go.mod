@@ -22,15 +22,21 @@ replace crabscript.rs/code => ../code
 
 replace crabscript.rs/vm => ../vm
 
-require crabscript.rs/repl v0.0.0-00010101000000-000000000000
+replace crabscript.rs/module => ../module
+
+require (
+	crabscript.rs/ast v0.0.0-00010101000000-000000000000
+	crabscript.rs/compiler v0.0.0-00010101000000-000000000000
+	crabscript.rs/evaluator v0.0.0-00010101000000-000000000000
+	crabscript.rs/lexer v0.0.0-00010101000000-000000000000
+	crabscript.rs/module v0.0.0-00010101000000-000000000000
+	crabscript.rs/object v0.0.0-00010101000000-000000000000
+	crabscript.rs/parser v0.0.0-00010101000000-000000000000
+	crabscript.rs/repl v0.0.0-00010101000000-000000000000
+	crabscript.rs/vm v0.0.0-00010101000000-000000000000
+)
 
 require (
-	crabscript.rs/ast v0.0.0-00010101000000-000000000000 // indirect
 	crabscript.rs/code v0.0.0-00010101000000-000000000000 // indirect
-	crabscript.rs/compiler v0.0.0-00010101000000-000000000000 // indirect
-	crabscript.rs/lexer v0.0.0-00010101000000-000000000000 // indirect
-	crabscript.rs/object v0.0.0-00010101000000-000000000000 // indirect
-	crabscript.rs/parser v0.0.0-00010101000000-000000000000 // indirect
 	crabscript.rs/token v0.0.0-00010101000000-000000000000 // indirect
-	crabscript.rs/vm v0.0.0-00010101000000-000000000000 // indirect
 )
@@ -3,41 +3,85 @@ package main
 import (
 	"crabscript.rs/repl"
 	"fmt"
+	"io"
 	"os"
 	"os/user"
 )
 
+// Modes the interpreter can run in
+const (
+	modeCompile = iota // compile a script to an object file
+	modeRepl           // interactive repl
+	modeRun            // run a script file
+)
+
+const usage = `usage:
+  crabscript                                             start the repl
+  crabscript run [--engine=vm|eval] file.crab [args...]  run a script or compiled .crabc file
+  crabscript -o out.crabc file.crab                      compile a script to bytecode
+
+flags go before the script file name, run passes everything after it to
+the script as args
+
+imported modules are looked up next to the importing script, then in the
+directories listed in $CRABPATH
+`
+
 func main() {
+	os.Exit(run(os.Args, os.Stdin, os.Stdout, os.Stderr))
+}
 
-	handleInput(os.Args)
+// run dispatches on the command line and returns the process exit status
+func run(args []string, in io.Reader, out io.Writer, errOut io.Writer) int {
+	mode, opts, err := handleInput(args)
+	if err != nil {
+		fmt.Fprintf(errOut, "%s\n%s", err, usage)
+		return exitUsage
+	}
+
+	switch mode {
+	case modeRun:
+		return runFile(opts, out, errOut)
+	case modeCompile:
+		return compileFile(opts, errOut)
+	}
 
 	user, err := user.Current()
 	if err != nil {
 		panic(err)
 	}
 
-	fmt.Printf("Welcome, %v, to 🦀script!\n", user.Name)
-	repl.Start(os.Stdin, os.Stdout)
+	fmt.Fprintf(out, "Welcome, %v, to 🦀script!\n", user.Name)
+	repl.Start(in, out)
+	return exitOk
 }
 
-// returns code based on option
-// 0 - compile an object
-// 1 - repl
-// 2 - interpret
-func handleInput(args []string) (int, error) {
+// returns mode based on option
+func handleInput(args []string) (int, *options, error) {
 	if len(args) == 1 {
-		return 1, nil
+		return modeRepl, nil, nil
+	}
+
+	if args[1] == "run" {
+		opts, err := parseRunOptions(args[2:])
+		if err != nil {
+			return -1, nil, err
+		}
+		return modeRun, opts, nil
 	}
 
 	for i := 1; i < len(args); i++ {
 		arg := args[i]
 		if arg == "-o" {
 			if i+1 >= len(args) {
-				return -1, fmt.Errorf("missing output file name")
-			} else {
-
+				return -1, nil, fmt.Errorf("missing output file name")
+			}
+			opts, err := parseCompileOptions(args[1:])
+			if err != nil {
+				return -1, nil, err
 			}
+			return modeCompile, opts, nil
 		}
 	}
-	return -1, fmt.Errorf("unable to parse options")
+	return -1, nil, fmt.Errorf("unable to parse options")
 }
@@ -0,0 +1,235 @@
+package main
+
+import (
+	"bytes"
+	"crabscript.rs/ast"
+	"crabscript.rs/compiler"
+	"crabscript.rs/evaluator"
+	"crabscript.rs/lexer"
+	"crabscript.rs/module"
+	"crabscript.rs/object"
+	"crabscript.rs/parser"
+	"crabscript.rs/vm"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Process exit statuses
+const (
+	exitOk    = 0 // script ran to completion, unless it called exit(code)
+	exitError = 1 // script failed to parse, compile or run
+	exitUsage = 2 // bad command line
+)
+
+// global index of `args`, see newSymbolTable
+const argsGlobal = 0
+
+// environment variable listing the directories searched for imported
+// modules not found next to their importer
+const searchPathEnv = "CRABPATH"
+
+type options struct {
+	engine     string   // 'vm' or 'eval'
+	file       string   // script to run
+	scriptArgs []string // arguments passed through to the script as `args`
+	output     string   // object file to write when compiling
+}
+
+func parseRunOptions(args []string) (*options, error) {
+	opts := &options{}
+
+	flags := flag.NewFlagSet("run", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&opts.engine, "engine", "vm", "use 'vm' or 'eval'")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	if opts.engine != "vm" && opts.engine != "eval" {
+		return nil, fmt.Errorf("unknown engine %q", opts.engine)
+	}
+	if flags.NArg() < 1 {
+		return nil, fmt.Errorf("missing script file name")
+	}
+
+	opts.file = flags.Arg(0)
+	opts.scriptArgs = flags.Args()[1:]
+	return opts, nil
+}
+
+func parseCompileOptions(args []string) (*options, error) {
+	opts := &options{}
+
+	flags := flag.NewFlagSet("compile", flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+	flags.StringVar(&opts.output, "o", "", "bytecode file to write")
+
+	if err := flags.Parse(args); err != nil {
+		return nil, err
+	}
+	// parsing stops at the file name, so a flag after it would be left over
+	for i, arg := range flags.Args() {
+		if i > 0 && strings.HasPrefix(arg, "-") {
+			return nil, fmt.Errorf("flag %s must come before the script file name", arg)
+		}
+	}
+	if flags.NArg() != 1 {
+		return nil, fmt.Errorf("expected exactly one script file name")
+	}
+
+	opts.file = flags.Arg(0)
+	return opts, nil
+}
+
+// lex, parse and run a script file, returning its exit status
+// files starting with the bytecode header are run directly on the vm
+func runFile(opts *options, out io.Writer, errOut io.Writer) int {
+	src, err := os.ReadFile(opts.file)
+	if err != nil {
+		fmt.Fprintf(errOut, "%s\n", err)
+		return exitError
+	}
+
+	scriptArgs := make([]object.Object, len(opts.scriptArgs))
+	for i, a := range opts.scriptArgs {
+		scriptArgs[i] = &object.String{Value: a}
+	}
+	argv := &object.Array{Elements: scriptArgs}
+
+	if compiler.IsBytecode(src) {
+		if opts.engine == "eval" {
+			fmt.Fprintf(errOut, "%s is compiled bytecode and can only run on the vm\n", opts.file)
+			return exitError
+		}
+
+		bytecode, err := compiler.ReadBytecode(bytes.NewReader(src))
+		if err != nil {
+			fmt.Fprintf(errOut, "%s: %s\n", opts.file, err)
+			return exitError
+		}
+		return runBytecode(bytecode, argv, errOut)
+	}
+
+	program, ok := parseFile(opts.file, src, errOut)
+	if !ok {
+		return exitError
+	}
+
+	if opts.engine == "eval" {
+		return evalProgram(program, argv, errOut)
+	}
+	return execProgram(program, argv, errOut)
+}
+
+// compile a script file and write its bytecode to the output file
+func compileFile(opts *options, errOut io.Writer) int {
+	src, err := os.ReadFile(opts.file)
+	if err != nil {
+		fmt.Fprintf(errOut, "%s\n", err)
+		return exitError
+	}
+
+	program, ok := parseFile(opts.file, src, errOut)
+	if !ok {
+		return exitError
+	}
+
+	comp := compiler.NewWithState(newSymbolTable(), []object.Object{})
+	comp.SetLoader(newLoader())
+	if err := comp.Compile(program); err != nil {
+		fmt.Fprintf(errOut, "compiler error: %s\n", err)
+		return exitError
+	}
+
+	var buf bytes.Buffer
+	if _, err := comp.Bytecode().WriteTo(&buf); err != nil {
+		fmt.Fprintf(errOut, "%s\n", err)
+		return exitError
+	}
+	if err := os.WriteFile(opts.output, buf.Bytes(), 0o644); err != nil {
+		fmt.Fprintf(errOut, "%s\n", err)
+		return exitError
+	}
+	return exitOk
+}
+
+func parseFile(name string, src []byte, errOut io.Writer) (*ast.Program, bool) {
+	p := parser.New(lexer.NewWithFile(name, string(src)))
+	program := p.ParseProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) > 0 {
+		for _, d := range diagnostics {
+			io.WriteString(errOut, d.Format())
+		}
+		return nil, false
+	}
+	return program, true
+}
+
+// symbol table shared by scripts and compiled bytecode, `args` is always
+// the first global so bytecode can be run without its symbol table
+func newSymbolTable() *compiler.SymbolTable {
+	symbolTable := compiler.NewSymbolTable()
+	for i, v := range object.Builtins {
+		symbolTable.DefineBuiltin(i, v.Name)
+	}
+	symbolTable.Define("args")
+	return symbolTable
+}
+
+func newLoader() *module.Loader {
+	return module.NewLoader(filepath.SplitList(os.Getenv(searchPathEnv))...)
+}
+
+// run the program on the tree-walking evaluator
+func evalProgram(program *ast.Program, argv *object.Array, errOut io.Writer) int {
+	env := object.NewEnvironment()
+	env.SetLoader(newLoader())
+	env.Set("args", argv)
+
+	result := evaluator.Eval(program, env)
+	if exit, ok := result.(*object.Exit); ok {
+		return exit.Code
+	}
+	if errObj, ok := result.(*object.Error); ok {
+		fmt.Fprintf(errOut, "%s\n", errObj.Inspect())
+		return exitError
+	}
+	return exitOk
+}
+
+// compile the program and run it on the vm
+func execProgram(program *ast.Program, argv *object.Array, errOut io.Writer) int {
+	comp := compiler.NewWithState(newSymbolTable(), []object.Object{})
+	comp.SetLoader(newLoader())
+	if err := comp.Compile(program); err != nil {
+		fmt.Fprintf(errOut, "compiler error: %s\n", err)
+		return exitError
+	}
+	return runBytecode(comp.Bytecode(), argv, errOut)
+}
+
+func runBytecode(bytecode *compiler.Bytecode, argv *object.Array, errOut io.Writer) int {
+	globals := make([]object.Object, vm.GlobalSize)
+	globals[argsGlobal] = argv
+
+	machine := vm.NewWithGblStore(bytecode, globals)
+	err := machine.Run()
+	if exit, ok := err.(*object.Exit); ok {
+		return exit.Code
+	}
+	if err != nil {
+		var rtErr *vm.RuntimeError
+		if errors.As(err, &rtErr) {
+			io.WriteString(errOut, rtErr.StackTrace())
+		} else {
+			fmt.Fprintf(errOut, "vm error: %s\n", err)
+		}
+		return exitError
+	}
+	return exitOk
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"crabscript.rs/compiler"
+)
+
+// writes a script to a new directory, returning its path
+func writeScript(t *testing.T, name, src string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func runCli(args ...string) (int, string) {
+	var out, errOut bytes.Buffer
+	status := run(append([]string{"crabscript"}, args...), strings.NewReader(""), &out, &errOut)
+	return status, errOut.String()
+}
+
+func TestRunExitStatus(t *testing.T) {
+	ok := writeScript(t, "ok.crab", `let x = 1 + 2`)
+	fails := writeScript(t, "fails.crab", `let f = fn() { 1 / 0 }; f()`)
+	broken := writeScript(t, "broken.crab", `let = 1`)
+	checksArgs := writeScript(t, "args.crab", `if (len(args) > 0) { throw "got " + args[0] }`)
+	exits := writeScript(t, "exits.crab", `let f = fn() { try { exit(3) } catch (e) { 4 } }; f(); 1 / 0`)
+	exitsOk := writeScript(t, "exits_ok.crab", `exit(); 1 / 0`)
+	badExit := writeScript(t, "bad_exit.crab", `exit(256)`)
+
+	tests := []struct {
+		args     []string
+		status   int
+		contains string
+	}{
+		{[]string{"run", ok}, exitOk, ""},
+		{[]string{"run", "--engine=vm", ok}, exitOk, ""},
+		{[]string{"run", "--engine=eval", ok}, exitOk, ""},
+		{[]string{"run", "--engine=vm", fails}, exitError, "division by zero"},
+		{[]string{"run", "--engine=eval", fails}, exitError, "division by zero"},
+		{[]string{"run", broken}, exitError, "expected next token Ident, got ="},
+		{[]string{"run", "--engine=eval", broken}, exitError, "expected next token Ident, got ="},
+		{[]string{"run", checksArgs}, exitOk, ""},
+		{[]string{"run", checksArgs, "hi"}, exitError, "got hi"},
+		{[]string{"run", "--engine=eval", checksArgs, "ho"}, exitError, "got ho"},
+		{[]string{"run", exits}, 3, ""},
+		{[]string{"run", "--engine=eval", exits}, 3, ""},
+		{[]string{"run", exitsOk}, exitOk, ""},
+		{[]string{"run", "--engine=eval", exitsOk}, exitOk, ""},
+		{[]string{"run", badExit}, exitError, "exit status 256 out of range 0 to 255"},
+		{[]string{"run", "--engine=eval", badExit}, exitError, "exit status 256 out of range 0 to 255"},
+		{[]string{"run", filepath.Join(t.TempDir(), "missing.crab")}, exitError, "no such file"},
+		{[]string{"run", "--engine=jit", ok}, exitUsage, `unknown engine "jit"`},
+		{[]string{"run"}, exitUsage, "missing script file name"},
+		{[]string{"frobnicate"}, exitUsage, "usage:"},
+		{[]string{"-o"}, exitUsage, "missing output file name"},
+		{[]string{ok, "-o", "out.crabc"}, exitUsage, "flag -o must come before the script file name"},
+		{[]string{"-o", "out.crabc", ok, "-o", "other.crabc"}, exitUsage, "flag -o must come before the script file name"},
+		{[]string{"run", checksArgs, "--engine=eval"}, exitError, "got --engine=eval"},
+	}
+
+	for _, tt := range tests {
+		status, errOut := runCli(tt.args...)
+		if status != tt.status {
+			t.Errorf("wrong exit status for %q. want %d, got %d (%s)", tt.args, tt.status, status, errOut)
+		}
+		if !strings.Contains(errOut, tt.contains) {
+			t.Errorf("wrong output for %q. want %q in %q", tt.args, tt.contains, errOut)
+		}
+	}
+}
+
+func TestCompileAndRunBytecode(t *testing.T) {
+	script := writeScript(t, "script.crab", `if (len(args) > 0) { throw "got " + args[0] }`)
+	object := filepath.Join(t.TempDir(), "script.crabc")
+
+	if status, errOut := runCli("-o", object, script); status != exitOk {
+		t.Fatalf("compile failed with status %d: %s", status, errOut)
+	}
+	src, err := os.ReadFile(object)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !compiler.IsBytecode(src) {
+		t.Fatalf("%s does not hold bytecode", object)
+	}
+
+	tests := []struct {
+		args     []string
+		status   int
+		contains string
+	}{
+		{[]string{"run", object}, exitOk, ""},
+		{[]string{"run", object, "ho"}, exitError, "got ho"},
+		{[]string{"run", "--engine=eval", object}, exitError, "compiled bytecode and can only run on the vm"},
+	}
+
+	for _, tt := range tests {
+		status, errOut := runCli(tt.args...)
+		if status != tt.status {
+			t.Errorf("wrong exit status for %q. want %d, got %d (%s)", tt.args, tt.status, status, errOut)
+		}
+		if !strings.Contains(errOut, tt.contains) {
+			t.Errorf("wrong output for %q. want %q in %q", tt.args, tt.contains, errOut)
+		}
+	}
+
+	truncated := writeScript(t, "truncated.crabc", string(src[:len(src)/2]))
+	if status, _ := runCli("run", truncated); status != exitError {
+		t.Errorf("wrong exit status for truncated bytecode. want %d, got %d", exitError, status)
+	}
+
+	broken := writeScript(t, "broken.crab", `let = 1`)
+	if status, _ := runCli("-o", filepath.Join(t.TempDir(), "out.crabc"), broken); status != exitError {
+		t.Errorf("wrong exit status compiling a broken script. want %d, got %d", exitError, status)
+	}
+}
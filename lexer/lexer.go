@@ -2,6 +2,9 @@ package lexer
 
 import (
 	"crabscript.rs/token"
+	"fmt"
+	"strconv"
+	"strings"
 	"unicode"
 	"unicode/utf8"
 )
@@ -13,17 +16,39 @@ type Lexer struct {
 	position     int  // current index into input
 	readPosition int  //current reading pos in input (position + 1)
 	ch           rune // current char
+
+	filename string // source file name, empty for repl input
+	line     int    // line of the current char
+	column   int    // column of the current char, in runes
+
+	errors   []Error       // lexing errors, in source order
+	comments []token.Token // comments skipped over, kept for tooling
+
+	insertSemi bool   // a newline after the last token ends the statement
+	nesting    []rune // brackets opened and not yet closed, innermost last
 }
 
 // New creates a new Lexer instance
 func New(input string) *Lexer {
-	l := &Lexer{input: input}
+	return NewWithFile("", input)
+}
+
+// NewWithFile creates a new Lexer instance whose token positions
+// refer to the given file name
+func NewWithFile(filename string, input string) *Lexer {
+	l := &Lexer{input: input, filename: filename, line: 1}
 	l.readChar()
 	return l
 }
 
 // Gets the next char and increments index
 func (l *Lexer) readChar() {
+	if l.ch == '\n' {
+		l.line++
+		l.column = 0
+	}
+	l.column++
+
 	inpSlice := l.input[l.readPosition:]
 	runeChar, runeSize := utf8.DecodeRune([]byte(inpSlice))
 	if len(inpSlice) <= 0 {
@@ -35,6 +60,16 @@ func (l *Lexer) readChar() {
 	l.readPosition += runeSize
 }
 
+// position of the current char
+func (l *Lexer) curPosition() token.Position {
+	return token.Position{
+		Filename: l.filename,
+		Offset:   l.position,
+		Line:     l.line,
+		Column:   l.column,
+	}
+}
+
 // Gets current char being read
 func (l *Lexer) peekChar() rune {
 	inpSlice := l.input[l.readPosition:]
@@ -46,12 +81,109 @@ func (l *Lexer) peekChar() rune {
 	}
 }
 
+// Gets the char n places after the current char
+func (l *Lexer) peekCharN(n int) rune {
+	inpSlice := l.input[l.readPosition:]
+	for ; n > 1 && len(inpSlice) > 0; n-- {
+		_, runeSize := utf8.DecodeRuneInString(inpSlice)
+		inpSlice = inpSlice[runeSize:]
+	}
+
+	runeChar, _ := utf8.DecodeRuneInString(inpSlice)
+	if len(inpSlice) <= 0 {
+		return 0
+	}
+	return runeChar
+}
+
 // moves to the next token, in cases such as '==' this would move 2 bytes
 // instead of 1.
+//
+// Like Go, a newline after an identifier, literal, closing bracket or one
+// of `return`, `break` and `continue` is returned as a semicolon with the
+// literal "\n". No semicolon is inserted inside parens or brackets, or
+// before an `else`, `catch`, `finally`, `|>`, `&&` or `||` starting the
+// next line.
 func (l *Lexer) NextToken() token.Token {
-	var tok token.Token
+	newline, sawNewline := l.swallowWhitespace()
+	if sawNewline && l.insertSemi && !l.startsWithClause() && !l.startsWithOperator() {
+		l.insertSemi = false
+		return token.Token{Type: token.Semicolon, Literal: "\n", Pos: newline, End: newline}
+	}
+
+	start := l.curPosition()
+	tok := l.nextToken()
+	tok.Pos = start
+	tok.End = l.curPosition()
+
+	l.trackNesting(tok.Type)
+	l.insertSemi = endsStatement(tok.Type) && !l.inBrackets()
 
-	l.swallowWhitespace()
+	return tok
+}
+
+// tokens after which a newline ends the statement
+func endsStatement(t token.TokenType) bool {
+	switch t {
+	case token.Ident, token.Int, token.Float, token.String, token.StringEnd, token.True, token.False,
+		token.Return, token.Break, token.Continue,
+		token.RParen, token.RBracket, token.RBrace:
+		return true
+	}
+	return false
+}
+
+func (l *Lexer) trackNesting(t token.TokenType) {
+	switch t {
+	case token.LParen:
+		l.nesting = append(l.nesting, '(')
+	case token.LBracket:
+		l.nesting = append(l.nesting, '[')
+	case token.LBrace:
+		l.nesting = append(l.nesting, '{')
+	case token.RParen, token.RBracket, token.RBrace:
+		if len(l.nesting) > 0 {
+			l.nesting = l.nesting[:len(l.nesting)-1]
+		}
+	}
+}
+
+// reports whether the innermost open bracket is a paren, square bracket or
+// interpolation, where newlines never end a statement
+func (l *Lexer) inBrackets() bool {
+	if len(l.nesting) == 0 {
+		return false
+	}
+	innermost := l.nesting[len(l.nesting)-1]
+	return innermost == '(' || innermost == '[' || innermost == '$'
+}
+
+// reports whether the input continues with a keyword that carries on the
+// expression before it, so that `}` and `else`, `catch` or `finally` may
+// be split across lines
+func (l *Lexer) startsWithClause() bool {
+	rest := l.input[l.position:]
+	for _, keyword := range []string{"else", "catch", "finally"} {
+		if !strings.HasPrefix(rest, keyword) {
+			continue
+		}
+		next, _ := utf8.DecodeRuneInString(rest[len(keyword):])
+		if !unicode.IsLetter(next) && !unicode.IsDigit(next) && next != '_' {
+			return true
+		}
+	}
+	return false
+}
+
+// reports whether the input continues with `|>`, `&&` or `||`, so that a
+// chain of pipes or conditions may be split across lines
+func (l *Lexer) startsWithOperator() bool {
+	rest := l.input[l.position:]
+	return strings.HasPrefix(rest, "|>") || strings.HasPrefix(rest, "&&") || strings.HasPrefix(rest, "||")
+}
+
+func (l *Lexer) nextToken() token.Token {
+	var tok token.Token
 
 	switch l.ch {
 	case '=':
@@ -61,6 +193,8 @@ func (l *Lexer) NextToken() token.Token {
 			l.readChar()
 			tok.Type = token.Eq
 			tok.Literal = string(oldCh) + string(l.ch)
+		} else if l.peekChar() == '>' {
+			tok = l.newTwoCharToken(token.Arrow)
 		} else {
 			tok = newToken(token.Assign, l.ch)
 		}
@@ -73,15 +207,51 @@ func (l *Lexer) NextToken() token.Token {
 	case '{':
 		tok = newToken(token.LBrace, l.ch)
 	case '}':
-		tok = newToken(token.RBrace, l.ch)
+		if l.inInterpolation() {
+			// the interpolated expression is done, carry on with the string
+			l.nesting = l.nesting[:len(l.nesting)-1]
+			tok = l.readStringToken(token.StringEnd)
+		} else {
+			tok = newToken(token.RBrace, l.ch)
+		}
 	case '+':
-		tok = newToken(token.Plus, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.PlusAssign)
+		} else {
+			tok = newToken(token.Plus, l.ch)
+		}
 	case '-':
-		tok = newToken(token.Minus, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.MinusAssign)
+		} else {
+			tok = newToken(token.Minus, l.ch)
+		}
 	case '>':
-		tok = newToken(token.Gt, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.newTwoCharToken(token.Ge)
+		case '>':
+			if strings.HasPrefix(l.input[l.position:], ">>=") {
+				tok = l.newThreeCharToken(token.ShrAssign)
+			} else {
+				tok = l.newTwoCharToken(token.Shr)
+			}
+		default:
+			tok = newToken(token.Gt, l.ch)
+		}
 	case '<':
-		tok = newToken(token.Lt, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.newTwoCharToken(token.Le)
+		case '<':
+			if strings.HasPrefix(l.input[l.position:], "<<=") {
+				tok = l.newThreeCharToken(token.ShlAssign)
+			} else {
+				tok = l.newTwoCharToken(token.Shl)
+			}
+		default:
+			tok = newToken(token.Lt, l.ch)
+		}
 	case '!':
 		if l.peekChar() == '=' {
 			// 2B width token need to read next char
@@ -93,30 +263,86 @@ func (l *Lexer) NextToken() token.Token {
 			tok = newToken(token.Bang, l.ch)
 		}
 	case '*':
-		tok = newToken(token.Asterisk, l.ch)
+		switch l.peekChar() {
+		case '=':
+			tok = l.newTwoCharToken(token.AsteriskAssign)
+		case '*':
+			if strings.HasPrefix(l.input[l.position:], "**=") {
+				tok = l.newThreeCharToken(token.PowerAssign)
+			} else {
+				tok = l.newTwoCharToken(token.Power)
+			}
+		default:
+			tok = newToken(token.Asterisk, l.ch)
+		}
+	case '%':
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.PercentAssign)
+		} else {
+			tok = newToken(token.Percent, l.ch)
+		}
 	case '/':
-		tok = newToken(token.Slash, l.ch)
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.SlashAssign)
+		} else {
+			tok = newToken(token.Slash, l.ch)
+		}
+	case '&':
+		switch l.peekChar() {
+		case '&':
+			tok = l.newTwoCharToken(token.And)
+		case '=':
+			tok = l.newTwoCharToken(token.BitAndAssign)
+		default:
+			tok = newToken(token.BitAnd, l.ch)
+		}
+	case '|':
+		switch l.peekChar() {
+		case '|':
+			tok = l.newTwoCharToken(token.Or)
+		case '>':
+			tok = l.newTwoCharToken(token.Pipe)
+		case '=':
+			tok = l.newTwoCharToken(token.BitOrAssign)
+		default:
+			tok = newToken(token.BitOr, l.ch)
+		}
+	case '^':
+		if l.peekChar() == '=' {
+			tok = l.newTwoCharToken(token.BitXorAssign)
+		} else {
+			tok = newToken(token.BitXor, l.ch)
+		}
 	case ',':
 		tok = newToken(token.Comma, l.ch)
 	case '"':
+		tok = l.readStringToken(token.String)
+	case '`':
 		tok.Type = token.String
-		tok.Literal = l.readString()
+		tok.Literal = l.readRawString()
 	case '[':
 		tok = newToken(token.LBracket, l.ch)
 	case ']':
 		tok = newToken(token.RBracket, l.ch)
 	case ':':
 		tok = newToken(token.Colon, l.ch)
+	case '.':
+		if strings.HasPrefix(l.input[l.position:], "...") {
+			l.readChar()
+			l.readChar()
+			tok = token.Token{Type: token.Ellipsis, Literal: "..."}
+		} else {
+			tok = newToken(token.Dot, l.ch)
+		}
 	case 0:
 		tok = newToken(token.Eof, l.ch)
 	default: // character
-		if unicode.IsDigit(l.ch) {
-			tok.Type = token.Int
-			tok.Literal = l.readNumber()
+		if isDigit(l.ch) {
+			tok.Literal, tok.Type = l.readNumber()
 			return tok
 			// TODO assume all non-digit valid chars are usable letters
 			// TODO this will allow emojis as bindings
-		} else if unicode.IsLetter(l.ch) {
+		} else if unicode.IsLetter(l.ch) || l.ch == '_' {
 			tok.Literal = l.readIdentifier()
 			tok.Type = token.LookupIdent(tok.Literal)
 			return tok
@@ -128,17 +354,132 @@ func (l *Lexer) NextToken() token.Token {
 	return tok
 }
 
-func (l *Lexer) readString() string {
+// reads the rest of a double quoted string as a token of type end, or as a
+// StringPart when it is cut short by a `${` starting an interpolation. The
+// lexer then returns the tokens of the interpolated expression, and picks up
+// the string again at the `}` matching the `${`.
+func (l *Lexer) readStringToken(end token.TokenType) token.Token {
+	literal, interpolated := l.readString()
+	if interpolated {
+		l.nesting = append(l.nesting, '$')
+		return token.Token{Type: token.StringPart, Literal: literal}
+	}
+	return token.Token{Type: end, Literal: literal}
+}
+
+// reads a double quoted string, decoding escape sequences, up to the closing
+// quote or a `${`, reporting which of the two it stopped at
+func (l *Lexer) readString() (string, bool) {
+	start := l.curPosition()
+	var out strings.Builder
+
+	for {
+		l.readChar()
+		switch l.ch {
+		case '"':
+			return out.String(), false
+		case 0:
+			l.errorAt(start, "unterminated string literal")
+			return out.String(), false
+		case '\\':
+			l.readEscape(&out)
+		case '$':
+			if l.peekChar() == '{' {
+				l.readChar()
+				return out.String(), true
+			}
+			out.WriteRune(l.ch)
+		default:
+			out.WriteRune(l.ch)
+		}
+	}
+}
+
+// reports whether the innermost open bracket is the `${` of an interpolation
+func (l *Lexer) inInterpolation() bool {
+	return len(l.nesting) > 0 && l.nesting[len(l.nesting)-1] == '$'
+}
+
+// reads a backtick quoted string, which has no escapes and may span lines
+func (l *Lexer) readRawString() string {
+	start := l.curPosition()
 	position := l.position + 1
 
 	for {
 		l.readChar()
-		if l.ch == '"' || l.ch == 0 {
-			break
+		switch l.ch {
+		case '`':
+			return l.input[position:l.position]
+		case 0:
+			l.errorAt(start, "unterminated raw string literal")
+			return l.input[position:l.position]
 		}
 	}
+}
 
-	return l.input[position:l.position]
+// decodes the escape sequence starting at the current '\'
+func (l *Lexer) readEscape(out *strings.Builder) {
+	start := l.curPosition()
+	l.readChar()
+
+	switch l.ch {
+	case 'n':
+		out.WriteRune('\n')
+	case 't':
+		out.WriteRune('\t')
+	case 'r':
+		out.WriteRune('\r')
+	case '0':
+		out.WriteRune(0)
+	case '\\', '"', '$':
+		out.WriteRune(l.ch)
+	case '\n':
+		// escaped newline continues the string on the next line
+	case 'u':
+		l.readUnicodeEscape(start, out)
+	case 0:
+		// unterminated, reported by readString
+	default:
+		l.errorAt(start, fmt.Sprintf("unknown escape sequence \\%c", l.ch))
+		out.WriteRune('\\')
+		out.WriteRune(l.ch)
+	}
+}
+
+// decodes a '\u{1F980}' escape, the current char is the 'u'
+func (l *Lexer) readUnicodeEscape(start token.Position, out *strings.Builder) {
+	if l.peekChar() != '{' {
+		l.errorAt(start, "invalid unicode escape, want \\u{hex}")
+		return
+	}
+	l.readChar()
+
+	var digits strings.Builder
+	for l.peekChar() != '}' {
+		if !isHexDigit(l.peekChar()) {
+			l.errorAt(start, "invalid unicode escape, want \\u{hex}")
+			return
+		}
+		l.readChar()
+		digits.WriteRune(l.ch)
+	}
+	l.readChar()
+
+	value, err := strconv.ParseUint(digits.String(), 16, 32)
+	if err != nil || digits.Len() > 6 || !utf8.ValidRune(rune(value)) {
+		l.errorAt(start, fmt.Sprintf("invalid unicode code point %q", digits.String()))
+		return
+	}
+	out.WriteRune(rune(value))
+}
+
+// only ASCII digits make up numbers, unlike unicode.IsDigit
+func isDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9'
+}
+
+func isHexDigit(ch rune) bool {
+	return '0' <= ch && ch <= '9' || 'a' <= ch && ch <= 'f' || 'A' <= ch && ch <= 'F'
 }
 
 // creates a token from a rune type
@@ -150,24 +491,211 @@ func newToken(tokenType token.TokenType, ch rune) token.Token {
 	return token.Token{Type: tokenType, Literal: str}
 }
 
+// reads an identifier, which starts with a letter that may be followed by
+// letters, digits and underscores
 func (l *Lexer) readIdentifier() string {
 	position := l.position
-	for unicode.IsLetter(l.ch) {
+	for unicode.IsLetter(l.ch) || unicode.IsDigit(l.ch) || l.ch == '_' {
 		l.readChar()
 	}
 	return l.input[position:l.position]
 }
 
-func (l *Lexer) swallowWhitespace() {
-	for unicode.IsSpace(l.ch) {
+// skips whitespace and comments, returning the position of the first
+// newline skipped if any
+func (l *Lexer) swallowWhitespace() (token.Position, bool) {
+	var newline token.Position
+	sawNewline := false
+
+	for {
+		switch {
+		case l.ch == '\n' && !sawNewline:
+			newline = l.curPosition()
+			sawNewline = true
+			l.readChar()
+		case unicode.IsSpace(l.ch):
+			l.readChar()
+		case l.ch == '/' && l.peekChar() == '/':
+			l.readLineComment()
+		case l.ch == '/' && l.peekChar() == '*':
+			start := l.curPosition()
+			l.readBlockComment()
+			// a block comment spanning lines counts as a newline
+			if l.line > start.Line && !sawNewline {
+				newline = start
+				sawNewline = true
+			}
+		default:
+			return newline, sawNewline
+		}
+	}
+}
+
+// reads a '//' comment up to (not including) the end of the line
+func (l *Lexer) readLineComment() {
+	start := l.curPosition()
+
+	for l.ch != '\n' && l.ch != 0 {
 		l.readChar()
 	}
+
+	l.addComment(start)
+}
+
+// reads a '/* */' comment, which may contain nested block comments
+func (l *Lexer) readBlockComment() {
+	start := l.curPosition()
+	depth := 0
+
+	for {
+		switch {
+		case l.ch == 0:
+			l.errorAt(start, "unterminated block comment")
+			l.addComment(start)
+			return
+		case l.ch == '/' && l.peekChar() == '*':
+			depth++
+			l.readChar()
+			l.readChar()
+		case l.ch == '*' && l.peekChar() == '/':
+			depth--
+			l.readChar()
+			l.readChar()
+			if depth == 0 {
+				l.addComment(start)
+				return
+			}
+		default:
+			l.readChar()
+		}
+	}
+}
+
+// records the comment between start and the current char
+func (l *Lexer) addComment(start token.Position) {
+	l.comments = append(l.comments, token.Token{
+		Type:    token.Comment,
+		Literal: l.input[start.Offset:l.position],
+		Pos:     start,
+		End:     l.curPosition(),
+	})
+}
+
+// Comments returns the comments read so far, in source order
+func (l *Lexer) Comments() []token.Token {
+	return l.comments
+}
+
+// Error is a lexing error at a position in the source
+type Error struct {
+	Pos token.Position
+	Msg string
+}
+
+func (e Error) Error() string {
+	return fmt.Sprintf("%s: %s", e.Pos, e.Msg)
+}
+
+// Errors returns the lexing errors found so far, prefixed with their position
+func (l *Lexer) Errors() []string {
+	errors := make([]string, len(l.errors))
+	for i, err := range l.errors {
+		errors[i] = err.Error()
+	}
+	return errors
+}
+
+// ErrorList returns the lexing errors found so far
+func (l *Lexer) ErrorList() []Error {
+	return l.errors
+}
+
+func (l *Lexer) errorAt(pos token.Position, msg string) {
+	l.errors = append(l.errors, Error{Pos: pos, Msg: msg})
+}
+
+// SourceLine returns the line of input containing pos, without its newline
+func (l *Lexer) SourceLine(pos token.Position) string {
+	offset := pos.Offset
+	if offset < 0 || offset > len(l.input) {
+		return ""
+	}
+
+	start := strings.LastIndexByte(l.input[:offset], '\n') + 1
+	end := strings.IndexByte(l.input[offset:], '\n')
+	if end < 0 {
+		return l.input[start:]
+	}
+	return strings.TrimSuffix(l.input[start:offset+end], "\r")
 }
 
-func (l *Lexer) readNumber() string {
+// reads an integer or a float such as 1.5, 2e10 or 1.5e-3. Integers may
+// have a 0x, 0o or 0b prefix, and digits may be separated by underscores
+// as in 1_000_000. Misplaced underscores are left for the parser to report.
+func (l *Lexer) readNumber() (string, token.TokenType) {
 	position := l.position
-	for unicode.IsNumber(l.ch) {
+	tokType := token.TokenType(token.Int)
+
+	if l.ch == '0' && isBasePrefix(l.peekChar()) {
+		l.readChar()
 		l.readChar()
+		for isHexDigit(l.ch) || l.ch == '_' {
+			l.readChar()
+		}
+		return l.input[position:l.position], tokType
 	}
-	return l.input[position:l.position]
+
+	l.readDigits()
+
+	// only a '.' followed by a digit starts a fraction
+	if l.ch == '.' && isDigit(l.peekChar()) {
+		tokType = token.Float
+		l.readChar()
+		l.readDigits()
+	}
+
+	if l.ch == 'e' || l.ch == 'E' {
+		next := l.peekChar()
+		if next == '+' || next == '-' {
+			next = l.peekCharN(2)
+		}
+		if isDigit(next) {
+			tokType = token.Float
+			l.readChar()
+			if l.ch == '+' || l.ch == '-' {
+				l.readChar()
+			}
+			l.readDigits()
+		}
+	}
+
+	return l.input[position:l.position], tokType
+}
+
+func (l *Lexer) readDigits() {
+	for isDigit(l.ch) || l.ch == '_' {
+		l.readChar()
+	}
+}
+
+func isBasePrefix(ch rune) bool {
+	switch ch {
+	case 'x', 'X', 'o', 'O', 'b', 'B':
+		return true
+	}
+	return false
+}
+
+// 2B width token, reads the next char as part of the token
+func (l *Lexer) newTwoCharToken(tokenType token.TokenType) token.Token {
+	ch := l.ch
+	l.readChar()
+	return token.Token{Type: tokenType, Literal: string(ch) + string(l.ch)}
+}
+
+func (l *Lexer) newThreeCharToken(tokenType token.TokenType) token.Token {
+	start := l.position
+	l.readChar()
+	l.readChar()
+	return token.Token{Type: tokenType, Literal: l.input[start : l.position+1]}
 }
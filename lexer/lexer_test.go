@@ -12,7 +12,7 @@ let ten = 10;
      x + y;
 };
    let result = add(five, ten);
-   !-/*5;
+   !-/ *5;
 5 < 10 > 5;
 
 if (5 < 10) {
@@ -100,6 +100,7 @@ return false;
 		{token.False, "false"},
 		{token.Semicolon, ";"},
 		{token.RBrace, "}"},
+		{token.Semicolon, "\n"},
 		{token.Int, "10"},
 		{token.Eq, "=="},
 		{token.Int, "10"},
@@ -109,9 +110,13 @@ return false;
 		{token.Int, "9"},
 		{token.Semicolon, ";"},
 		{token.String, "foobar"},
+		{token.Semicolon, "\n"},
 		{token.String, "foo bar"},
+		{token.Semicolon, "\n"},
 		{token.String, "crab🦀"},
+		{token.Semicolon, "\n"},
 		{token.String, "🦀crab"},
+		{token.Semicolon, "\n"},
 		{token.LBracket, "["},
 		{token.Int, "1"},
 		{token.Comma, ","},
@@ -123,6 +128,701 @@ return false;
 		{token.Colon, ":"},
 		{token.String, "bar"},
 		{token.RBrace, "}"},
+		{token.Semicolon, "\n"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTokenPositions(t *testing.T) {
+	input := "let a = 1;\n  \"🦀\" + a"
+
+	tests := []struct {
+		expectedType token.TokenType
+		line         int
+		column       int
+		offset       int
+		endOffset    int
+	}{
+		{token.Let, 1, 1, 0, 3},
+		{token.Ident, 1, 5, 4, 5},
+		{token.Assign, 1, 7, 6, 7},
+		{token.Int, 1, 9, 8, 9},
+		{token.Semicolon, 1, 10, 9, 10},
+		{token.String, 2, 3, 13, 19},
+		{token.Plus, 2, 7, 20, 21},
+		{token.Ident, 2, 9, 22, 23},
+		{token.Eof, 2, 10, 23, 23},
+	}
+
+	l := NewWithFile("main.crab", input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Pos.Line != tt.line || tok.Pos.Column != tt.column {
+			t.Errorf("test[%v]: position wrong. Expected %d:%d, got %d:%d", i, tt.line, tt.column, tok.Pos.Line, tok.Pos.Column)
+		}
+		if tok.Pos.Offset != tt.offset || tok.End.Offset != tt.endOffset {
+			t.Errorf("test[%v]: span wrong. Expected [%d, %d), got [%d, %d)", i, tt.offset, tt.endOffset, tok.Pos.Offset, tok.End.Offset)
+		}
+		if tok.Pos.Filename != "main.crab" {
+			t.Errorf("test[%v]: file name wrong. Expected main.crab, got %q", i, tok.Pos.Filename)
+		}
+	}
+}
+
+func TestComments(t *testing.T) {
+	input := `// leading comment
+let a = 1; // trailing comment
+/* block
+   /* nested */ still comment */
+a / 2 /**/ * 3
+`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Let, "let"},
+		{token.Ident, "a"},
+		{token.Assign, "="},
+		{token.Int, "1"},
+		{token.Semicolon, ";"},
+		{token.Ident, "a"},
+		{token.Slash, "/"},
+		{token.Int, "2"},
+		{token.Asterisk, "*"},
+		{token.Int, "3"},
+		{token.Semicolon, "\n"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+
+	expectedComments := []struct {
+		literal string
+		line    int
+		column  int
+	}{
+		{"// leading comment", 1, 1},
+		{"// trailing comment", 2, 12},
+		{"/* block\n   /* nested */ still comment */", 3, 1},
+		{"/**/", 5, 7},
+	}
+
+	comments := l.Comments()
+	if len(comments) != len(expectedComments) {
+		t.Fatalf("wrong number of comments. Expected %d, got %d", len(expectedComments), len(comments))
+	}
+	for i, ec := range expectedComments {
+		c := comments[i]
+		if c.Type != token.Comment || c.Literal != ec.literal {
+			t.Errorf("comment[%v] wrong. Expected %q, got %v %q", i, ec.literal, c.Type, c.Literal)
+		}
+		if c.Pos.Line != ec.line || c.Pos.Column != ec.column {
+			t.Errorf("comment[%v] position wrong. Expected %d:%d, got %s", i, ec.line, ec.column, c.Pos)
+		}
+	}
+
+	if len(l.Errors()) != 0 {
+		t.Errorf("unexpected lexer errors: %v", l.Errors())
+	}
+}
+
+func TestUnterminatedBlockComment(t *testing.T) {
+	l := New("1 + /* open /* nested */ never closed")
+
+	for tok := l.NextToken(); tok.Type != token.Eof; tok = l.NextToken() {
+	}
+
+	errors := l.Errors()
+	if len(errors) != 1 {
+		t.Fatalf("expected 1 lexer error, got %v", errors)
+	}
+	if errors[0] != "1:5: unterminated block comment" {
+		t.Errorf("wrong error, got %q", errors[0])
+	}
+}
+
+func TestStringEscapes(t *testing.T) {
+	tests := []struct {
+		input           string
+		expectedLiteral string
+	}{
+		{`"say \"hi\""`, `say "hi"`},
+		{`"a\nb\tc\rd"`, "a\nb\tc\rd"},
+		{`"back\\slash"`, `back\slash`},
+		{`"nul\0"`, "nul\x00"},
+		{`"crab \u{1F980}"`, "crab 🦀"},
+		{`"\u{41}\u{e9}"`, "Aé"},
+		{"\"one \\\ntwo\"", "one two"},
+		{"`raw \\n \"quoted\"`", `raw \n "quoted"`},
+		{"`multi\nline`", "multi\nline"},
+		{"``", ""},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		tok := l.NextToken()
+		if tok.Type != token.String {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, token.String, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Errorf("test[%v]: Literal wrong. Expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+		if len(l.Errors()) != 0 {
+			t.Errorf("test[%v]: unexpected lexer errors: %v", i, l.Errors())
+		}
+		if next := l.NextToken(); next.Type != token.Eof {
+			t.Errorf("test[%v]: expected Eof after string, got %v", i, next.Type)
+		}
+	}
+}
+
+func TestStringErrors(t *testing.T) {
+	tests := []struct {
+		input         string
+		expectedError string
+	}{
+		{`"bad \q escape"`, `1:6: unknown escape sequence \q`},
+		{`"no end`, "1:1: unterminated string literal"},
+		{`let s = "trailing \`, "1:9: unterminated string literal"},
+		{"`no end", "1:1: unterminated raw string literal"},
+		{`"\u1F980"`, `1:2: invalid unicode escape, want \u{hex}`},
+		{`"\u{1F98"`, `1:2: invalid unicode escape, want \u{hex}`},
+		{`"\u{110000}"`, `1:2: invalid unicode code point "110000"`},
+		{`"\u{}"`, `1:2: invalid unicode code point ""`},
+	}
+
+	for i, tt := range tests {
+		l := New(tt.input)
+		for tok := l.NextToken(); tok.Type != token.Eof; tok = l.NextToken() {
+		}
+
+		errors := l.Errors()
+		if len(errors) == 0 {
+			t.Errorf("test[%v]: expected lexer error for %s", i, tt.input)
+			continue
+		}
+		if errors[0] != tt.expectedError {
+			t.Errorf("test[%v]: wrong error. Expected %q, got %q", i, tt.expectedError, errors[0])
+		}
+	}
+}
+
+func TestNumbers(t *testing.T) {
+	input := `1 1.5 0.25 1e3 1e-3 2.5E+10 7e [1.x] 1_000 12٣ ٣`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Int, "1"},
+		{token.Float, "1.5"},
+		{token.Float, "0.25"},
+		{token.Float, "1e3"},
+		{token.Float, "1e-3"},
+		{token.Float, "2.5E+10"},
+		{token.Int, "7"},
+		{token.Ident, "e"},
+		{token.LBracket, "["},
+		{token.Int, "1"},
+		{token.Dot, "."},
+		{token.Ident, "x"},
+		{token.RBracket, "]"},
+		{token.Int, "1_000"},
+		{token.Int, "12"},
+		{token.Illegal, "٣"},
+		{token.Illegal, "٣"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestAssignmentOperators(t *testing.T) {
+	input := `a = b += c -= d *= e /= f == g %= h **= i &= j |= k ^= l <<= m >>= n && o`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Ident, "a"},
+		{token.Assign, "="},
+		{token.Ident, "b"},
+		{token.PlusAssign, "+="},
+		{token.Ident, "c"},
+		{token.MinusAssign, "-="},
+		{token.Ident, "d"},
+		{token.AsteriskAssign, "*="},
+		{token.Ident, "e"},
+		{token.SlashAssign, "/="},
+		{token.Ident, "f"},
+		{token.Eq, "=="},
+		{token.Ident, "g"},
+		{token.PercentAssign, "%="},
+		{token.Ident, "h"},
+		{token.PowerAssign, "**="},
+		{token.Ident, "i"},
+		{token.BitAndAssign, "&="},
+		{token.Ident, "j"},
+		{token.BitOrAssign, "|="},
+		{token.Ident, "k"},
+		{token.BitXorAssign, "^="},
+		{token.Ident, "l"},
+		{token.ShlAssign, "<<="},
+		{token.Ident, "m"},
+		{token.ShrAssign, ">>="},
+		{token.Ident, "n"},
+		{token.And, "&&"},
+		{token.Ident, "o"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestLoopKeywords(t *testing.T) {
+	input := `while for in break continue inside`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.While, "while"},
+		{token.For, "for"},
+		{token.In, "in"},
+		{token.Break, "break"},
+		{token.Continue, "continue"},
+		{token.Ident, "inside"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestEllipsis(t *testing.T) {
+	input := `let [a, ...rest] = xs; ..`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Let, "let"},
+		{token.LBracket, "["},
+		{token.Ident, "a"},
+		{token.Comma, ","},
+		{token.Ellipsis, "..."},
+		{token.Ident, "rest"},
+		{token.RBracket, "]"},
+		{token.Assign, "="},
+		{token.Ident, "xs"},
+		{token.Semicolon, ";"},
+		{token.Dot, "."},
+		{token.Dot, "."},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestLogicalOperators(t *testing.T) {
+	input := `a && b || c & d | e |> f`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Ident, "a"},
+		{token.And, "&&"},
+		{token.Ident, "b"},
+		{token.Or, "||"},
+		{token.Ident, "c"},
+		{token.BitAnd, "&"},
+		{token.Ident, "d"},
+		{token.BitOr, "|"},
+		{token.Ident, "e"},
+		{token.Pipe, "|>"},
+		{token.Ident, "f"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+// a line starting with `&&`, `||` or `|>` carries on the one before it
+func TestOperatorsContinueLines(t *testing.T) {
+	input := "a\n  && b\n  || c\n  |> f\n& d"
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Ident, "a"},
+		{token.And, "&&"},
+		{token.Ident, "b"},
+		{token.Or, "||"},
+		{token.Ident, "c"},
+		{token.Pipe, "|>"},
+		{token.Ident, "f"},
+		{token.Semicolon, "\n"},
+		{token.BitAnd, "&"},
+		{token.Ident, "d"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestArithmeticAndBitwiseOperators(t *testing.T) {
+	input := `a <= b >= c % d ** e * f & g | h ^ i << j >> k < l > m`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Ident, "a"},
+		{token.Le, "<="},
+		{token.Ident, "b"},
+		{token.Ge, ">="},
+		{token.Ident, "c"},
+		{token.Percent, "%"},
+		{token.Ident, "d"},
+		{token.Power, "**"},
+		{token.Ident, "e"},
+		{token.Asterisk, "*"},
+		{token.Ident, "f"},
+		{token.BitAnd, "&"},
+		{token.Ident, "g"},
+		{token.BitOr, "|"},
+		{token.Ident, "h"},
+		{token.BitXor, "^"},
+		{token.Ident, "i"},
+		{token.Shl, "<<"},
+		{token.Ident, "j"},
+		{token.Shr, ">>"},
+		{token.Ident, "k"},
+		{token.Lt, "<"},
+		{token.Ident, "l"},
+		{token.Gt, ">"},
+		{token.Ident, "m"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestIdentifiersAndIntegerLiterals(t *testing.T) {
+	input := `user_id v2 a_1_b 0x1F 0o17 0b1010 0XfF 1_000_000 1_000.5 0x_ff 2x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Ident, "user_id"},
+		{token.Ident, "v2"},
+		{token.Ident, "a_1_b"},
+		{token.Int, "0x1F"},
+		{token.Int, "0o17"},
+		{token.Int, "0b1010"},
+		{token.Int, "0XfF"},
+		{token.Int, "1_000_000"},
+		{token.Float, "1_000.5"},
+		{token.Int, "0x_ff"},
+		{token.Int, "2"},
+		{token.Ident, "x"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestSourceLine(t *testing.T) {
+	input := "let a = 1\r\nlet b = 2\n\nc"
+	l := New(input)
+
+	tests := []struct {
+		offset   int
+		expected string
+	}{
+		{0, "let a = 1"},
+		{4, "let a = 1"},
+		{11, "let b = 2"},
+		{20, "let b = 2"},
+		{21, ""},
+		{22, "c"},
+		{23, "c"},
+		{99, ""},
+	}
+
+	for _, tt := range tests {
+		line := l.SourceLine(token.Position{Offset: tt.offset})
+		if line != tt.expected {
+			t.Errorf("offset %d: wrong line. want %q, got %q", tt.offset, tt.expected, line)
+		}
+	}
+}
+
+func TestInterpolatedStrings(t *testing.T) {
+	input := `"user ${name} has ${len(items)} items" "${ {"a": "${b}"} }" "\${x} $y"
+"${a +
+b}"`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.StringPart, "user "},
+		{token.Ident, "name"},
+		{token.StringPart, " has "},
+		{token.Ident, "len"},
+		{token.LParen, "("},
+		{token.Ident, "items"},
+		{token.RParen, ")"},
+		{token.StringEnd, " items"},
+		{token.StringPart, ""},
+		{token.LBrace, "{"},
+		{token.String, "a"},
+		{token.Colon, ":"},
+		{token.StringPart, ""},
+		{token.Ident, "b"},
+		{token.StringEnd, ""},
+		{token.RBrace, "}"},
+		{token.StringEnd, ""},
+		{token.String, "${x} $y"},
+		{token.Semicolon, "\n"},
+		{token.StringPart, ""},
+		{token.Ident, "a"},
+		{token.Plus, "+"},
+		{token.Ident, "b"},
+		{token.StringEnd, ""},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %q, got %q", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestMatchTokens(t *testing.T) {
+	input := `match (x) { _ => 1, [_a] => 2 }`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Match, "match"},
+		{token.LParen, "("},
+		{token.Ident, "x"},
+		{token.RParen, ")"},
+		{token.LBrace, "{"},
+		{token.Ident, "_"},
+		{token.Arrow, "=>"},
+		{token.Int, "1"},
+		{token.Comma, ","},
+		{token.LBracket, "["},
+		{token.Ident, "_a"},
+		{token.RBracket, "]"},
+		{token.Arrow, "=>"},
+		{token.Int, "2"},
+		{token.RBrace, "}"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestTryTokens(t *testing.T) {
+	input := `try { throw e }
+catch (e) { x }
+finally { y }
+catch_up`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Try, "try"},
+		{token.LBrace, "{"},
+		{token.Throw, "throw"},
+		{token.Ident, "e"},
+		{token.RBrace, "}"},
+		{token.Catch, "catch"},
+		{token.LParen, "("},
+		{token.Ident, "e"},
+		{token.RParen, ")"},
+		{token.LBrace, "{"},
+		{token.Ident, "x"},
+		{token.RBrace, "}"},
+		{token.Finally, "finally"},
+		{token.LBrace, "{"},
+		{token.Ident, "y"},
+		{token.RBrace, "}"},
+		{token.Semicolon, "\n"},
+		{token.Ident, "catch_up"},
+		{token.Eof, ""},
+	}
+
+	l := New(input)
+
+	for i, tt := range tests {
+		tok := l.NextToken()
+		if tok.Type != tt.expectedType {
+			t.Fatalf("test[%v]: Token type wrong. Expected %v, got %v", i, tt.expectedType, tok.Type)
+		}
+		if tok.Literal != tt.expectedLiteral {
+			t.Fatalf("test[%v]: Literal wrong. Expected %v, got %v", i, tt.expectedLiteral, tok.Literal)
+		}
+	}
+}
+
+func TestModuleTokens(t *testing.T) {
+	input := `import "lib/strings.crab" as str
+export let shout = str.upper
+1.5.x`
+
+	tests := []struct {
+		expectedType    token.TokenType
+		expectedLiteral string
+	}{
+		{token.Import, "import"},
+		{token.String, "lib/strings.crab"},
+		{token.As, "as"},
+		{token.Ident, "str"},
+		{token.Semicolon, "\n"},
+		{token.Export, "export"},
+		{token.Let, "let"},
+		{token.Ident, "shout"},
+		{token.Assign, "="},
+		{token.Ident, "str"},
+		{token.Dot, "."},
+		{token.Ident, "upper"},
+		{token.Semicolon, "\n"},
+		{token.Float, "1.5"},
+		{token.Dot, "."},
+		{token.Ident, "x"},
 		{token.Eof, ""},
 	}
 
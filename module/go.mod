@@ -0,0 +1,18 @@
+module crabscript.rs/module
+
+go 1.21.0
+
+replace (
+	crabscript.rs/ast => ../ast
+	crabscript.rs/lexer => ../lexer
+	crabscript.rs/parser => ../parser
+	crabscript.rs/token => ../token
+)
+
+require (
+	crabscript.rs/ast v0.0.0-00010101000000-000000000000
+	crabscript.rs/lexer v0.0.0-00010101000000-000000000000
+	crabscript.rs/parser v0.0.0-00010101000000-000000000000
+)
+
+require crabscript.rs/token v0.0.0-00010101000000-000000000000 // indirect
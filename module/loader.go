@@ -0,0 +1,137 @@
+// Package module finds and parses the script files a program imports with
+// `import "path/to/mod.crab" as m`, shared by the evaluator and the compiler.
+package module
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"crabscript.rs/ast"
+	"crabscript.rs/lexer"
+	"crabscript.rs/parser"
+)
+
+// Loader resolves imports relative to the importing file and then against
+// its search path. Each module is loaded once, later imports get the value
+// its first load returned, and an import cycle is an error.
+type Loader struct {
+	SearchPath []string // directories searched for modules not next to their importer
+
+	modules map[string]interface{} // loaded modules by absolute path
+	loading []loading              // files being loaded, outermost first
+}
+
+type loading struct {
+	key  string // absolute path
+	file string // path as resolved from the importer, for messages
+}
+
+// Error is an import that cannot be resolved or parsed, or that closes a
+// cycle. Load returns the errors of compiling or running a module as is.
+type Error struct {
+	Msg string
+}
+
+func (e *Error) Error() string {
+	return e.Msg
+}
+
+func NewLoader(searchPath ...string) *Loader {
+	return &Loader{SearchPath: searchPath, modules: map[string]interface{}{}}
+}
+
+// Load returns the module imported as path by the script file from. The
+// first time a module is imported it is parsed and handed to load, which
+// runs or compiles it, with from's own imports loaded through l as well.
+func (l *Loader) Load(from, path string, load func(*ast.Program) (interface{}, error)) (interface{}, error) {
+	file, err := l.Resolve(from, path)
+	if err != nil {
+		return nil, err
+	}
+	key, err := filepath.Abs(file)
+	if err != nil {
+		return nil, &Error{Msg: err.Error()}
+	}
+
+	if mod, ok := l.modules[key]; ok {
+		return mod, nil
+	}
+
+	// the script importing the first module is being loaded too, so that a
+	// module importing it back is a cycle
+	if len(l.loading) == 0 && from != "" {
+		root, err := filepath.Abs(from)
+		if err != nil {
+			return nil, &Error{Msg: err.Error()}
+		}
+		l.loading = append(l.loading, loading{root, from})
+		defer func() { l.loading = l.loading[:0] }()
+	}
+
+	for i, f := range l.loading {
+		if f.key == key {
+			files := []string{}
+			for _, f := range l.loading[i:] {
+				files = append(files, f.file)
+			}
+			return nil, &Error{Msg: "import cycle " + strings.Join(append(files, file), " -> ")}
+		}
+	}
+
+	program, err := parse(file)
+	if err != nil {
+		return nil, err
+	}
+
+	l.loading = append(l.loading, loading{key, file})
+	mod, err := load(program)
+	l.loading = l.loading[:len(l.loading)-1]
+	if err != nil {
+		return nil, err
+	}
+
+	l.modules[key] = mod
+	return mod, nil
+}
+
+// Resolve returns the file of the module imported as path by the script
+// file from, relative to from's directory or else to a directory of the
+// search path
+func (l *Loader) Resolve(from, path string) (string, error) {
+	if filepath.IsAbs(path) {
+		if isFile(path) {
+			return filepath.Clean(path), nil
+		}
+		return "", &Error{Msg: fmt.Sprintf("cannot find module %q", path)}
+	}
+
+	dirs := append([]string{filepath.Dir(from)}, l.SearchPath...)
+	for _, dir := range dirs {
+		file := filepath.Join(dir, path)
+		if isFile(file) {
+			return file, nil
+		}
+	}
+	return "", &Error{Msg: fmt.Sprintf("cannot find module %q in %s", path, strings.Join(dirs, ", "))}
+}
+
+func parse(file string) (*ast.Program, error) {
+	src, err := os.ReadFile(file)
+	if err != nil {
+		return nil, &Error{Msg: err.Error()}
+	}
+
+	p := parser.New(lexer.NewWithFile(file, string(src)))
+	program := p.ParseProgram()
+	if diagnostics := p.Diagnostics(); len(diagnostics) > 0 {
+		return nil, &Error{Msg: diagnostics[0].String()}
+	}
+	return program, nil
+}
+
+func isFile(path string) bool {
+	info, err := os.Stat(path)
+	return err == nil && info.Mode().IsRegular()
+}
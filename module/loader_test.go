@@ -0,0 +1,152 @@
+package module
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"crabscript.rs/ast"
+)
+
+// writes the files under a new directory, returning its path
+func writeFiles(t *testing.T, files map[string]string) string {
+	dir := t.TempDir()
+	for name, src := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestResolve(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.crab":         "",
+		"lib/a.crab":        "",
+		"lib/b.crab":        "",
+		"std/strings.crab":  "",
+		"std/lib/only.crab": "",
+	})
+	l := NewLoader(filepath.Join(dir, "std"))
+	main := filepath.Join(dir, "main.crab")
+
+	tests := []struct {
+		from     string
+		path     string
+		expected string
+	}{
+		{main, "lib/a.crab", "lib/a.crab"},
+		{filepath.Join(dir, "lib/a.crab"), "b.crab", "lib/b.crab"},
+		{filepath.Join(dir, "lib/a.crab"), "../main.crab", "main.crab"},
+		{main, "strings.crab", "std/strings.crab"},
+		{main, "lib/only.crab", "std/lib/only.crab"},
+		{main, filepath.Join(dir, "lib/b.crab"), "lib/b.crab"},
+	}
+
+	for _, tt := range tests {
+		file, err := l.Resolve(tt.from, tt.path)
+		if err != nil {
+			t.Errorf("unexpected error resolving %q: %s", tt.path, err)
+			continue
+		}
+		if expected := filepath.Join(dir, tt.expected); file != expected {
+			t.Errorf("wrong file for %q. want %q, got %q", tt.path, expected, file)
+		}
+	}
+
+	_, err := l.Resolve(main, "missing.crab")
+	if err == nil || !strings.HasPrefix(err.Error(), `cannot find module "missing.crab" in `) {
+		t.Errorf("wrong error for missing module: %v", err)
+	}
+	_, err = l.Resolve(main, "lib")
+	if err == nil {
+		t.Errorf("expected an error importing a directory")
+	}
+}
+
+// loads the imports of each module before it, recording the order modules
+// were loaded in
+func loadAll(l *Loader, loaded *[]string) func(*ast.Program) (interface{}, error) {
+	var load func(*ast.Program) (interface{}, error)
+	load = func(program *ast.Program) (interface{}, error) {
+		for _, st := range program.Statements {
+			if imp, ok := st.(*ast.ImportStatement); ok {
+				if _, err := l.Load(imp.Pos().Filename, imp.Path, load); err != nil {
+					return nil, err
+				}
+			}
+		}
+		*loaded = append(*loaded, program.String())
+		return program.String(), nil
+	}
+	return load
+}
+
+func TestLoad(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.crab": `import "a.crab" as a; import "b.crab" as b`,
+		"a.crab":    `import "b.crab" as b; let a = 1`,
+		"b.crab":    `let b = 2`,
+	})
+	l := NewLoader()
+	main := filepath.Join(dir, "main.crab")
+
+	loaded := []string{}
+	mod, err := l.Load(main, "a.crab", loadAll(l, &loaded))
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if mod != `import "b.crab" as b;let a = 1;` {
+		t.Errorf("wrong module: %v", mod)
+	}
+
+	if _, err := l.Load(main, "b.crab", loadAll(l, &loaded)); err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	expected := []string{"let b = 2;", `import "b.crab" as b;let a = 1;`}
+	if strings.Join(loaded, "|") != strings.Join(expected, "|") {
+		t.Errorf("modules not loaded once each. want %q, got %q", expected, loaded)
+	}
+}
+
+func TestLoadErrors(t *testing.T) {
+	dir := writeFiles(t, map[string]string{
+		"main.crab":   `import "a.crab" as a`,
+		"a.crab":      `import "b.crab" as b`,
+		"b.crab":      `import "a.crab" as a`,
+		"self.crab":   `import "self.crab" as me`,
+		"back.crab":   `import "main.crab" as main`,
+		"broken.crab": "let = 1",
+	})
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chdir(wd)
+
+	tests := []struct {
+		path     string
+		expected string
+	}{
+		{"a.crab", "import cycle a.crab -> b.crab -> a.crab"},
+		{"self.crab", "import cycle self.crab -> self.crab"},
+		{"back.crab", "import cycle main.crab -> back.crab -> main.crab"},
+		{"broken.crab", "broken.crab:1:5: expected next token Ident, got ="},
+	}
+
+	for _, tt := range tests {
+		l := NewLoader()
+		_, err := l.Load("main.crab", tt.path, loadAll(l, &[]string{}))
+		if err == nil || err.Error() != tt.expected {
+			t.Errorf("wrong error for %q. want %q, got %v", tt.path, tt.expected, err)
+		}
+	}
+}
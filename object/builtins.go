@@ -3,6 +3,9 @@ package object
 import (
 	"bytes"
 	"fmt"
+	"math"
+	"strconv"
+	"strings"
 	"unicode/utf8"
 )
 
@@ -158,6 +161,102 @@ var Builtins = []struct {
 			},
 		},
 	},
+	{
+		Name: "int",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got %d, want 1", len(args))
+				}
+
+				switch arg := args[0].(type) {
+				case *Integer:
+					return arg
+				case *Float:
+					// out of range floats, infinities included, have no Integer
+					if math.IsNaN(arg.Value) || arg.Value < math.MinInt64 || arg.Value >= -math.MinInt64 {
+						return newError("cannot convert %s to Integer", arg.Inspect())
+					}
+					return &Integer{Value: int64(arg.Value)}
+				case *String:
+					value, err := strconv.ParseInt(strings.TrimSpace(arg.Value), 0, 64)
+					if err != nil {
+						return newError("cannot convert %q to Integer", arg.Value)
+					}
+					return &Integer{Value: value}
+				case *Boolean:
+					if arg.Value {
+						return &Integer{Value: 1}
+					}
+					return &Integer{Value: 0}
+				default:
+					return newError("argument to `int` not supported, got %s", args[0].Type())
+				}
+			},
+		},
+	},
+	{
+		Name: "float",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got %d, want 1", len(args))
+				}
+
+				switch arg := args[0].(type) {
+				case *Integer:
+					return &Float{Value: float64(arg.Value)}
+				case *Float:
+					return arg
+				case *String:
+					value, err := strconv.ParseFloat(strings.TrimSpace(arg.Value), 64)
+					if err != nil {
+						return newError("cannot convert %q to Float", arg.Value)
+					}
+					return &Float{Value: value}
+				default:
+					return newError("argument to `float` not supported, got %s", args[0].Type())
+				}
+			},
+		},
+	},
+	{
+		Name: "str",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) != 1 {
+					return newError("wrong number of arguments. got %d, want 1", len(args))
+				}
+
+				if arg, ok := args[0].(*String); ok {
+					return arg
+				}
+				return &String{Value: args[0].Inspect()}
+			},
+		},
+	},
+	{
+		Name: "exit",
+		Builtin: &Builtin{
+			Fn: func(args ...Object) Object {
+				if len(args) > 1 {
+					return newError("wrong number of arguments. got %d, want 0 or 1", len(args))
+				}
+				if len(args) == 0 {
+					return &Exit{Code: 0}
+				}
+
+				code, ok := args[0].(*Integer)
+				if !ok {
+					return newError("argument to `exit` not supported, got %s", args[0].Type())
+				}
+				if code.Value < 0 || code.Value > 255 {
+					return newError("exit status %d out of range 0 to 255", code.Value)
+				}
+				return &Exit{Code: int(code.Value)}
+			},
+		},
+	},
 }
 
 func newError(format string, a ...interface{}) *Error {
@@ -0,0 +1,17 @@
+package object
+
+import "fmt"
+
+// Cell boxes a variable captured by a closure so that the closure and the
+// scope that defined the variable share updates to it
+type Cell struct {
+	Value Object
+}
+
+func (c *Cell) Type() ObjectType {
+	return CellObj
+}
+
+func (c *Cell) Inspect() string {
+	return fmt.Sprintf("Cell[%p]", c)
+}
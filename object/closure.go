@@ -4,7 +4,7 @@ import "fmt"
 
 type Closure struct {
 	Fn   *CompFn
-	Free []Object // free variables bound to the scope
+	Free []*Cell // free variables bound to the scope, shared with it
 }
 
 func (c *Closure) Type() ObjectType {
@@ -9,12 +9,24 @@ type CompFn struct {
 	Instructions  code.Instructions // set of instructions to call when fn is called
 	LocalVarCount int               // count of variables bound inside the fn
 	ParamCount    int               // count of params expected in the fn
+	SourceMap     code.SourceMap    // instruction offset -> source position
+	Handlers      code.HandlerTable // where errors raised by the fn are caught
+	Name          string            // name the fn was bound to, empty if anonymous
+	Signature     Signature         // params for binding defaults, rest and named args
 }
 
 func (cf *CompFn) Type() ObjectType {
 	return CompFnObj
 }
 
+// DisplayName returns the fn name for use in stack traces
+func (cf *CompFn) DisplayName() string {
+	if cf.Name == "" {
+		return "<anonymous>"
+	}
+	return cf.Name
+}
+
 func (cf *CompFn) Inspect() string {
 	return fmt.Sprintf("CompiledFn[%p]", cf)
 }
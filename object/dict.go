@@ -3,6 +3,8 @@ package object
 import (
 	"bytes"
 	"hash/fnv"
+	"math"
+	"sort"
 	"strings"
 )
 
@@ -34,6 +36,39 @@ func (d *Dict) Inspect() string {
 	return out.String()
 }
 
+// SortedKeys returns the keys ordered by type, then by value, so that
+// iterating a dict is deterministic
+func (d *Dict) SortedKeys() []Object {
+	keys := make([]Object, 0, len(d.Pairs))
+	for _, pair := range d.Pairs {
+		keys = append(keys, pair.Key)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keyLess(keys[i], keys[j])
+	})
+	return keys
+}
+
+func keyLess(a Object, b Object) bool {
+	if a.Type() != b.Type() {
+		return a.Type() < b.Type()
+	}
+
+	switch a := a.(type) {
+	case *Integer:
+		return a.Value < b.(*Integer).Value
+	case *Float:
+		return a.Value < b.(*Float).Value
+	case *String:
+		return a.Value < b.(*String).Value
+	case *Boolean:
+		return !a.Value && b.(*Boolean).Value
+	default:
+		return a.Inspect() < b.Inspect()
+	}
+}
+
 // DictPair stuff
 type DictPair struct {
 	Key   Object
@@ -62,6 +97,10 @@ func (i *Integer) DictKey() DictKey {
 	return DictKey{Type: i.Type(), Value: uint64(i.Value)}
 }
 
+func (f *Float) DictKey() DictKey {
+	return DictKey{Type: f.Type(), Value: math.Float64bits(f.Value)}
+}
+
 func (s *String) DictKey() DictKey {
 	h := fnv.New64a()
 	h.Write([]byte(s.Value))
@@ -1,8 +1,11 @@
 package object
 
+import "crabscript.rs/module"
+
 type Environment struct {
-	store map[string]Object
-	outer *Environment
+	store  map[string]Object
+	outer  *Environment
+	loader *module.Loader // loads the modules imported by the script, shared by its modules
 }
 
 func NewEnvironment() *Environment {
@@ -30,3 +33,33 @@ func (e *Environment) Set(name string, value Object) Object {
 	e.store[name] = value
 	return value
 }
+
+// Assign rebinds name in the innermost environment that defines it,
+// returning false when name is not bound
+func (e *Environment) Assign(name string, value Object) bool {
+	if _, ok := e.store[name]; ok {
+		e.store[name] = value
+		return true
+	}
+
+	if e.outer != nil {
+		return e.outer.Assign(name, value)
+	}
+	return false
+}
+
+// Loader returns the loader of the script the environment belongs to,
+// creating one without a search path if none was set
+func (e *Environment) Loader() *module.Loader {
+	if e.outer != nil {
+		return e.outer.Loader()
+	}
+	if e.loader == nil {
+		e.loader = module.NewLoader()
+	}
+	return e.loader
+}
+
+func (e *Environment) SetLoader(l *module.Loader) {
+	e.loader = l
+}
@@ -0,0 +1,35 @@
+package object
+
+// Equal reports whether a and b hold the same value, for matching them
+// against literal patterns. Numbers are equal across ints and floats, and
+// values of other types are only equal to themselves.
+func Equal(a, b Object) bool {
+	switch a := a.(type) {
+	case *Integer:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == b.Value
+		case *Float:
+			return float64(a.Value) == b.Value
+		}
+	case *Float:
+		switch b := b.(type) {
+		case *Integer:
+			return a.Value == float64(b.Value)
+		case *Float:
+			return a.Value == b.Value
+		}
+	case *String:
+		if b, ok := b.(*String); ok {
+			return a.Value == b.Value
+		}
+	case *Boolean:
+		if b, ok := b.(*Boolean); ok {
+			return a.Value == b.Value
+		}
+	case *Null:
+		_, ok := b.(*Null)
+		return ok
+	}
+	return a == b
+}
@@ -1,9 +1,19 @@
 package object
 
-import "fmt"
+import (
+	"fmt"
+	"strings"
+
+	"crabscript.rs/token"
+)
 
 type Error struct {
 	Message string
+	Pos     token.Position // where the error was raised, if known
+
+	Kind  string           // kind given to throw, empty for errors raised by the engine
+	Value Object           // value given to throw, nil for errors raised by the engine
+	Trace []token.Position // calls the error unwound through, innermost first
 }
 
 func (e *Error) Type() ObjectType {
@@ -11,5 +21,89 @@ func (e *Error) Type() ObjectType {
 }
 
 func (e *Error) Inspect() string {
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("ERROR: %s: %q", e.Pos, e.Message)
+	}
 	return fmt.Sprintf("ERROR: %q", e.Message)
 }
+
+// Error lets the vm raise errors returned by builtins and throw
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Thrown returns the error raised by `throw val`. A dict gives the message
+// and kind of the error by its "message" and "kind" keys, any other value is
+// the message itself.
+func Thrown(val Object) *Error {
+	err := &Error{Message: val.Inspect(), Kind: "Error", Value: val}
+
+	if dict, ok := val.(*Dict); ok {
+		if msg, ok := dictString(dict, "message"); ok {
+			err.Message = msg
+		} else {
+			err.Message = inspectSorted(dict)
+		}
+		if kind, ok := dictString(dict, "kind"); ok {
+			err.Kind = kind
+		}
+	}
+	return err
+}
+
+// displays dict as Inspect does with its pairs in key order, so that the
+// message of a thrown dict reads the same every time
+func inspectSorted(dict *Dict) string {
+	elem := make([]string, 0, len(dict.Pairs))
+	for _, key := range dict.SortedKeys() {
+		pair := dict.Pairs[key.(Hashable).DictKey()]
+		elem = append(elem, key.Inspect()+":"+pair.Value.Inspect())
+	}
+	return "[" + strings.Join(elem, ", ") + "]"
+}
+
+// Dict returns the value a catch binds the error to, a dict of its
+// "message", "kind" and "trace", the source positions of the calls from
+// where it was raised to where it was caught. A thrown dict keeps its own
+// keys, so rethrowing a caught error keeps its trace.
+func (e *Error) Dict(trace []token.Position) *Dict {
+	dict := &Dict{Pairs: map[DictKey]DictPair{}}
+	if thrown, ok := e.Value.(*Dict); ok {
+		for k, pair := range thrown.Pairs {
+			dict.Pairs[k] = pair
+		}
+	}
+
+	kind := e.Kind
+	if kind == "" {
+		kind = "RuntimeError"
+	}
+	positions := make([]Object, len(trace))
+	for i, pos := range trace {
+		positions[i] = &String{Value: pos.String()}
+	}
+
+	setMissing(dict, "message", &String{Value: e.Message})
+	setMissing(dict, "kind", &String{Value: kind})
+	setMissing(dict, "trace", &Array{Elements: positions})
+	return dict
+}
+
+func dictString(dict *Dict, key string) (string, bool) {
+	pair, ok := dict.Pairs[(&String{Value: key}).DictKey()]
+	if !ok {
+		return "", false
+	}
+	str, ok := pair.Value.(*String)
+	if !ok {
+		return "", false
+	}
+	return str.Value, true
+}
+
+func setMissing(dict *Dict, key string, val Object) {
+	k := &String{Value: key}
+	if _, ok := dict.Pairs[k.DictKey()]; !ok {
+		dict.Pairs[k.DictKey()] = DictPair{Key: k, Value: val}
+	}
+}
@@ -0,0 +1,23 @@
+package object
+
+import "fmt"
+
+// Exit is returned by the exit builtin to end the script with a status.
+// Like an Error it unwinds every call, but no catch block can stop it, and
+// whatever runs the script exits with Code once it is handed back.
+type Exit struct {
+	Code int
+}
+
+func (e *Exit) Type() ObjectType {
+	return ExitObj
+}
+
+func (e *Exit) Inspect() string {
+	return fmt.Sprintf("exit(%d)", e.Code)
+}
+
+// Error lets the vm return an exit from Run like the errors it raises
+func (e *Exit) Error() string {
+	return fmt.Sprintf("exit status %d", e.Code)
+}
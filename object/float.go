@@ -0,0 +1,24 @@
+package object
+
+import (
+	"strconv"
+	"strings"
+)
+
+type Float struct {
+	Value float64
+}
+
+// Inspect always shows a fraction or exponent so floats can be told apart
+// from integers, eg. 2.0 rather than 2
+func (f *Float) Inspect() string {
+	str := strconv.FormatFloat(f.Value, 'g', -1, 64)
+	if !strings.ContainsAny(str, ".eIN") {
+		str += ".0"
+	}
+	return str
+}
+
+func (f *Float) Type() ObjectType {
+	return FloatObj
+}
@@ -8,10 +8,24 @@ import (
 
 type Function struct {
 	Parameters []*ast.Identifier
+	Defaults   []ast.Expression // default of each parameter, nil where there is none
+	Rest       *ast.Identifier  // collects extra arguments into an array, if any
 	Body       *ast.BlockStatement
 	Env        *Environment
 }
 
+// Signature returns the params for binding the arguments of a call
+func (f *Function) Signature() Signature {
+	sig := Signature{Variadic: f.Rest != nil}
+	for i, p := range f.Parameters {
+		sig.Params = append(sig.Params, p.Value)
+		if sig.Required == i && (i >= len(f.Defaults) || f.Defaults[i] == nil) {
+			sig.Required++
+		}
+	}
+	return sig
+}
+
 func (f *Function) Type() ObjectType {
 	return FunctionObj
 }
@@ -21,8 +35,15 @@ func (f *Function) Inspect() string {
 
 	params := []string{}
 
-	for _, p := range f.Parameters {
-		params = append(params, p.String())
+	for i, p := range f.Parameters {
+		if i < len(f.Defaults) && f.Defaults[i] != nil {
+			params = append(params, p.String()+" = "+f.Defaults[i].String())
+		} else {
+			params = append(params, p.String())
+		}
+	}
+	if f.Rest != nil {
+		params = append(params, "..."+f.Rest.String())
 	}
 
 	out.WriteString("fn")
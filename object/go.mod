@@ -3,6 +3,9 @@ module crabscript.rs/object
 replace (
 	crabscript.rs/ast => ../ast
 	crabscript.rs/code => ../code
+	crabscript.rs/lexer => ../lexer
+	crabscript.rs/module => ../module
+	crabscript.rs/parser => ../parser
 	crabscript.rs/token => ../token
 )
 
@@ -11,6 +14,11 @@ go 1.21.0
 require (
 	crabscript.rs/ast v0.0.0-00010101000000-000000000000
 	crabscript.rs/code v0.0.0-00010101000000-000000000000
+	crabscript.rs/module v0.0.0-00010101000000-000000000000
+	crabscript.rs/token v0.0.0-00010101000000-000000000000
 )
 
-require crabscript.rs/token v0.0.0-00010101000000-000000000000 // indirect
+require (
+	crabscript.rs/lexer v0.0.0-00010101000000-000000000000 // indirect
+	crabscript.rs/parser v0.0.0-00010101000000-000000000000 // indirect
+)
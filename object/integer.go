@@ -1,6 +1,9 @@
 package object
 
-import "fmt"
+import (
+	"fmt"
+	"math"
+)
 
 type Integer struct {
 	Value int64
@@ -13,3 +16,22 @@ func (i *Integer) Inspect() string {
 func (i *Integer) Type() ObjectType {
 	return IntegerObj
 }
+
+// IntPow raises base to a non-negative exp by squaring, wrapping on overflow
+// like the other integer ops. Negative exponents give a fractional result,
+// which is returned as a float.
+func IntPow(base int64, exp int64) Object {
+	if exp < 0 {
+		return &Float{Value: math.Pow(float64(base), float64(exp))}
+	}
+
+	result := int64(1)
+	for exp > 0 {
+		if exp&1 == 1 {
+			result *= base
+		}
+		base *= base
+		exp >>= 1
+	}
+	return &Integer{Value: result}
+}
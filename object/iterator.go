@@ -0,0 +1,52 @@
+package object
+
+import "fmt"
+
+// Iterator walks the elements of an iterable for a for-in loop
+type Iterator struct {
+	Elements []Object
+	Pos      int // index of the next element
+}
+
+// NewIterator returns an iterator over a snapshot of obj, arrays yield their
+// elements, strings their characters and dicts their keys in sorted order
+func NewIterator(obj Object) (*Iterator, bool) {
+	switch obj := obj.(type) {
+	case *Array:
+		elements := make([]Object, len(obj.Elements))
+		copy(elements, obj.Elements)
+		return &Iterator{Elements: elements}, true
+
+	case *String:
+		elements := []Object{}
+		for _, r := range obj.Value {
+			elements = append(elements, &String{Value: string(r)})
+		}
+		return &Iterator{Elements: elements}, true
+
+	case *Dict:
+		return &Iterator{Elements: obj.SortedKeys()}, true
+
+	default:
+		return nil, false
+	}
+}
+
+// Next returns the next element, or false once all have been returned
+func (it *Iterator) Next() (Object, bool) {
+	if it.Pos >= len(it.Elements) {
+		return nil, false
+	}
+
+	elem := it.Elements[it.Pos]
+	it.Pos++
+	return elem, true
+}
+
+func (it *Iterator) Type() ObjectType {
+	return IteratorObj
+}
+
+func (it *Iterator) Inspect() string {
+	return fmt.Sprintf("Iterator[%p]", it)
+}
@@ -0,0 +1,29 @@
+package object
+
+import "crabscript.rs/token"
+
+// Break and Continue signal the evaluator to leave or restart the
+// innermost loop, like ReturnValue they never escape to scripts
+type Break struct {
+	Pos token.Position // position of the break statement
+}
+
+func (b *Break) Type() ObjectType {
+	return BreakObj
+}
+
+func (b *Break) Inspect() string {
+	return "break"
+}
+
+type Continue struct {
+	Pos token.Position // position of the continue statement
+}
+
+func (c *Continue) Type() ObjectType {
+	return ContinueObj
+}
+
+func (c *Continue) Inspect() string {
+	return "continue"
+}
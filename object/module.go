@@ -0,0 +1,25 @@
+package object
+
+// Module is a script imported by another, run in an environment of its own
+// whose exported names importers can read
+type Module struct {
+	Name    string // path the module was imported by
+	Env     *Environment
+	Exports map[string]bool
+}
+
+func (m *Module) Type() ObjectType {
+	return ModuleObj
+}
+
+func (m *Module) Inspect() string {
+	return "module " + m.Name
+}
+
+// Get returns the current value of the export name
+func (m *Module) Get(name string) (Object, bool) {
+	if !m.Exports[name] {
+		return nil, false
+	}
+	return m.Env.Get(name)
+}
@@ -9,6 +9,7 @@ type Object interface {
 
 const (
 	IntegerObj  = "Integer"
+	FloatObj    = "Float"
 	BooleanObj  = "Boolean"
 	NullObj     = "Null"
 	ReturnObj   = "Return"
@@ -20,4 +21,10 @@ const (
 	DictObj     = "Dict"
 	CompFnObj   = "CompFnObj"
 	ClosureObj  = "ClosureObj"
+	CellObj     = "Cell"
+	IteratorObj = "Iterator"
+	BreakObj    = "Break"
+	ContinueObj = "Continue"
+	ModuleObj   = "Module"
+	ExitObj     = "Exit"
 )
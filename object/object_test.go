@@ -1,6 +1,11 @@
 package object
 
-import "testing"
+import (
+	"strings"
+	"testing"
+
+	"crabscript.rs/token"
+)
 
 func TestStringHashKey(t *testing.T) {
 
@@ -21,3 +26,226 @@ func TestStringHashKey(t *testing.T) {
 		t.Errorf("strings with different content have same hash keys")
 	}
 }
+
+func TestSignatureBind(t *testing.T) {
+	one, two := &Integer{Value: 1}, &Integer{Value: 2}
+	sig := Signature{Params: []string{"a", "b"}, Required: 1, Variadic: true}
+
+	values, err := sig.Bind([]Object{one}, []string{"b"}, []Object{two})
+	if err != nil {
+		t.Fatalf("bind failed: %s", err)
+	}
+	if len(values) != 3 || values[0] != one || values[1] != two {
+		t.Fatalf("wrong values: %v", values)
+	}
+	if rest, ok := values[2].(*Array); !ok || len(rest.Elements) != 0 {
+		t.Errorf("rest is not an empty array: %v", values[2])
+	}
+
+	values, err = sig.Bind([]Object{one}, nil, nil)
+	if err != nil {
+		t.Fatalf("bind failed: %s", err)
+	}
+	if values[1] != nil {
+		t.Errorf("param left out was bound to %v", values[1])
+	}
+
+	values, err = sig.Bind([]Object{one, two, one, two}, nil, nil)
+	if err != nil {
+		t.Fatalf("bind failed: %s", err)
+	}
+	if rest := values[2].(*Array); len(rest.Elements) != 2 || rest.Elements[0] != one {
+		t.Errorf("wrong rest: %v", rest.Elements)
+	}
+}
+
+func TestEqual(t *testing.T) {
+	arr := &Array{}
+	tests := []struct {
+		a, b     Object
+		expected bool
+	}{
+		{&Integer{Value: 1}, &Integer{Value: 1}, true},
+		{&Integer{Value: 1}, &Float{Value: 1}, true},
+		{&Float{Value: 1.5}, &Integer{Value: 1}, false},
+		{&String{Value: "a"}, &String{Value: "a"}, true},
+		{&String{Value: "1"}, &Integer{Value: 1}, false},
+		{&Boolean{Value: true}, &Boolean{Value: true}, true},
+		{&Null{}, &Null{}, true},
+		{&Null{}, &Boolean{Value: false}, false},
+		{arr, arr, true},
+		{arr, &Array{}, false},
+	}
+
+	for _, tt := range tests {
+		if got := Equal(tt.a, tt.b); got != tt.expected {
+			t.Errorf("Equal(%s, %s) = %t, want %t", tt.a.Inspect(), tt.b.Inspect(), got, tt.expected)
+		}
+	}
+}
+
+func TestErrorDict(t *testing.T) {
+	trace := []token.Position{{Line: 2, Column: 3}, {Line: 5, Column: 1}}
+
+	dict := (&Error{Message: "bad"}).Dict(trace)
+	if got := dict.Inspect(); !strings.Contains(got, "kind:RuntimeError") || !strings.Contains(got, "message:bad") || !strings.Contains(got, "trace:[2:3, 5:1]") {
+		t.Errorf("wrong dict for engine error: %s", got)
+	}
+
+	thrown := Thrown(&String{Value: "oops"})
+	if thrown.Message != "oops" || thrown.Kind != "Error" {
+		t.Errorf("wrong error thrown for string: %+v", thrown)
+	}
+
+	caught := &Dict{Pairs: map[DictKey]DictPair{}}
+	setMissing(caught, "kind", &String{Value: "Custom"})
+	setMissing(caught, "trace", &Array{})
+	thrown = Thrown(caught)
+	if thrown.Message != "[kind:Custom, trace:[]]" || thrown.Value != caught || thrown.Kind != "Custom" {
+		t.Errorf("wrong error thrown for dict: %+v", thrown)
+	}
+	// a rethrown error keeps its trace
+	if got := thrown.Dict(trace).Inspect(); !strings.Contains(got, "trace:[]") || !strings.Contains(got, "kind:Custom") {
+		t.Errorf("wrong dict for rethrown error: %s", got)
+	}
+}
+
+func TestFloatHashKey(t *testing.T) {
+	half1 := &Float{Value: 0.5}
+	half2 := &Float{Value: 0.5}
+	third := &Float{Value: 1.0 / 3}
+
+	if half1.DictKey() != half2.DictKey() {
+		t.Errorf("floats with same value have different hash keys")
+	}
+
+	if half1.DictKey() == third.DictKey() {
+		t.Errorf("floats with different values have same hash keys")
+	}
+
+	if (&Float{Value: 1}).DictKey() == (&Integer{Value: 1}).DictKey() {
+		t.Errorf("float and integer share a hash key")
+	}
+}
+
+func TestFloatInspect(t *testing.T) {
+	tests := []struct {
+		value    float64
+		expected string
+	}{
+		{1.5, "1.5"},
+		{2, "2.0"},
+		{-0.25, "-0.25"},
+		{1e21, "1e+21"},
+	}
+
+	for _, tt := range tests {
+		if got := (&Float{Value: tt.value}).Inspect(); got != tt.expected {
+			t.Errorf("wrong Inspect for %v, got %q want %q", tt.value, got, tt.expected)
+		}
+	}
+}
+
+func TestEnvironmentAssign(t *testing.T) {
+	outer := NewEnvironment()
+	outer.Set("a", &Integer{Value: 1})
+	inner := NewEnclosedEnvironment(outer)
+	inner.Set("b", &Integer{Value: 2})
+
+	if !inner.Assign("a", &Integer{Value: 3}) {
+		t.Fatalf("expected outer binding to be assignable")
+	}
+	if val, _ := outer.Get("a"); val.(*Integer).Value != 3 {
+		t.Errorf("outer binding not updated, got %s", val.Inspect())
+	}
+	if _, ok := inner.store["a"]; ok {
+		t.Errorf("assignment created a new binding in the inner environment")
+	}
+
+	if outer.Assign("b", &Integer{Value: 4}) {
+		t.Errorf("inner binding assignable from the outer environment")
+	}
+	if inner.Assign("c", &Integer{Value: 5}) {
+		t.Errorf("unbound name assignable")
+	}
+}
+
+func TestDictSortedKeys(t *testing.T) {
+	keys := []Object{
+		&String{Value: "b"},
+		&Integer{Value: 10},
+		&String{Value: "a"},
+		&Integer{Value: -1},
+		&Boolean{Value: true},
+		&Boolean{Value: false},
+	}
+
+	dict := &Dict{Pairs: map[DictKey]DictPair{}}
+	for _, k := range keys {
+		dict.Pairs[k.(Hashable).DictKey()] = DictPair{Key: k, Value: k}
+	}
+
+	expected := []string{"false", "true", "-1", "10", "a", "b"}
+
+	sorted := dict.SortedKeys()
+	if len(sorted) != len(expected) {
+		t.Fatalf("wrong number of keys. got=%d, want=%d", len(sorted), len(expected))
+	}
+	for i, want := range expected {
+		if sorted[i].Inspect() != want {
+			t.Errorf("key %d wrong. got=%s, want=%s", i, sorted[i].Inspect(), want)
+		}
+	}
+}
+
+func TestIterator(t *testing.T) {
+	arr := &Array{Elements: []Object{&Integer{Value: 1}, &Integer{Value: 2}}}
+
+	iter, ok := NewIterator(arr)
+	if !ok {
+		t.Fatalf("array not iterable")
+	}
+
+	// iterating a snapshot, appends don't affect the loop
+	arr.Elements = append(arr.Elements, &Integer{Value: 3})
+
+	for _, want := range []int64{1, 2} {
+		elem, ok := iter.Next()
+		if !ok {
+			t.Fatalf("iterator exhausted early")
+		}
+		if elem.(*Integer).Value != want {
+			t.Errorf("wrong element. got=%s, want=%d", elem.Inspect(), want)
+		}
+	}
+	if _, ok := iter.Next(); ok {
+		t.Errorf("iterator not exhausted")
+	}
+
+	if _, ok := NewIterator(&Integer{Value: 1}); ok {
+		t.Errorf("integer is iterable")
+	}
+}
+
+func TestModuleGet(t *testing.T) {
+	env := NewEnvironment()
+	env.Set("shown", &Integer{Value: 1})
+	env.Set("hidden", &Integer{Value: 2})
+	mod := &Module{Name: "lib.crab", Env: env, Exports: map[string]bool{"shown": true, "later": true}}
+
+	if val, ok := mod.Get("shown"); !ok || val.Inspect() != "1" {
+		t.Errorf("wrong export shown: %v", val)
+	}
+	if _, ok := mod.Get("hidden"); ok {
+		t.Errorf("unexported name read from module")
+	}
+	if _, ok := mod.Get("later"); ok {
+		t.Errorf("export never bound read from module")
+	}
+
+	// exports are read live from the module's environment
+	env.Assign("shown", &Integer{Value: 3})
+	if val, _ := mod.Get("shown"); val.Inspect() != "3" {
+		t.Errorf("stale export shown: %v", val)
+	}
+}
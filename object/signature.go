@@ -0,0 +1,76 @@
+package object
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// Signature lists the params of a fn, for binding the arguments of a call
+type Signature struct {
+	Params   []string // names of the params, not including the rest param
+	Required int      // count of leading params without a default
+	Variadic bool     // whether extra arguments are collected into a rest param
+}
+
+// Bind matches the positional args and the named args of a call to the
+// params. It returns the value of each param, nil where it was left out and
+// takes its default, followed by an array of the extra args when variadic.
+func (s Signature) Bind(args []Object, names []string, named []Object) ([]Object, error) {
+	n := len(s.Params)
+	if len(args) > n && !s.Variadic {
+		return nil, s.countError(len(args) + len(named))
+	}
+
+	values := make([]Object, n, n+1)
+	copy(values, args)
+
+	for i, name := range names {
+		idx := s.index(name)
+		if idx < 0 {
+			return nil, fmt.Errorf("unknown argument %s", name)
+		}
+		if values[idx] != nil {
+			return nil, fmt.Errorf("argument %s given twice", name)
+		}
+		values[idx] = named[i]
+	}
+
+	for i := 0; i < s.Required; i++ {
+		if values[i] == nil {
+			if len(names) == 0 {
+				return nil, s.countError(len(args))
+			}
+			return nil, fmt.Errorf("missing argument %s", s.Params[i])
+		}
+	}
+
+	if s.Variadic {
+		rest := []Object{}
+		if len(args) > n {
+			rest = append(rest, args[n:]...)
+		}
+		values = append(values, &Array{Elements: rest})
+	}
+
+	return values, nil
+}
+
+func (s Signature) index(name string) int {
+	for i, param := range s.Params {
+		if param == name {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s Signature) countError(got int) error {
+	want := strconv.Itoa(s.Required)
+	switch {
+	case s.Variadic:
+		want = "at least " + want
+	case s.Required < len(s.Params):
+		want = fmt.Sprintf("%d to %d", s.Required, len(s.Params))
+	}
+	return fmt.Errorf("wrong number of arguments: want %s got %d", want, got)
+}
@@ -0,0 +1,107 @@
+package object
+
+import "fmt"
+
+// Slice returns the elements of an array, or the characters of a string,
+// from start up to but excluding end, taking every step-th one. Like
+// Python, negative bounds count back from the end, out of range bounds are
+// clamped, and a negative step walks backwards. Missing bounds are nil or
+// Null.
+func Slice(obj Object, start, end, step Object) (Object, error) {
+	var length int64
+	switch obj := obj.(type) {
+	case *Array:
+		length = int64(len(obj.Elements))
+	case *String:
+		length = int64(len([]rune(obj.Value)))
+	default:
+		return nil, fmt.Errorf("slice operator not supported: %s", obj.Type())
+	}
+
+	stepVal, ok, err := sliceBound(step)
+	if err != nil {
+		return nil, err
+	}
+	if !ok {
+		stepVal = 1
+	}
+	if stepVal == 0 {
+		return nil, fmt.Errorf("slice step cannot be zero")
+	}
+
+	startVal, endVal := int64(0), length
+	if stepVal < 0 {
+		startVal, endVal = length-1, -1
+	}
+	if idx, ok, err := sliceBound(start); err != nil {
+		return nil, err
+	} else if ok {
+		startVal = clampSliceIndex(idx, length, stepVal)
+	}
+	if idx, ok, err := sliceBound(end); err != nil {
+		return nil, err
+	} else if ok {
+		endVal = clampSliceIndex(idx, length, stepVal)
+	}
+
+	// counting the elements up front can't overflow, unlike stepping an
+	// index past end
+	count := int64(0)
+	switch {
+	case stepVal > 0 && startVal < endVal:
+		count = (endVal-startVal-1)/stepVal + 1
+	case stepVal < 0 && startVal > endVal:
+		count = (startVal-endVal-1)/-stepVal + 1
+	}
+
+	switch obj := obj.(type) {
+	case *Array:
+		elements := make([]Object, count)
+		for i := range elements {
+			elements[i] = obj.Elements[startVal+int64(i)*stepVal]
+		}
+		return &Array{Elements: elements}, nil
+
+	default:
+		runes := []rune(obj.(*String).Value)
+		sliced := make([]rune, count)
+		for i := range sliced {
+			sliced[i] = runes[startVal+int64(i)*stepVal]
+		}
+		return &String{Value: string(sliced)}, nil
+	}
+}
+
+// value of an integer slice bound, or false when the bound is missing
+func sliceBound(bound Object) (int64, bool, error) {
+	switch bound := bound.(type) {
+	case nil, *Null:
+		return 0, false, nil
+	case *Integer:
+		return bound.Value, true, nil
+	default:
+		return 0, false, fmt.Errorf("slice indices must be Integer, got %s", bound.Type())
+	}
+}
+
+// moves a bound that counts back from the end, or lies outside the
+// sequence, to the first or last place a slice walking in the direction of
+// step can stop at
+func clampSliceIndex(idx, length, step int64) int64 {
+	if idx < 0 {
+		idx += length
+		if idx < 0 {
+			if step < 0 {
+				return -1
+			}
+			return 0
+		}
+	}
+	if idx >= length {
+		if step < 0 {
+			return length - 1
+		}
+		return length
+	}
+	return idx
+}
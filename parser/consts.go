@@ -5,25 +5,61 @@ import "crabscript.rs/token"
 const (
 	_ int = iota
 	Lowest
+	Assign
+	Pipe
+	Or
+	And
 	Eq
 	Ltgt
+	BitOr
+	BitXor
+	BitAnd
+	Shift
 	Sum
 	Prod
 	Prefix
+	Power // binds tighter than prefix ops, `-2 ** 2` is `-(2 ** 2)`
 	Call
 	Index
 )
 
 // Precedence of binary operations
 var precedences = map[token.TokenType]int{
+	token.Assign:         Assign,
+	token.PlusAssign:     Assign,
+	token.MinusAssign:    Assign,
+	token.AsteriskAssign: Assign,
+	token.SlashAssign:    Assign,
+	token.PercentAssign:  Assign,
+	token.PowerAssign:    Assign,
+	token.BitAndAssign:   Assign,
+	token.BitOrAssign:    Assign,
+	token.BitXorAssign:   Assign,
+	token.ShlAssign:      Assign,
+	token.ShrAssign:      Assign,
+
+	token.Pipe: Pipe,
+	token.Or:   Or,
+	token.And:  And,
+
 	token.Eq:       Eq,
 	token.NEq:      Eq,
 	token.Lt:       Ltgt,
 	token.Gt:       Ltgt,
+	token.Le:       Ltgt,
+	token.Ge:       Ltgt,
+	token.BitOr:    BitOr,
+	token.BitXor:   BitXor,
+	token.BitAnd:   BitAnd,
+	token.Shl:      Shift,
+	token.Shr:      Shift,
 	token.Plus:     Sum,
 	token.Minus:    Sum,
 	token.Slash:    Prod,
 	token.Asterisk: Prod,
+	token.Percent:  Prod,
+	token.Power:    Power,
 	token.LParen:   Call,
 	token.LBracket: Index,
+	token.Dot:      Index,
 }
@@ -3,18 +3,171 @@ package parser
 import (
 	"crabscript.rs/token"
 	"fmt"
+	"strings"
+	"unicode/utf8"
 )
 
+// Severity of a Diagnostic
+type Severity int
+
+const (
+	SeverityError Severity = iota
+	SeverityWarning
+)
+
+func (s Severity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	}
+	return fmt.Sprintf("Severity(%d)", int(s))
+}
+
+// Diagnostic is a problem found while lexing or parsing the source
+type Diagnostic struct {
+	Pos      token.Position
+	Severity Severity
+	Msg      string
+
+	Expected token.TokenType // token the parser wanted, if any
+	Found    token.Token     // token the parser got instead, if any
+
+	Snippet string // source line the diagnostic points into
+}
+
+// String formats the diagnostic as file:line:col: msg
+func (d Diagnostic) String() string {
+	return fmt.Sprintf("%s: %s", d.Pos, d.Msg)
+}
+
+// Format renders the diagnostic with its source line and a caret under the
+// column it points at:
+//
+//	script.crab:1:5: error: expected next token Ident, got =
+//	  let = 5;
+//	      ^
+func (d Diagnostic) Format() string {
+	var out strings.Builder
+
+	fmt.Fprintf(&out, "%s: %s: %s\n", d.Pos, d.Severity, d.Msg)
+	if d.Snippet == "" {
+		return out.String()
+	}
+
+	fmt.Fprintf(&out, "  %s\n  ", d.Snippet)
+
+	// keep tabs so the caret lines up with the snippet above it
+	line := d.Snippet
+	for col := 1; col < d.Pos.Column && len(line) > 0; col++ {
+		ch, size := utf8.DecodeRuneInString(line)
+		if ch == '\t' {
+			out.WriteRune('\t')
+		} else {
+			out.WriteRune(' ')
+		}
+		line = line[size:]
+	}
+	out.WriteString("^\n")
+
+	return out.String()
+}
+
 func (p *Parser) peekError(t token.TokenType) {
-	msg := fmt.Sprintf("expected next token %v, got %v", t, p.peekToken.Type)
-	p.errors = append(p.errors, msg)
+	p.report(Diagnostic{
+		Pos:      p.peekToken.Pos,
+		Msg:      fmt.Sprintf("expected next token %v, got %v", t, p.peekToken.Type),
+		Expected: t,
+		Found:    p.peekToken,
+	})
 }
 
+// Errors returns the lexing errors followed by the parsing errors
 func (p *Parser) Errors() []string {
-	return p.errors
+	errors := []string{}
+	for _, d := range p.Diagnostics() {
+		errors = append(errors, d.String())
+	}
+	return errors
+}
+
+// Diagnostics returns the lexing diagnostics followed by the parsing ones
+func (p *Parser) Diagnostics() []Diagnostic {
+	var diagnostics []Diagnostic
+	for _, err := range p.l.ErrorList() {
+		diagnostics = append(diagnostics, Diagnostic{
+			Pos:      err.Pos,
+			Severity: SeverityError,
+			Msg:      err.Msg,
+			Snippet:  p.l.SourceLine(err.Pos),
+		})
+	}
+	return append(diagnostics, p.diagnostics...)
 }
 
 func (p *Parser) noPrefixParseFnError(t token.TokenType) {
-	msg := fmt.Sprintf("no prefix parse fn available for %v", t)
-	p.errors = append(p.errors, msg)
+	p.report(Diagnostic{
+		Pos:   p.curToken.Pos,
+		Msg:   fmt.Sprintf("no prefix parse fn available for %v", t),
+		Found: p.curToken,
+	})
+}
+
+// records an error prefixed with the file:line:col it occurred at
+func (p *Parser) errorAt(pos token.Position, msg string) {
+	p.report(Diagnostic{Pos: pos, Msg: msg})
+}
+
+// records an error, unless the parser is still recovering from an earlier
+// one in the same statement, which would most likely be a knock-on effect.
+// An error the lexer found in the statement up to d, such as an unterminated
+// string swallowing the rest of it, counts as such an earlier one.
+func (p *Parser) report(d Diagnostic) {
+	if p.recovering {
+		return
+	}
+	p.recovering = true
+
+	if errs := p.l.ErrorList(); p.lexErrors < len(errs) && errs[p.lexErrors].Pos.Offset <= d.Pos.Offset {
+		return
+	}
+
+	d.Severity = SeverityError
+	d.Snippet = p.l.SourceLine(d.Pos)
+	p.diagnostics = append(p.diagnostics, d)
+}
+
+// skips the rest of the statement from start that failed to parse in a
+// block nested depth braces deep. Only braces are counted, as statements
+// can't nest in parens or brackets without them. It stops on the `;` ending
+// the statement, on the `}` closing the block, or before a keyword starting
+// the next statement. Returns true when it stopped on such a keyword, which
+// the parser should resume at rather than skip over.
+func (p *Parser) synchronize(depth int, start token.Token) bool {
+	defer func() { p.recovering = false }()
+
+	for !p.curTokenIs(token.Eof) && p.depth >= depth {
+		if p.depth == depth {
+			// the statement was cut short by the one after it
+			if startsStatement(p.curToken.Type) && p.curToken.Pos != start.Pos {
+				return true
+			}
+			if p.curTokenIs(token.Semicolon) || startsStatement(p.peekToken.Type) {
+				return false
+			}
+		}
+		p.nextToken()
+	}
+	return false
+}
+
+// keywords that can only begin a statement
+func startsStatement(t token.TokenType) bool {
+	switch t {
+	case token.Let, token.Return, token.Throw, token.While, token.For, token.Break, token.Continue,
+		token.Import, token.Export:
+		return true
+	}
+	return false
 }
@@ -9,8 +9,12 @@ import (
 // Implementation of the Pratt (top-down) parser.
 // Ref: https://matklad.github.io/2020/04/13/simple-but-powerful-pratt-parsing.html
 type Parser struct {
-	l      *lexer.Lexer
-	errors []string
+	l           *lexer.Lexer
+	diagnostics []Diagnostic
+
+	depth      int  // braces opened up to and including curToken
+	recovering bool // skipping the rest of a statement after an error
+	lexErrors  int  // lexing errors already made into bad statements
 
 	curToken  token.Token
 	peekToken token.Token
@@ -21,7 +25,7 @@ type Parser struct {
 
 // Inits Parser instructions
 func New(l *lexer.Lexer) *Parser {
-	p := &Parser{l: l, errors: []string{}}
+	p := &Parser{l: l}
 
 	// read 2 toks so that peekToken is populated
 	p.nextToken()
@@ -30,14 +34,18 @@ func New(l *lexer.Lexer) *Parser {
 	p.prefixParseFns = make(map[token.TokenType]prefixParseFn)
 	p.registerPrefix(token.Ident, p.parseIdentifier)
 	p.registerPrefix(token.Int, p.parseIntegerLiteral)
+	p.registerPrefix(token.Float, p.parseFloatLiteral)
 	p.registerPrefix(token.Bang, p.parsePrefixExpression)
 	p.registerPrefix(token.Minus, p.parsePrefixExpression)
 	p.registerPrefix(token.True, p.parseBoolean)
 	p.registerPrefix(token.False, p.parseBoolean)
 	p.registerPrefix(token.LParen, p.parseGroupedExpression)
 	p.registerPrefix(token.If, p.parseIfExpression)
+	p.registerPrefix(token.Match, p.parseMatchExpression)
+	p.registerPrefix(token.Try, p.parseTryExpression)
 	p.registerPrefix(token.Function, p.parseFunctionLiteral)
 	p.registerPrefix(token.String, p.parseStringLiteral)
+	p.registerPrefix(token.StringPart, p.parseInterpolatedString)
 	p.registerPrefix(token.LBracket, p.parseArrayLiteral)
 	p.registerPrefix(token.LBrace, p.parseDictLiteral)
 
@@ -50,8 +58,33 @@ func New(l *lexer.Lexer) *Parser {
 	p.registerInfix(token.NEq, p.parseInfixExpression)
 	p.registerInfix(token.Lt, p.parseInfixExpression)
 	p.registerInfix(token.Gt, p.parseInfixExpression)
+	p.registerInfix(token.Le, p.parseInfixExpression)
+	p.registerInfix(token.Ge, p.parseInfixExpression)
+	p.registerInfix(token.Percent, p.parseInfixExpression)
+	p.registerInfix(token.Power, p.parseInfixExpression)
+	p.registerInfix(token.BitAnd, p.parseInfixExpression)
+	p.registerInfix(token.BitOr, p.parseInfixExpression)
+	p.registerInfix(token.BitXor, p.parseInfixExpression)
+	p.registerInfix(token.Shl, p.parseInfixExpression)
+	p.registerInfix(token.Shr, p.parseInfixExpression)
+	p.registerInfix(token.And, p.parseInfixExpression)
+	p.registerInfix(token.Or, p.parseInfixExpression)
+	p.registerInfix(token.Pipe, p.parsePipeExpression)
 	p.registerInfix(token.LParen, p.parseCallExpression)
 	p.registerInfix(token.LBracket, p.parseIndexExpression)
+	p.registerInfix(token.Dot, p.parseMemberExpression)
+	p.registerInfix(token.Assign, p.parseAssignExpression)
+	p.registerInfix(token.PlusAssign, p.parseAssignExpression)
+	p.registerInfix(token.MinusAssign, p.parseAssignExpression)
+	p.registerInfix(token.AsteriskAssign, p.parseAssignExpression)
+	p.registerInfix(token.SlashAssign, p.parseAssignExpression)
+	p.registerInfix(token.PercentAssign, p.parseAssignExpression)
+	p.registerInfix(token.PowerAssign, p.parseAssignExpression)
+	p.registerInfix(token.BitAndAssign, p.parseAssignExpression)
+	p.registerInfix(token.BitOrAssign, p.parseAssignExpression)
+	p.registerInfix(token.BitXorAssign, p.parseAssignExpression)
+	p.registerInfix(token.ShlAssign, p.parseAssignExpression)
+	p.registerInfix(token.ShrAssign, p.parseAssignExpression)
 
 	return p
 }
@@ -62,12 +95,27 @@ func (p *Parser) ParseProgram() *ast.Program {
 	program.Statements = []ast.Statement{}
 
 	for !p.curTokenIs(token.Eof) {
+		start := p.curToken
 		stmt := p.parseStatement()
-
 		if stmt != nil {
 			program.Statements = append(program.Statements, stmt)
 		}
+
+		// skip the rest of a statement that failed to parse and carry on
+		// with the next one, so a single mistake doesn't hide later errors
+		if p.recovering && p.synchronize(0, start) {
+			continue
+		}
 		p.nextToken()
 	}
+
+	// an error lexing past the last statement, such as an unterminated
+	// block comment, still makes the program bad
+	errs := p.l.ErrorList()
+	if p.lexErrors < len(errs) {
+		bad := token.Token{Type: token.Illegal, Pos: errs[p.lexErrors].Pos}
+		program.Statements = append(program.Statements, &ast.BadStatement{Token: bad})
+		p.lexErrors = len(errs)
+	}
 	return program
 }
@@ -3,6 +3,7 @@ package parser
 import (
 	"crabscript.rs/ast"
 	"crabscript.rs/lexer"
+	"crabscript.rs/token"
 	"fmt"
 	"testing"
 )
@@ -62,6 +63,61 @@ func testLetStatement(t *testing.T, s ast.Statement, name string) bool {
 	return true
 }
 
+func TestIntegerLiteralBases(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected int64
+	}{
+		{"0x1F", 31},
+		{"0o17", 15},
+		{"0b1010", 10},
+		{"1_000_000", 1000000},
+		{"0xFF_FF", 65535},
+		{"9223372036854775807", 9223372036854775807},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt := program.Statements[0].(*ast.ExpressionStatement)
+		literal, ok := stmt.Expression.(*ast.IntegerLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression not ast.IntegerLiteral, got %T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %v, got %v", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestIntegerLiteralErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"9223372036854775808", "1:1: integer literal 9223372036854775808 overflows int64"},
+		{"let a = 0xFFFFFFFFFFFFFFFFF", "1:9: integer literal 0xFFFFFFFFFFFFFFFFF overflows int64"},
+		{"1__000", "1:1: could not parse 1__000 as integer"},
+		{"0b102", "1:1: could not parse 0b102 as integer"},
+		{"0x", "1:1: could not parse 0x as integer"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want %q, got %q", tt.expected, errors[0])
+		}
+	}
+}
+
 func TestIdentifierExpression(t *testing.T) {
 	input := "foobar;"
 
@@ -331,6 +387,58 @@ func TestOperatorPrecedenceParsing(t *testing.T) {
 			"add(a * b[2], b[1], 2 * [1, 2][1])",
 			"add((a * (b[2])), (b[1]), (2 * ([1, 2][1])))",
 		},
+		{
+			"a || b && c",
+			"(a || (b && c))",
+		},
+		{
+			"a && b || c && d",
+			"((a && b) || (c && d))",
+		},
+		{
+			"a < b && b == c || !d",
+			"(((a < b) && (b == c)) || (!d))",
+		},
+		{
+			"x = a || b",
+			"(x = (a || b))",
+		},
+		{
+			"a <= b == b >= c",
+			"((a <= b) == (b >= c))",
+		},
+		{
+			"a + b % c * d",
+			"(a + ((b % c) * d))",
+		},
+		{
+			"a ** b ** c",
+			"(a ** (b ** c))",
+		},
+		{
+			"-a ** b * c",
+			"((-(a ** b)) * c)",
+		},
+		{
+			"a * b ** -c",
+			"(a * (b ** (-c)))",
+		},
+		{
+			"a | b ^ c & d",
+			"(a | (b ^ (c & d)))",
+		},
+		{
+			"a & b << c + d",
+			"(a & (b << (c + d)))",
+		},
+		{
+			"a >> b < c << d",
+			"((a >> b) < (c << d))",
+		},
+		{
+			"a & b == 0 && c | d != 0",
+			"(((a & b) == 0) && ((c | d) != 0))",
+		},
 	}
 	for _, tt := range tests {
 		l := lexer.New(tt.input)
@@ -637,6 +745,113 @@ func TestFunctionParameterParsing(t *testing.T) {
 	}
 }
 
+func TestFunctionParameterDefaultsAndRest(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x, y = 10) { x }", "fn(x, y = 10)x"},
+		{"fn(x = 1 + 2, y = x) { x }", "fn(x = (1 + 2), y = x)x"},
+		{"fn(first, ...rest) { rest }", "fn(first, ...rest)rest"},
+		{"fn(...all) { all }", "fn(...all)all"},
+		{"fn(a, b = 2,) { a }", "fn(a, b = 2)a"},
+		{"f(1, y: 2, z: a + b)", "f(1, y: 2, z: (a + b))"},
+		{"f(y: 2)", "f(y: 2)"},
+		{"f(a[1:2], {a: 1}[a])", "f((a[1:2]), ({a:1}[a]))"},
+		{"x |> f(y: 2)", "(x |> f(y: 2))"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("fn(x, y = 10, ...rest) { x }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	function := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.FunctionLiteral)
+	if len(function.Parameters) != 2 {
+		t.Fatalf("wrong number of parameters. got=%d", len(function.Parameters))
+	}
+	if function.Default(0) != nil {
+		t.Errorf("x has a default: %s", function.Default(0))
+	}
+	testIntegerLiteral(t, function.Default(1), 10)
+	testIdentifier(t, function.Rest, "rest")
+
+	p = New(lexer.New("f(1, y: 2)"))
+	program = p.ParseProgram()
+	checkParserErrors(t, p)
+
+	call := program.Statements[0].(*ast.ExpressionStatement).Expression.(*ast.CallExpression)
+	if len(call.Arguments) != 1 || len(call.Named) != 1 {
+		t.Fatalf("wrong arguments. got %d positional and %d named", len(call.Arguments), len(call.Named))
+	}
+	testIdentifier(t, call.Named[0].Name, "y")
+	testIntegerLiteral(t, call.Named[0].Value, 2)
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"fn(x = 1, y) { }", "1:11: parameter y without a default follows one with a default"},
+		{"fn(...rest, x) { }", "1:11: expected next token ), got ,"},
+		{"fn(1) { }", "1:4: expected next token Ident, got Int"},
+		{"fn(x y) { }", "1:6: expected next token ,, got Ident"},
+		{"f(y: 1, 2)", "1:9: positional argument follows named argument"},
+		{"f(1 2)", "1:5: expected next token ,, got Int"},
+		{"fn(a, a) { }", "1:7: duplicate parameter a"},
+		{"fn(a, b = 1, b = 2) { }", "1:14: duplicate parameter b"},
+		{"fn(a, ...a) { }", "1:10: duplicate parameter a"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q. want %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
+func TestFunctionLiteralWithName(t *testing.T) {
+	input := `let myFunction = fn() { };`
+
+	l := lexer.New(input)
+	p := New(l)
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d\n", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.LetStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.LetStatement. got=%T", program.Statements[0])
+	}
+
+	function, ok := stmt.Value.(*ast.FunctionLiteral)
+	if !ok {
+		t.Fatalf("stmt.Value is not ast.FunctionLiteral. got=%T", stmt.Value)
+	}
+
+	if function.Name != "myFunction" {
+		t.Errorf("function literal name wrong. want 'myFunction', got=%q\n", function.Name)
+	}
+}
+
 func TestCallExpressionParsing(t *testing.T) {
 	input := "add(1, 2 * 3, 4 + 5);"
 
@@ -712,6 +927,82 @@ func TestLetStatements(t *testing.T) {
 	}
 }
 
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, b] = xs;", "let [a, b] = xs;"},
+		{"let [a, ...rest] = xs", "let [a, ...rest] = xs;"},
+		{"let [...all] = xs", "let [...all] = xs;"},
+		{"let [] = xs", "let [] = xs;"},
+		{"let [a, b,] = xs", "let [a, b] = xs;"},
+		{"let {name, age} = person", "let {name, age} = person;"},
+		{"let {\n  name,\n  age\n} = person", "let {name, age} = person;"},
+		{"let {} = d", "let {} = d;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != 1 {
+			t.Fatalf("%q: wrong number of statements. got=%d", tt.input, len(program.Statements))
+		}
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("let [a, ...rest] = fn() { [1] }"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.LetStatement)
+	if stmt.Name != nil {
+		t.Errorf("stmt.Name not nil. got=%s", stmt.Name)
+	}
+	pattern, ok := stmt.Pattern.(*ast.ArrayPattern)
+	if !ok {
+		t.Fatalf("stmt.Pattern not *ast.ArrayPattern. got=%T", stmt.Pattern)
+	}
+	if len(pattern.Elements) != 1 {
+		t.Fatalf("wrong number of elements. got=%d", len(pattern.Elements))
+	}
+	testIdentifier(t, pattern.Elements[0], "a")
+	testIdentifier(t, pattern.Rest, "rest")
+	if fn := stmt.Value.(*ast.FunctionLiteral); fn.Name != "" {
+		t.Errorf("destructured fn was named %q", fn.Name)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"let [a, ...rest, b] = xs", "1:16: expected next token ], got ,"},
+		{"let [a b] = xs", "1:8: expected next token ,, got Ident"},
+		{"let [1] = xs", "1:6: expected next token Ident, got Int"},
+		{"let [...] = xs", "1:9: expected next token Ident, got ]"},
+		{`let {"name"} = d`, "1:6: expected next token Ident, got String"},
+		{"let {a: b} = d", "1:7: expected next token ,, got :"},
+		{"let [a] xs", "1:9: expected next token =, got Ident"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error for %q. want %q, got %q", tt.input, tt.expected, errors[0])
+		}
+	}
+}
+
 func TestReturnStatements(t *testing.T) {
 	tests := []struct {
 		input         string
@@ -813,6 +1104,71 @@ func TestParsingIndexExpressions(t *testing.T) {
 	}
 }
 
+func TestParsingSliceExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:2]", "(a[1:2])"},
+		{"a[:2]", "(a[:2])"},
+		{"a[1:]", "(a[1:])"},
+		{"a[:]", "(a[:])"},
+		{"a[::-1]", "(a[::(-1)])"},
+		{"a[1 + 1:n - 1:2]", "(a[(1 + 1):(n - 1):2])"},
+		{"a[1::2]", "(a[1::2])"},
+		{"a[:2:]", "(a[:2])"},
+		{"a[1:][0]", "((a[1:])[0])"},
+		{"f(a)[:n]", "(f(a)[:n])"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	p := New(lexer.New("myArray[1:-1]"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	slice, ok := stmt.Expression.(*ast.SliceExpression)
+	if !ok {
+		t.Fatalf("exp not *ast.SliceExpression. got=%T", stmt.Expression)
+	}
+	testIdentifier(t, slice.Left, "myArray")
+	testIntegerLiteral(t, slice.Start, 1)
+	if slice.Step != nil {
+		t.Errorf("slice.Step not nil. got=%s", slice.Step)
+	}
+
+	errorTests := []struct {
+		input    string
+		expected string
+	}{
+		{"a[1:2:3:4]", "1:8: expected next token ], got :"},
+		{"a[1:2", "1:6: expected next token ], got Eof"},
+		{"a[:)]", "1:4: no prefix parse fn available for )"},
+	}
+
+	for _, tt := range errorTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want %q, got %q", tt.expected, errors[0])
+		}
+	}
+}
+
 func TestParsingHashLiteralsStringKeys(t *testing.T) {
 	input := `{"one": 1, "two": 2, "three": 3}`
 
@@ -914,3 +1270,697 @@ func TestParsingHashLiteralsWithExpressions(t *testing.T) {
 		testFunc(value)
 	}
 }
+
+func TestErrorPositions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let = 5;", "1:5: expected next token Ident, got ="},
+		{"let a = 1;\nlet b 2;", "2:7: expected next token =, got Int"},
+		{"if (true) {\n  1\n} else 2", "3:8: expected next token {, got Int"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want %q, got %q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestNodePositions(t *testing.T) {
+	input := "let x = 1;\nx + 2;"
+
+	p := New(lexer.NewWithFile("pos.crab", input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if pos := program.Statements[0].Pos().String(); pos != "pos.crab:1:1" {
+		t.Errorf("let statement position wrong, got %s", pos)
+	}
+
+	stmt := program.Statements[1].(*ast.ExpressionStatement)
+	infix := stmt.Expression.(*ast.InfixExpression)
+	if pos := infix.Pos().String(); pos != "pos.crab:2:3" {
+		t.Errorf("infix position wrong, got %s", pos)
+	}
+	if pos := infix.Left.Pos().String(); pos != "pos.crab:2:1" {
+		t.Errorf("identifier position wrong, got %s", pos)
+	}
+}
+
+func TestLexerErrorsAreReported(t *testing.T) {
+	p := New(lexer.New("let a = 1;\n/* never closed"))
+	p.ParseProgram()
+
+	errors := p.Errors()
+	if len(errors) != 1 || errors[0] != "2:1: unterminated block comment" {
+		t.Errorf("wrong errors, got %q", errors)
+	}
+}
+
+func TestFloatLiteralExpression(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected float64
+	}{
+		{"1.5;", 1.5},
+		{"0.125", 0.125},
+		{"1e-3", 0.001},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		stmt, ok := program.Statements[0].(*ast.ExpressionStatement)
+		if !ok {
+			t.Fatalf("program.Statements[0] is not ast.ExpressionStatement. got=%T", program.Statements[0])
+		}
+
+		literal, ok := stmt.Expression.(*ast.FloatLiteral)
+		if !ok {
+			t.Fatalf("stmt.Expression not ast.FloatLiteral, got %T", stmt.Expression)
+		}
+		if literal.Value != tt.expected {
+			t.Errorf("literal.Value not %v, got %v", tt.expected, literal.Value)
+		}
+	}
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x = 5", "(x = 5)"},
+		{"x = y = 5", "(x = (y = 5))"},
+		{"x += 1 + 2", "(x += (1 + 2))"},
+		{"x -= 1", "(x -= 1)"},
+		{"x *= 2", "(x *= 2)"},
+		{"x /= 2", "(x /= 2)"},
+		{"x %= 2", "(x %= 2)"},
+		{"x **= 2 ** 3", "(x **= (2 ** 3))"},
+		{"x &= y | 1", "(x &= (y | 1))"},
+		{"x |= 1", "(x |= 1)"},
+		{"x ^= 1", "(x ^= 1)"},
+		{"x <<= 1", "(x <<= 1)"},
+		{"x >>= y = 1", "(x >>= (y = 1))"},
+		{"a[1] = b * 2", "((a[1]) = (b * 2))"},
+		{`d["k"] += 1`, "((d[k]) += 1)"},
+		{"let x = y = 1;", "let x = (y = 1);"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if program.String() != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, program.String())
+		}
+	}
+}
+
+func TestInvalidAssignTarget(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"1 = 2", "1:3: cannot assign to 1"},
+		{"a + b = c", "1:7: cannot assign to (a + b)"},
+		{"f() = 1", "1:5: cannot assign to f()"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want %q, got %q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestWhileStatement(t *testing.T) {
+	input := `while (x < 10) { x += 1; }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.WhileStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.WhileStatement. got=%T", program.Statements[0])
+	}
+
+	testInfixExpression(t, stmt.Condition, "x", "<", 10)
+
+	if len(stmt.Body.Statements) != 1 {
+		t.Fatalf("body is not 1 statement. got=%d", len(stmt.Body.Statements))
+	}
+	if stmt.String() != "while (x < 10) (x += 1)" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestForInStatement(t *testing.T) {
+	input := `for (item in [1, 2]) { puts(item); }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	if len(program.Statements) != 1 {
+		t.Fatalf("program.Statements does not contain 1 statement. got=%d", len(program.Statements))
+	}
+
+	stmt, ok := program.Statements[0].(*ast.ForInStatement)
+	if !ok {
+		t.Fatalf("program.Statements[0] is not ast.ForInStatement. got=%T", program.Statements[0])
+	}
+
+	testIdentifier(t, stmt.Variable, "item")
+
+	if _, ok := stmt.Iterable.(*ast.ArrayLiteral); !ok {
+		t.Errorf("stmt.Iterable is not ast.ArrayLiteral. got=%T", stmt.Iterable)
+	}
+	if stmt.String() != "for (item in [1, 2]) puts(item)" {
+		t.Errorf("stmt.String() wrong. got=%q", stmt.String())
+	}
+}
+
+func TestBreakContinueStatements(t *testing.T) {
+	input := `while (true) { break; continue }`
+
+	p := New(lexer.New(input))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.WhileStatement)
+	if len(stmt.Body.Statements) != 2 {
+		t.Fatalf("body is not 2 statements. got=%d", len(stmt.Body.Statements))
+	}
+	if _, ok := stmt.Body.Statements[0].(*ast.BreakStatement); !ok {
+		t.Errorf("body[0] is not ast.BreakStatement. got=%T", stmt.Body.Statements[0])
+	}
+	if _, ok := stmt.Body.Statements[1].(*ast.ContinueStatement); !ok {
+		t.Errorf("body[1] is not ast.ContinueStatement. got=%T", stmt.Body.Statements[1])
+	}
+}
+
+func TestLoopParseErrors(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"while x { }", "1:7: expected next token (, got Ident"},
+		{"for (1 in xs) { }", "1:6: expected next token Ident, got Int"},
+		{"for (x of xs) { }", "1:8: expected next token In, got Ident"},
+		{"for (x in xs) x", "1:15: expected next token {, got Ident"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want %q, got %q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestAutomaticSemicolons(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected []string
+	}{
+		{"let a = b\n(c)", []string{"let a = b;", "c"}},
+		{"a\n[1]", []string{"a", "[1]"}},
+		{"x\n-1", []string{"x", "(-1)"}},
+		{"let a = b(\n  c,\n  d\n)", []string{"let a = b(c, d);"}},
+		{"[\n  1,\n  2\n]", []string{"[1, 2]"}},
+		{"{\n  \"a\": [\n    1,\n    2\n  ]\n}", []string{"{a:[1, 2]}"}},
+		{"if (x) {\n  1\n}\nelse {\n  2\n}", []string{"if x 1else 2"}},
+		{"fn() {\n  return\n}", []string{"fn()return ;"}},
+		{"while (x) {\n  x -= 1\n}\ny", []string{"while x (x -= 1)", "y"}},
+		{"a;;\n\nb", []string{"a", "b"}},
+		{"xs\n  |> f\n  |> g(1)\ny", []string{"((xs |> f()) |> g(1))", "y"}},
+		{"a\n  && b\n  || c\ny", []string{"((a && b) || c)", "y"}},
+		{"if (a\n  && b) {\n  1\n}", []string{"if (a && b) 1"}},
+		{"let ok = x > 0\n  && x < 10", []string{"let ok = ((x > 0) && (x < 10));"}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if len(program.Statements) != len(tt.expected) {
+			t.Fatalf("%q: wrong number of statements. want %d, got %d (%q)",
+				tt.input, len(tt.expected), len(program.Statements), program.String())
+		}
+		for i, stmt := range program.Statements {
+			if stmt.String() != tt.expected[i] {
+				t.Errorf("%q: statement %d wrong. want %q, got %q", tt.input, i, tt.expected[i], stmt.String())
+			}
+		}
+	}
+}
+
+func TestErrorRecovery(t *testing.T) {
+	tests := []struct {
+		input      string
+		errors     []string
+		statements string
+	}{
+		{
+			"let a = ;\nlet b = 2\nlet = 3\nb",
+			[]string{
+				"1:9: no prefix parse fn available for ;",
+				"3:5: expected next token Ident, got =",
+			},
+			"<bad statement>let b = 2;<bad statement>b",
+		},
+		{
+			"let f = fn(x) {\n  let y = x +\n  let z = (1\n  return z\n}\nlet w = 1",
+			[]string{
+				"3:3: no prefix parse fn available for Let",
+				"4:3: expected next token ), got Return",
+			},
+			"let f = fn(x)<bad statement><bad statement>return z;;let w = 1;",
+		},
+		{
+			"let f = fn() {\n  let d = {\"a\" 1}; let y = 2\n  y }\nf()",
+			[]string{"2:16: expected next token :, got Int"},
+			"let f = fn()<bad statement>let y = 2;y;f()",
+		},
+		{
+			"while (x) {\n  let a =\n}\nlet b = 1\nlet = 2",
+			[]string{
+				"3:1: no prefix parse fn available for }",
+				"5:5: expected next token Ident, got =",
+			},
+			"while x <bad statement>let b = 1;<bad statement>",
+		},
+		{
+			"let f = fn() {\n  let a = 1\n\nlet b = )\nlet c = 3",
+			[]string{
+				"4:9: no prefix parse fn available for )",
+				"5:10: expected next token }, got Eof",
+			},
+			"<bad statement>",
+		},
+		{
+			"}\nlet a = 1\n)\nlet b = [1, 2\nlet c = 2",
+			[]string{
+				"1:1: no prefix parse fn available for }",
+				"3:1: no prefix parse fn available for )",
+				"5:1: expected next token ], got Let",
+			},
+			"<bad statement>let a = 1;<bad statement><bad statement>let c = 2;",
+		},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) != len(tt.errors) {
+			t.Fatalf("%q: wrong number of errors. want %q, got %q", tt.input, tt.errors, errors)
+		}
+		for i, err := range errors {
+			if err != tt.errors[i] {
+				t.Errorf("%q: error %d wrong. want %q, got %q", tt.input, i, tt.errors[i], err)
+			}
+		}
+		if program.String() != tt.statements {
+			t.Errorf("%q: wrong statements. want %q, got %q", tt.input, tt.statements, program.String())
+		}
+	}
+}
+
+func TestDiagnostics(t *testing.T) {
+	p := New(lexer.NewWithFile("main.crab", "let a = 1\n\tlet = 2\nlet s = \"é\\q\""))
+	p.ParseProgram()
+
+	diagnostics := p.Diagnostics()
+	if len(diagnostics) != 2 {
+		t.Fatalf("wrong number of diagnostics, got %v", diagnostics)
+	}
+
+	// lexing errors come first
+	lexErr := diagnostics[0]
+	if lexErr.Severity != SeverityError || lexErr.Msg != `unknown escape sequence \q` {
+		t.Errorf("wrong lexer diagnostic, got %+v", lexErr)
+	}
+
+	d := diagnostics[1]
+	if d.Severity != SeverityError {
+		t.Errorf("wrong severity, got %v", d.Severity)
+	}
+	if d.Expected != token.Ident {
+		t.Errorf("wrong expected token, got %q", d.Expected)
+	}
+	if d.Found.Type != token.Assign || d.Found.Literal != "=" {
+		t.Errorf("wrong found token, got %+v", d.Found)
+	}
+	if d.Snippet != "\tlet = 2" {
+		t.Errorf("wrong snippet, got %q", d.Snippet)
+	}
+
+	expected := "main.crab:2:6: error: expected next token Ident, got =\n" +
+		"  \tlet = 2\n" +
+		"  \t    ^\n"
+	if d.Format() != expected {
+		t.Errorf("wrong format. want\n%s\ngot\n%s", expected, d.Format())
+	}
+
+	expected = "main.crab:3:11: error: unknown escape sequence \\q\n" +
+		"  let s = \"é\\q\"\n" +
+		"            ^\n"
+	if lexErr.Format() != expected {
+		t.Errorf("wrong format. want\n%s\ngot\n%s", expected, lexErr.Format())
+	}
+}
+
+func TestLexerErrorsStopParserErrors(t *testing.T) {
+	tests := []struct {
+		input  string
+		errors []string
+	}{
+		{`puts("abc`, []string{"1:6: unterminated string literal"}},
+		{"let s = `abc", []string{"1:9: unterminated raw string literal"}},
+		{"let a = [1, \"x\\q\" 2]", []string{`1:15: unknown escape sequence \q`}},
+		// the statements after the bad one are still checked
+		{"let s = \"\\q\"\nlet = 1", []string{`1:10: unknown escape sequence \q`, "2:5: expected next token Ident, got ="}},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		diagnostics := p.Diagnostics()
+		if len(diagnostics) != len(tt.errors) {
+			t.Errorf("%q: wrong number of diagnostics. want %q, got %v", tt.input, tt.errors, diagnostics)
+			continue
+		}
+		for i, d := range diagnostics {
+			if d.String() != tt.errors[i] {
+				t.Errorf("%q: diagnostic %d wrong. want %q, got %q", tt.input, i, tt.errors[i], d.String())
+			}
+		}
+	}
+}
+
+func TestBadNodes(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"let a = ;", "1:1: bad statement"},
+		{"let f = fn() {\n  1 +\n}", "2:3: bad statement"},
+		{"let a = 1\nif (a) { a } else", "2:1: bad statement"},
+		// errors of the lexer
+		{`let s = "a\q"; s`, "1:1: bad statement"},
+		{"let a = 1\n\"abc", "2:1: bad statement"},
+		{"let f = fn() {\n  \"\\u{zz}\"\n}", "2:3: bad statement"},
+		{"1 @ 2", "1:3: bad statement"},
+		{"let a = 1 /* a", "1:11: bad statement"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+
+		if len(p.Errors()) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		err := ast.Validate(program)
+		if err == nil {
+			t.Fatalf("%q: expected bad nodes in %q", tt.input, program.String())
+		}
+		if err.Error() != tt.expected {
+			t.Errorf("%q: wrong error. want %q, got %q", tt.input, tt.expected, err)
+		}
+	}
+}
+
+func TestInterpolatedStringParsing(t *testing.T) {
+	p := New(lexer.New(`"user ${name} has ${len(items) + 1} items"`))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	str, ok := stmt.Expression.(*ast.InterpolatedString)
+	if !ok {
+		t.Fatalf("stmt.Expression not ast.InterpolatedString, got %T", stmt.Expression)
+	}
+	if len(str.Parts) != 5 {
+		t.Fatalf("wrong number of parts, got %d", len(str.Parts))
+	}
+	if str.String() != "user ${name} has ${(len(items) + 1)} items" {
+		t.Errorf("wrong string, got %q", str.String())
+	}
+	testIdentifier(t, str.Parts[1], "name")
+
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`"a ${}"`, "1:6: no prefix parse fn available for StringEnd"},
+		{`"a ${b c}"`, "1:8: expected next token StringEnd, got Ident"},
+		{`"a ${b`, "1:7: expected next token StringEnd, got Eof"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+
+		errors := p.Errors()
+		if len(errors) == 0 {
+			t.Fatalf("expected parser errors for %q", tt.input)
+		}
+		if errors[0] != tt.expected {
+			t.Errorf("wrong error. want %q, got %q", tt.expected, errors[0])
+		}
+	}
+}
+
+func TestPipeExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"x |> f(a)", "(x |> f(a))"},
+		{"x |> f", "(x |> f())"},
+		{"x |> f() |> g(1, 2)", "((x |> f()) |> g(1, 2))"},
+		{"a + b |> f(c * d)", "((a + b) |> f((c * d)))"},
+		{"a || b |> f", "((a || b) |> f())"},
+		{"x = y |> f", "(x = (y |> f()))"},
+		{"x |> (y |> f)", "(x |> (y |> f())())"},
+		{"x |> a[0]", "(x |> (a[0])())"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		actual := program.String()
+		if actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+
+		// the printed form parses back to the same tree
+		p = New(lexer.New(actual))
+		reparsed := p.ParseProgram()
+		checkParserErrors(t, p)
+		if reparsed.String() != actual {
+			t.Errorf("%q did not round-trip, got %q", actual, reparsed.String())
+		}
+	}
+
+	p := New(lexer.New("x |> f(a)"))
+	program := p.ParseProgram()
+	checkParserErrors(t, p)
+
+	stmt := program.Statements[0].(*ast.ExpressionStatement)
+	call, ok := stmt.Expression.(*ast.CallExpression)
+	if !ok {
+		t.Fatalf("stmt.Expression not ast.CallExpression, got %T", stmt.Expression)
+	}
+	if !call.Piped {
+		t.Errorf("call not marked as piped")
+	}
+	testIdentifier(t, call.Function, "f")
+	if len(call.Arguments) != 2 {
+		t.Fatalf("wrong number of arguments, got %d", len(call.Arguments))
+	}
+	testIdentifier(t, call.Arguments[0], "x")
+	testIdentifier(t, call.Arguments[1], "a")
+
+	p = New(lexer.New("x |> ;"))
+	p.ParseProgram()
+	errors := p.Errors()
+	if len(errors) == 0 || errors[0] != "1:6: no prefix parse fn available for ;" {
+		t.Errorf("wrong errors for missing pipe target, got %q", errors)
+	}
+}
+
+func TestMatchExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { 1 => a, _ => b }", "match (x) { 1 => a, _ => b }"},
+		{"match (x) {\n  -1 => a\n  \"s\" => b\n}", "match (x) { (-1) => a, s => b }"},
+		{"match (x) { [a, _, ...rest] => a, [] => 0 }", "match (x) { [a, _, ...rest] => a, [] => 0 }"},
+		{"match (x) { {\"k\": [1, b], c} => b + c }", "match (x) { {\"k\": [1, b], \"c\": c} => (b + c) }"},
+		{"match (x) { n if n > 1 => n, true => 0, }", "match (x) { n if (n > 1) => n, true => 0 }"},
+		{"let y = match (f(x)) { _ => 1 } + 1", "let y = (match (f(x)) { _ => 1 } + 1);"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"match (x) { a + 1 => 1 }", "1:15: expected next token =>, got +"},
+		{"match (x) { 1 => a 2 => b }", "1:20: expected next token ,, got Int"},
+		{"match (x) { (a) => 1 }", "1:13: expected pattern, got ("},
+		{"match (x) { -a => 1 }", "1:14: expected number in pattern, got Ident"},
+		{"match (x) { {1: a} => 1 }", "1:14: expected key in dict pattern, got Int"},
+		{"match x { _ => 1 }", "1:7: expected next token (, got Ident"},
+	}
+
+	for _, tt := range errTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. want %q, got %q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestTryExpressionParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { a } catch (e) { b }", "try { a } catch (e) { b }"},
+		{"try { a } finally { c }", "try { a } finally { c }"},
+		{"try {\n  a\n}\ncatch (e) {\n  b\n}\nfinally {\n  c\n}", "try { a } catch (e) { b } finally { c }"},
+		{"let x = try { f() } catch (e) { 0 } + 1", "let x = (try { f() } catch (e) { 0 } + 1);"},
+		{"throw x + 1", "throw (x + 1);"},
+		{"fn() { throw e\n}", "fn()throw e;"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"try { a }", "1:10: expected catch or finally after try, got Eof"},
+		{"try { a } catch { b }", "1:17: expected next token (, got {"},
+		{"try { a } catch (1) { b }", "1:18: expected next token Ident, got Int"},
+		{"try a", "1:5: expected next token {, got Ident"},
+		{"throw;", "1:6: no prefix parse fn available for ;"},
+	}
+
+	for _, tt := range errTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. want %q, got %q", tt.input, tt.expected, errors)
+		}
+	}
+}
+
+func TestModuleParsing(t *testing.T) {
+	tests := []struct {
+		input    string
+		expected string
+	}{
+		{`import "lib/strings.crab" as str`, `import "lib/strings.crab" as str;`},
+		{"import \"a.crab\" as a\nimport \"b.crab\" as b", `import "a.crab" as a;import "b.crab" as b;`},
+		{"export let x = 1", "export let x = 1;"},
+		{"export let [a, ...b] = xs", "export let [a, ...b] = xs;"},
+		{"m.f(1) + m.xs[0]", "((m.f)(1) + ((m.xs)[0]))"},
+		{"-m.x", "(-(m.x))"},
+		{"m.x.y", "((m.x).y)"},
+	}
+
+	for _, tt := range tests {
+		p := New(lexer.New(tt.input))
+		program := p.ParseProgram()
+		checkParserErrors(t, p)
+
+		if actual := program.String(); actual != tt.expected {
+			t.Errorf("expected=%q, got=%q", tt.expected, actual)
+		}
+	}
+
+	errTests := []struct {
+		input    string
+		expected string
+	}{
+		{"import lib as l", "1:8: expected next token String, got Ident"},
+		{`import "lib.crab"`, "1:18: expected next token As, got Eof"},
+		{`import "lib.crab" as "l"`, "1:22: expected next token Ident, got String"},
+		{"export x = 1", "1:8: expected next token Let, got Ident"},
+		{"m.1", "1:3: expected next token Ident, got Int"},
+		{`fn() { import "lib.crab" as l }`, "1:8: import must be at the top level of a script"},
+		{"if (x) { export let y = 1 }", "1:17: export must be at the top level of a script"},
+	}
+
+	for _, tt := range errTests {
+		p := New(lexer.New(tt.input))
+		p.ParseProgram()
+		errors := p.Errors()
+		if len(errors) == 0 || errors[0] != tt.expected {
+			t.Errorf("wrong errors for %q. want %q, got %q", tt.input, tt.expected, errors)
+		}
+	}
+}
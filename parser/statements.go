@@ -3,6 +3,7 @@ package parser
 import (
 	"crabscript.rs/ast"
 	"crabscript.rs/token"
+	"errors"
 	"fmt"
 	"strconv"
 )
@@ -19,16 +20,55 @@ func (p *Parser) registerInfix(tokenType token.TokenType, fn infixParseFn) {
 	p.infixParseFns[tokenType] = fn
 }
 
-// keep parsing sub-statements until none are left
+// keep parsing sub-statements until none are left. A statement that fails
+// to parse is returned as an ast.BadStatement, with the parser recovering.
 func (p *Parser) parseStatement() ast.Statement {
+	start := p.curToken
+
+	var stmt ast.Statement
 	switch p.curToken.Type {
 	case token.Let:
-		return p.parseLetStatement()
+		stmt = p.parseLetStatement()
 	case token.Return:
-		return p.parseReturnStatement()
+		stmt = p.parseReturnStatement()
+	case token.Throw:
+		stmt = p.parseThrowStatement()
+	case token.Import:
+		stmt = p.parseImportStatement()
+	case token.Export:
+		stmt = p.parseExportStatement()
+	case token.While:
+		stmt = p.parseWhileStatement()
+	case token.For:
+		stmt = p.parseForInStatement()
+	case token.Break:
+		stmt = p.parseBreakStatement()
+	case token.Continue:
+		stmt = p.parseContinueStatement()
+	case token.Semicolon:
+		return nil // empty statement
 	default:
-		return p.parseExpressionStatement()
+		stmt = p.parseExpressionStatement()
+	}
+
+	if bad := p.lexedBadly(); bad || p.recovering {
+		return &ast.BadStatement{Token: start}
 	}
+	return stmt
+}
+
+// reports whether the lexer found an error in the tokens up to curToken
+// not yet accounted for, such as a bad escape in a string literal. The
+// lexer reported it already, the statement holding it is made bad so that
+// the engines don't run it.
+func (p *Parser) lexedBadly() bool {
+	errs := p.l.ErrorList()
+	bad := false
+	for p.lexErrors < len(errs) && errs[p.lexErrors].Pos.Offset < p.curToken.End.Offset {
+		p.lexErrors++
+		bad = true
+	}
+	return bad
 }
 
 func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
@@ -45,6 +85,14 @@ func (p *Parser) parseExpressionStatement() *ast.ExpressionStatement {
 func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	stmt := &ast.ReturnStatement{Token: p.curToken}
 
+	// bare return, ended by a semicolon, newline or the end of the block
+	if p.peekTokenIs(token.Semicolon) || p.peekTokenIs(token.RBrace) || p.peekTokenIs(token.Eof) {
+		if p.peekTokenIs(token.Semicolon) {
+			p.nextToken()
+		}
+		return stmt
+	}
+
 	p.nextToken()
 
 	stmt.ReturnValue = p.parseExpression(Lowest)
@@ -57,6 +105,130 @@ func (p *Parser) parseReturnStatement() *ast.ReturnStatement {
 	return stmt
 }
 
+func (p *Parser) parseThrowStatement() *ast.ThrowStatement {
+	stmt := &ast.ThrowStatement{Token: p.curToken}
+
+	p.nextToken()
+	stmt.Value = p.parseExpression(Lowest)
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+func (p *Parser) parseImportStatement() *ast.ImportStatement {
+	stmt := &ast.ImportStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.String) {
+		return nil
+	}
+	stmt.Path = p.curToken.Literal
+
+	if !p.expectPeek(token.As) || !p.expectPeek(token.Ident) {
+		return nil
+	}
+	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+
+	return stmt
+}
+
+// parses `export let ...`, a let statement whose names importers can read
+func (p *Parser) parseExportStatement() *ast.LetStatement {
+	if !p.expectPeek(token.Let) {
+		return nil
+	}
+
+	stmt := p.parseLetStatement()
+	if stmt != nil {
+		stmt.Export = true
+	}
+	return stmt
+}
+
+func (p *Parser) parseWhileStatement() *ast.WhileStatement {
+	stmt := &ast.WhileStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LParen) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Condition = p.parseExpression(Lowest)
+
+	if !p.expectPeek(token.RParen) {
+		return nil
+	}
+	if !p.expectPeek(token.LBrace) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+// parsing 'for (<Variable> in <Iterable>) { <Body> }'
+func (p *Parser) parseForInStatement() *ast.ForInStatement {
+	stmt := &ast.ForInStatement{Token: p.curToken}
+
+	if !p.expectPeek(token.LParen) {
+		return nil
+	}
+	if !p.expectPeek(token.Ident) {
+		return nil
+	}
+
+	stmt.Variable = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	if !p.expectPeek(token.In) {
+		return nil
+	}
+
+	p.nextToken()
+	stmt.Iterable = p.parseExpression(Lowest)
+
+	if !p.expectPeek(token.RParen) {
+		return nil
+	}
+	if !p.expectPeek(token.LBrace) {
+		return nil
+	}
+
+	stmt.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseBreakStatement() *ast.BreakStatement {
+	stmt := &ast.BreakStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+	return stmt
+}
+
+func (p *Parser) parseContinueStatement() *ast.ContinueStatement {
+	stmt := &ast.ContinueStatement{Token: p.curToken}
+
+	if p.peekTokenIs(token.Semicolon) {
+		p.nextToken()
+	}
+	return stmt
+}
+
 func (p *Parser) parseIdentifier() ast.Expression {
 	return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 }
@@ -65,8 +237,11 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	lit := &ast.IntegerLiteral{Token: p.curToken}
 
 	value, err := strconv.ParseInt(p.curToken.Literal, 0, 64)
-	if err != nil {
-		p.errors = append(p.errors,
+	if errors.Is(err, strconv.ErrRange) {
+		p.errorAt(p.curToken.Pos,
+			fmt.Sprintf("integer literal %v overflows int64", p.curToken.Literal))
+	} else if err != nil {
+		p.errorAt(p.curToken.Pos,
 			fmt.Sprintf("could not parse %v as integer", p.curToken.Literal))
 	}
 
@@ -74,11 +249,24 @@ func (p *Parser) parseIntegerLiteral() ast.Expression {
 	return lit
 }
 
+func (p *Parser) parseFloatLiteral() ast.Expression {
+	lit := &ast.FloatLiteral{Token: p.curToken}
+
+	value, err := strconv.ParseFloat(p.curToken.Literal, 64)
+	if err != nil {
+		p.errorAt(p.curToken.Pos,
+			fmt.Sprintf("could not parse %v as float", p.curToken.Literal))
+	}
+
+	lit.Value = value
+	return lit
+}
+
 func (p *Parser) parseExpression(precedence int) ast.Expression {
 	prefix := p.prefixParseFns[p.curToken.Type]
 	if prefix == nil {
 		p.noPrefixParseFnError(p.curToken.Type)
-		return nil
+		return &ast.BadExpression{Token: p.curToken}
 	}
 	leftExp := prefix()
 
@@ -99,12 +287,24 @@ func (p *Parser) parseExpression(precedence int) ast.Expression {
 func (p *Parser) parseLetStatement() *ast.LetStatement {
 	stmt := &ast.LetStatement{Token: p.curToken}
 
-	if !p.expectPeek(token.Ident) {
-		return nil
+	switch {
+	case p.peekTokenIs(token.LBracket):
+		p.nextToken()
+		if stmt.Pattern = p.parseArrayPattern(); stmt.Pattern == nil {
+			return nil
+		}
+	case p.peekTokenIs(token.LBrace):
+		p.nextToken()
+		if stmt.Pattern = p.parseDictPattern(); stmt.Pattern == nil {
+			return nil
+		}
+	default:
+		if !p.expectPeek(token.Ident) {
+			return nil
+		}
+		stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
 	}
 
-	stmt.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-
 	if !p.expectPeek(token.Assign) {
 		return nil
 	}
@@ -113,6 +313,11 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 
 	stmt.Value = p.parseExpression(Lowest)
 
+	// name fns after their binding so they can be identified in stack traces
+	if fn, ok := stmt.Value.(*ast.FunctionLiteral); ok && stmt.Name != nil {
+		fn.Name = stmt.Name.Value
+	}
+
 	if p.peekTokenIs(token.Semicolon) {
 		p.nextToken()
 	}
@@ -120,6 +325,61 @@ func (p *Parser) parseLetStatement() *ast.LetStatement {
 	return stmt
 }
 
+// parses `[a, b, ...rest]` on the left of a let, or returns nil
+func (p *Parser) parseArrayPattern() ast.Expression {
+	pattern := &ast.ArrayPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBracket) {
+		// the rest can only come last, so the `]` must follow it
+		if p.peekTokenIs(token.Ellipsis) {
+			p.nextToken()
+			if !p.expectPeek(token.Ident) {
+				return nil
+			}
+			pattern.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		if !p.expectPeek(token.Ident) {
+			return nil
+		}
+		pattern.Elements = append(pattern.Elements, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+
+		if !p.peekTokenIs(token.RBracket) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBracket) {
+		return nil
+	}
+
+	return pattern
+}
+
+// parses `{name, age}` on the left of a let, or returns nil
+func (p *Parser) parseDictPattern() ast.Expression {
+	pattern := &ast.DictPattern{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBrace) {
+		if !p.expectPeek(token.Ident) {
+			return nil
+		}
+		pattern.Keys = append(pattern.Keys, &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal})
+		p.skipNewlines()
+
+		if !p.peekTokenIs(token.RBrace) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBrace) {
+		return nil
+	}
+
+	return pattern
+}
+
 func (p *Parser) parsePrefixExpression() ast.Expression {
 	expression := &ast.PrefixExpression{
 		Token:    p.curToken,
@@ -139,12 +399,39 @@ func (p *Parser) parseInfixExpression(left ast.Expression) ast.Expression {
 	}
 
 	precedence := p.curPrecedence()
+	// `**` is right associative, `a ** b ** c` is `a ** (b ** c)`
+	if p.curTokenIs(token.Power) {
+		precedence--
+	}
 	p.nextToken()
 	expression.Right = p.parseExpression(precedence)
 
 	return expression
 }
 
+// assignment is right associative, `a = b = c` assigns c to both
+func (p *Parser) parseAssignExpression(left ast.Expression) ast.Expression {
+	expression := &ast.AssignExpression{
+		Token:    p.curToken,
+		Operator: p.curToken.Literal,
+		Target:   left,
+	}
+
+	switch left.(type) {
+	case *ast.Identifier, *ast.IndexExpression:
+	case *ast.BadExpression:
+		return left // target already failed to parse
+	default:
+		p.errorAt(p.curToken.Pos, fmt.Sprintf("cannot assign to %s", left))
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	p.nextToken()
+	expression.Value = p.parseExpression(Lowest)
+
+	return expression
+}
+
 func (p *Parser) parseBoolean() ast.Expression {
 	return &ast.Boolean{Token: p.curToken, Value: p.curTokenIs(token.True)}
 }
@@ -152,12 +439,13 @@ func (p *Parser) parseBoolean() ast.Expression {
 // parse expression until we find a right paren,
 // called when we first find a left paren
 func (p *Parser) parseGroupedExpression() ast.Expression {
+	start := p.curToken
 	p.nextToken()
 
 	exp := p.parseExpression(Lowest)
 
 	if !p.expectPeek(token.RParen) {
-		return nil
+		return &ast.BadExpression{Token: start}
 	}
 
 	return exp
@@ -168,19 +456,19 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 	// parsing the 'if (<Condition>)' expression
 	if !p.expectPeek(token.LParen) {
-		return nil
+		return &ast.BadExpression{Token: expression.Token}
 	}
 
 	p.nextToken()
 	expression.Condition = p.parseExpression(Lowest)
 
 	if !p.expectPeek(token.RParen) {
-		return nil
+		return &ast.BadExpression{Token: expression.Token}
 	}
 
 	// expecting block to follow Condition
 	if !p.expectPeek(token.LBrace) {
-		return nil
+		return &ast.BadExpression{Token: expression.Token}
 	}
 
 	expression.Consequence = p.parseBlockStatement()
@@ -191,7 +479,7 @@ func (p *Parser) parseIfExpression() ast.Expression {
 
 		// expecting block to follow
 		if !p.expectPeek(token.LBrace) {
-			return nil
+			return &ast.BadExpression{Token: expression.Token}
 		}
 
 		expression.Alternative = p.parseBlockStatement()
@@ -200,36 +488,273 @@ func (p *Parser) parseIfExpression() ast.Expression {
 	return expression
 }
 
+// parses `try { <Body> } catch (<Param>) { <Catch> } finally { <Finally> }`,
+// where either of the catch and finally clauses may be left out
+func (p *Parser) parseTryExpression() ast.Expression {
+	expression := &ast.TryExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LBrace) {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+	expression.Body = p.parseBlockStatement()
+
+	if p.peekTokenIs(token.Catch) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LParen) || !p.expectPeek(token.Ident) {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+		expression.Param = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+		if !p.expectPeek(token.RParen) || !p.expectPeek(token.LBrace) {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+		expression.Catch = p.parseBlockStatement()
+	}
+
+	if p.peekTokenIs(token.Finally) {
+		p.nextToken()
+
+		if !p.expectPeek(token.LBrace) {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+		expression.Finally = p.parseBlockStatement()
+	}
+
+	if expression.Catch == nil && expression.Finally == nil {
+		p.errorAt(p.peekToken.Pos, fmt.Sprintf("expected catch or finally after try, got %v", p.peekToken.Type))
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	return expression
+}
+
+// parses `match (<Subject>) { <Pattern> if <Guard> => <Body>, ... }`, where
+// the arms are separated by commas or newlines
+func (p *Parser) parseMatchExpression() ast.Expression {
+	expression := &ast.MatchExpression{Token: p.curToken}
+
+	if !p.expectPeek(token.LParen) {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	p.nextToken()
+	expression.Subject = p.parseExpression(Lowest)
+
+	if !p.expectPeek(token.RParen) || !p.expectPeek(token.LBrace) {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+	p.skipNewlines()
+
+	for !p.peekTokenIs(token.RBrace) {
+		p.nextToken()
+		arm := &ast.MatchArm{Pattern: p.parsePattern()}
+		if arm.Pattern == nil {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+
+		if p.peekTokenIs(token.If) {
+			p.nextToken()
+			p.nextToken()
+			arm.Guard = p.parseExpression(Lowest)
+		}
+
+		if !p.expectPeek(token.Arrow) {
+			return &ast.BadExpression{Token: expression.Token}
+		}
+		p.nextToken()
+		arm.Body = p.parseExpression(Lowest)
+		expression.Arms = append(expression.Arms, arm)
+
+		newline := p.peekTokenIs(token.Semicolon) && p.peekToken.Literal == "\n"
+		p.skipNewlines()
+		if p.peekTokenIs(token.Comma) {
+			p.nextToken()
+			p.skipNewlines()
+		} else if !newline && !p.peekTokenIs(token.RBrace) {
+			p.peekError(token.Comma)
+			return &ast.BadExpression{Token: expression.Token}
+		}
+	}
+
+	if !p.expectPeek(token.RBrace) {
+		return &ast.BadExpression{Token: expression.Token}
+	}
+
+	return expression
+}
+
+// parses the pattern of a match arm starting at the current token, or
+// returns nil
+func (p *Parser) parsePattern() ast.Expression {
+	switch p.curToken.Type {
+	case token.Ident:
+		return &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	case token.Int, token.Float, token.String, token.True, token.False:
+		return p.prefixParseFns[p.curToken.Type]()
+
+	case token.Minus:
+		if !p.peekTokenIs(token.Int) && !p.peekTokenIs(token.Float) {
+			p.errorAt(p.peekToken.Pos, fmt.Sprintf("expected number in pattern, got %v", p.peekToken.Type))
+			return nil
+		}
+		return p.parsePrefixExpression()
+
+	case token.LBracket:
+		return p.parseArrayShape()
+
+	case token.LBrace:
+		return p.parseDictShape()
+
+	default:
+		p.errorAt(p.curToken.Pos, fmt.Sprintf("expected pattern, got %v", p.curToken.Type))
+		return nil
+	}
+}
+
+// parses `[<Pattern>, ..., ...rest]`, or returns nil
+func (p *Parser) parseArrayShape() ast.Expression {
+	shape := &ast.ArrayShape{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBracket) {
+		// the rest can only come last, so the `]` must follow it
+		if p.peekTokenIs(token.Ellipsis) {
+			p.nextToken()
+			if !p.expectPeek(token.Ident) {
+				return nil
+			}
+			shape.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			break
+		}
+
+		p.nextToken()
+		el := p.parsePattern()
+		if el == nil {
+			return nil
+		}
+		shape.Elements = append(shape.Elements, el)
+
+		if !p.peekTokenIs(token.RBracket) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBracket) {
+		return nil
+	}
+
+	return shape
+}
+
+// parses `{"key": <Pattern>, name: <Pattern>, name}`, or returns nil. A
+// bare name is short for `name: name`.
+func (p *Parser) parseDictShape() ast.Expression {
+	shape := &ast.DictShape{Token: p.curToken}
+
+	for !p.peekTokenIs(token.RBrace) {
+		p.nextToken()
+		if !p.curTokenIs(token.String) && !p.curTokenIs(token.Ident) {
+			p.errorAt(p.curToken.Pos, fmt.Sprintf("expected key in dict pattern, got %v", p.curToken.Type))
+			return nil
+		}
+		key := p.curToken
+
+		var value ast.Expression
+		if p.peekTokenIs(token.Colon) {
+			p.nextToken()
+			p.nextToken()
+			if value = p.parsePattern(); value == nil {
+				return nil
+			}
+		} else if key.Type == token.Ident {
+			value = &ast.Identifier{Token: key, Value: key.Literal}
+		} else {
+			p.peekError(token.Colon)
+			return nil
+		}
+		shape.Keys = append(shape.Keys, key.Literal)
+		shape.Values = append(shape.Values, value)
+		p.skipNewlines()
+
+		if !p.peekTokenIs(token.RBrace) && !p.expectPeek(token.Comma) {
+			return nil
+		}
+	}
+
+	if !p.expectPeek(token.RBrace) {
+		return nil
+	}
+
+	return shape
+}
+
 // parse block until we hit '}' or Eof
 func (p *Parser) parseBlockStatement() *ast.BlockStatement {
 	block := &ast.BlockStatement{Token: p.curToken}
 	block.Statements = []ast.Statement{}
+	depth := p.depth
 
 	p.nextToken()
 
 	for !p.curTokenIs(token.RBrace) && !p.curTokenIs(token.Eof) {
+		start := p.curToken
 		stmt := p.parseStatement()
 		if stmt != nil {
+			p.checkNested(stmt)
 			block.Statements = append(block.Statements, stmt)
 		}
+		if p.recovering {
+			if p.synchronize(depth, start) {
+				continue
+			}
+			// the broken statement ran up to the end of the block
+			if p.depth < depth {
+				break
+			}
+		}
 		p.nextToken()
 	}
+
+	if p.curTokenIs(token.Eof) {
+		p.report(Diagnostic{
+			Pos:      p.curToken.Pos,
+			Msg:      fmt.Sprintf("expected next token %v, got %v", token.RBrace, token.Eof),
+			Expected: token.RBrace,
+			Found:    p.curToken,
+		})
+	}
 	return block
 }
 
+// reports an import or export inside a block, which are only allowed at the
+// top level of a script
+func (p *Parser) checkNested(stmt ast.Statement) {
+	switch stmt := stmt.(type) {
+	case *ast.ImportStatement:
+		p.errorAt(stmt.Pos(), "import must be at the top level of a script")
+	case *ast.LetStatement:
+		if stmt.Export {
+			p.errorAt(stmt.Pos(), "export must be at the top level of a script")
+		}
+	}
+}
+
 func (p *Parser) parseFunctionLiteral() ast.Expression {
 	lit := &ast.FunctionLiteral{Token: p.curToken}
 
 	// parsing params for fn
 	if !p.expectPeek(token.LParen) {
-		return nil
+		return &ast.BadExpression{Token: lit.Token}
 	}
 
-	lit.Parameters = p.parseFunctionParameters()
+	if !p.parseFunctionParameters(lit) {
+		return &ast.BadExpression{Token: lit.Token}
+	}
 
 	// expecting body of function after dealing with params
 	if !p.expectPeek(token.LBrace) {
-		return nil
+		return &ast.BadExpression{Token: lit.Token}
 	}
 
 	lit.Body = p.parseBlockStatement()
@@ -237,39 +762,105 @@ func (p *Parser) parseFunctionLiteral() ast.Expression {
 	return lit
 }
 
-func (p *Parser) parseFunctionParameters() []*ast.Identifier {
-	ident := []*ast.Identifier{}
+// parses `(a, b = 1, ...rest)` into the params of fn, a param without a
+// default can't follow one with a default, the rest param comes last and
+// no two params have the same name
+func (p *Parser) parseFunctionParameters(fn *ast.FunctionLiteral) bool {
+	fn.Parameters = []*ast.Identifier{}
+	seen := map[string]bool{}
+
+	for !p.peekTokenIs(token.RParen) {
+		if p.peekTokenIs(token.Ellipsis) {
+			p.nextToken()
+			if !p.expectPeek(token.Ident) {
+				return false
+			}
+			fn.Rest = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			if seen[fn.Rest.Value] {
+				p.errorAt(fn.Rest.Pos(), fmt.Sprintf("duplicate parameter %s", fn.Rest.Value))
+				return false
+			}
+			break
+		}
+
+		if !p.expectPeek(token.Ident) {
+			return false
+		}
+		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+		if seen[param.Value] {
+			p.errorAt(param.Pos(), fmt.Sprintf("duplicate parameter %s", param.Value))
+			return false
+		}
+		seen[param.Value] = true
+
+		var def ast.Expression
+		if p.peekTokenIs(token.Assign) {
+			p.nextToken()
+			p.nextToken()
+			def = p.parseExpression(Lowest)
+		} else if len(fn.Defaults) > 0 && fn.Defaults[len(fn.Defaults)-1] != nil {
+			p.errorAt(param.Pos(), fmt.Sprintf("parameter %s without a default follows one with a default", param.Value))
+			return false
+		}
+		fn.Parameters = append(fn.Parameters, param)
+		fn.Defaults = append(fn.Defaults, def)
 
-	// no params, increment token
-	if p.peekTokenIs(token.RParen) {
-		p.nextToken()
-		return ident
+		if !p.peekTokenIs(token.RParen) && !p.expectPeek(token.Comma) {
+			return false
+		}
 	}
 
-	p.nextToken()
+	return p.expectPeek(token.RParen)
+}
 
-	id := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-	ident = append(ident, id)
+func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
+	exp := &ast.CallExpression{Token: p.curToken, Function: function}
+	exp.Arguments = []ast.Expression{}
 
-	for p.peekTokenIs(token.Comma) {
-		p.nextToken()
+	for !p.peekTokenIs(token.RParen) {
 		p.nextToken()
 
-		param := &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
-		ident = append(ident, param)
+		// `name: value`, which can only be followed by more named arguments
+		if p.curTokenIs(token.Ident) && p.peekTokenIs(token.Colon) {
+			arg := &ast.NamedArgument{Token: p.curToken}
+			arg.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+			p.nextToken()
+			p.nextToken()
+			arg.Value = p.parseExpression(Lowest)
+			exp.Named = append(exp.Named, arg)
+		} else if len(exp.Named) > 0 {
+			p.errorAt(p.curToken.Pos, "positional argument follows named argument")
+			return &ast.BadExpression{Token: exp.Token}
+		} else {
+			exp.Arguments = append(exp.Arguments, p.parseExpression(Lowest))
+		}
+
+		if !p.peekTokenIs(token.RParen) && !p.expectPeek(token.Comma) {
+			return &ast.BadExpression{Token: exp.Token}
+		}
 	}
+
 	if !p.expectPeek(token.RParen) {
-		return nil
+		return &ast.BadExpression{Token: exp.Token}
 	}
 
-	return ident
+	return exp
 }
 
-func (p *Parser) parseCallExpression(function ast.Expression) ast.Expression {
-	exp := &ast.CallExpression{Token: p.curToken, Function: function}
-	exp.Arguments = p.parseExpressionList(token.RParen)
+// `x |> f(a)` is parsed as the call `f(x, a)`, and `x |> f` as `f(x)`
+func (p *Parser) parsePipeExpression(left ast.Expression) ast.Expression {
+	pipe := p.curToken
+	p.nextToken()
+	right := p.parseExpression(Pipe)
 
-	return exp
+	call, ok := right.(*ast.CallExpression)
+	if !ok || call.Piped {
+		call = &ast.CallExpression{Token: pipe, Function: right}
+	}
+	call.Arguments = append([]ast.Expression{left}, call.Arguments...)
+	call.Piped = true
+
+	return call
 }
 
 func (p *Parser) parseExpressionList(end token.TokenType) []ast.Expression {
@@ -301,6 +892,35 @@ func (p *Parser) parseStringLiteral() ast.Expression {
 	return &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal}
 }
 
+// parsing "<Text>${<Expression>}<Text>...", the current token is the
+// StringPart holding the text before the first `${`
+func (p *Parser) parseInterpolatedString() ast.Expression {
+	str := &ast.InterpolatedString{Token: p.curToken}
+
+	for {
+		if p.curToken.Literal != "" {
+			str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+		}
+
+		p.nextToken()
+		str.Parts = append(str.Parts, p.parseExpression(Lowest))
+
+		switch {
+		case p.peekTokenIs(token.StringPart):
+			p.nextToken()
+		case p.peekTokenIs(token.StringEnd):
+			p.nextToken()
+			if p.curToken.Literal != "" {
+				str.Parts = append(str.Parts, &ast.StringLiteral{Token: p.curToken, Value: p.curToken.Literal})
+			}
+			return str
+		default:
+			p.peekError(token.StringEnd)
+			return &ast.BadExpression{Token: str.Token}
+		}
+	}
+}
+
 func (p *Parser) parseArrayLiteral() ast.Expression {
 	array := &ast.ArrayLiteral{Token: p.curToken}
 
@@ -311,17 +931,64 @@ func (p *Parser) parseArrayLiteral() ast.Expression {
 func (p *Parser) parseIndexExpression(left ast.Expression) ast.Expression {
 	exp := &ast.IndexExpression{Token: p.curToken, Left: left}
 
+	if p.peekTokenIs(token.Colon) {
+		return p.parseSliceExpression(exp.Token, left, nil)
+	}
+
 	p.nextToken()
 
 	exp.Index = p.parseExpression(Lowest)
 
+	if p.peekTokenIs(token.Colon) {
+		return p.parseSliceExpression(exp.Token, left, exp.Index)
+	}
+
 	if !p.expectPeek(token.RBracket) {
-		return nil
+		return &ast.BadExpression{Token: exp.Token}
+	}
+
+	return exp
+}
+
+func (p *Parser) parseMemberExpression(left ast.Expression) ast.Expression {
+	exp := &ast.MemberExpression{Token: p.curToken, Left: left}
+
+	if !p.expectPeek(token.Ident) {
+		return &ast.BadExpression{Token: exp.Token}
+	}
+	exp.Name = &ast.Identifier{Token: p.curToken, Value: p.curToken.Literal}
+
+	return exp
+}
+
+// parses the rest of `left[start:end:step]` from the first `:`
+func (p *Parser) parseSliceExpression(tok token.Token, left ast.Expression, start ast.Expression) ast.Expression {
+	exp := &ast.SliceExpression{Token: tok, Left: left, Start: start}
+
+	p.nextToken()
+	exp.End = p.parseSliceBound()
+
+	if p.peekTokenIs(token.Colon) {
+		p.nextToken()
+		exp.Step = p.parseSliceBound()
+	}
+
+	if !p.expectPeek(token.RBracket) {
+		return &ast.BadExpression{Token: exp.Token}
 	}
 
 	return exp
 }
 
+// parses the bound after a `:` in a slice, which is nil when left out
+func (p *Parser) parseSliceBound() ast.Expression {
+	if p.peekTokenIs(token.Colon) || p.peekTokenIs(token.RBracket) {
+		return nil
+	}
+	p.nextToken()
+	return p.parseExpression(Lowest)
+}
+
 func (p *Parser) parseDictLiteral() ast.Expression {
 	dict := &ast.DictLiteral{Token: p.curToken, Pairs: make(map[ast.Expression]ast.Expression)}
 
@@ -331,22 +998,23 @@ func (p *Parser) parseDictLiteral() ast.Expression {
 
 		// error when missing colon separator
 		if !p.expectPeek(token.Colon) {
-			return nil
+			return &ast.BadExpression{Token: dict.Token}
 		}
 
 		p.nextToken()
 		value := p.parseExpression(Lowest)
 
 		dict.Pairs[key] = value
+		p.skipNewlines()
 
 		// error when not continuing nor closing dict definition
 		if !p.peekTokenIs(token.RBrace) && !p.expectPeek(token.Comma) {
-			return nil
+			return &ast.BadExpression{Token: dict.Token}
 		}
 	}
 
 	if !p.expectPeek(token.RBrace) {
-		return nil
+		return &ast.BadExpression{Token: dict.Token}
 	}
 
 	return dict
@@ -5,6 +5,16 @@ import "crabscript.rs/token"
 func (p *Parser) nextToken() {
 	p.curToken = p.peekToken
 	p.peekToken = p.l.NextToken()
+
+	switch p.curToken.Type {
+	case token.LBrace:
+		p.depth++
+	case token.RBrace:
+		// a stray brace is an error, don't let it unbalance the count
+		if p.depth > 0 {
+			p.depth--
+		}
+	}
 }
 
 func (p *Parser) curTokenIs(t token.TokenType) bool {
@@ -15,6 +25,14 @@ func (p *Parser) peekTokenIs(t token.TokenType) bool {
 	return p.peekToken.Type == t
 }
 
+// skips semicolons the lexer inserted at line ends, for constructs such as
+// dict literals that may span lines
+func (p *Parser) skipNewlines() {
+	for p.peekTokenIs(token.Semicolon) && p.peekToken.Literal == "\n" {
+		p.nextToken()
+	}
+}
+
 func (p *Parser) expectPeek(t token.TokenType) bool {
 	if p.peekTokenIs(t) {
 		p.nextToken()
@@ -20,9 +20,12 @@ replace crabscript.rs/code => ../code
 
 replace crabscript.rs/vm => ../vm
 
+replace crabscript.rs/module => ../module
+
 require (
 	crabscript.rs/compiler v0.0.0-00010101000000-000000000000
 	crabscript.rs/lexer v0.0.0-00010101000000-000000000000
+	crabscript.rs/module v0.0.0-00010101000000-000000000000
 	crabscript.rs/object v0.0.0-00010101000000-000000000000
 	crabscript.rs/parser v0.0.0-00010101000000-000000000000
 	crabscript.rs/vm v0.0.0-00010101000000-000000000000
@@ -4,9 +4,11 @@ import (
 	"bufio"
 	"crabscript.rs/compiler"
 	"crabscript.rs/lexer"
+	"crabscript.rs/module"
 	"crabscript.rs/object"
 	"crabscript.rs/parser"
 	"crabscript.rs/vm"
+	"errors"
 	"fmt"
 	"io"
 )
@@ -20,6 +22,7 @@ func Start(in io.Reader, out io.Writer) {
 	constants := []object.Object{}
 	globals := make([]object.Object, vm.GlobalSize)
 	symbolTable := compiler.NewSymbolTable()
+	loader := module.NewLoader()
 
 	for {
 		fmt.Printf(Prompt)
@@ -34,21 +37,31 @@ func Start(in io.Reader, out io.Writer) {
 		p := parser.New(l)
 
 		program := p.ParseProgram()
-		if len(p.Errors()) > 0 {
-			printParserErrors(out, p.Errors())
+		if diagnostics := p.Diagnostics(); len(diagnostics) > 0 {
+			printParserErrors(out, diagnostics)
 			continue
 		}
 
 		comp := compiler.NewWithState(symbolTable, constants)
+		comp.SetLoader(loader)
 		err := comp.Compile(program)
 		if err != nil {
-			fmt.Fprintf(out, "Compilation failed: %s", err)
+			fmt.Fprintf(out, "Compilation failed: %s\n", err)
+			continue
 		}
 
 		machine := vm.NewWithGblStore(comp.Bytecode(), globals)
 		err = machine.Run()
+		if _, ok := err.(*object.Exit); ok {
+			return
+		}
 		if err != nil {
-			fmt.Fprintf(out, "Bytecode failed to execute: %s", err)
+			var rtErr *vm.RuntimeError
+			if errors.As(err, &rtErr) {
+				io.WriteString(out, rtErr.StackTrace())
+			} else {
+				fmt.Fprintf(out, "Bytecode failed to execute: %s\n", err)
+			}
 			continue
 		}
 		lastPopped := machine.LastPoppedStackElem()
@@ -57,13 +70,9 @@ func Start(in io.Reader, out io.Writer) {
 	}
 }
 
-func printParserErrors(out io.Writer, errors []string) {
-	for _, err := range errors {
-		_, ok := io.WriteString(out, err)
-		if ok != nil {
-			panic("Cannot print error!")
-		}
-		_, ok = io.WriteString(out, "\n")
+func printParserErrors(out io.Writer, diagnostics []parser.Diagnostic) {
+	for _, d := range diagnostics {
+		_, ok := io.WriteString(out, d.Format())
 		if ok != nil {
 			panic("Cannot print error!")
 		}
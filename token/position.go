@@ -0,0 +1,33 @@
+package token
+
+import "fmt"
+
+// Position describes a location in a source file.
+// Line and Column are 1-based, Column counts runes rather than bytes.
+type Position struct {
+	Filename string
+	Offset   int // byte offset into the source
+	Line     int
+	Column   int
+}
+
+// IsValid reports whether the position has been set by the lexer
+func (p Position) IsValid() bool {
+	return p.Line > 0
+}
+
+// String formats the position as file:line:col, or line:col when there is
+// no file name
+func (p Position) String() string {
+	if !p.IsValid() {
+		if p.Filename != "" {
+			return p.Filename
+		}
+		return "-"
+	}
+
+	if p.Filename == "" {
+		return fmt.Sprintf("%d:%d", p.Line, p.Column)
+	}
+	return fmt.Sprintf("%s:%d:%d", p.Filename, p.Line, p.Column)
+}
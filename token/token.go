@@ -5,17 +5,26 @@ type TokenType string
 type Token struct {
 	Type    TokenType
 	Literal string
+	Pos     Position // position of the first char of the token
+	End     Position // position immediately after the token
 }
 
 const (
 	Illegal = "Illegal" // unknown token
 	Eof     = "Eof"     // end of file
+	Comment = "Comment" // only reported through Lexer.Comments
 
 	// identifiers and literals
 	Ident  = "Ident" // named var/fns
 	Int    = "Int"
+	Float  = "Float"
 	String = "String"
 
+	// interpolated strings, "a ${x} b" is StringPart("a "), the tokens of x
+	// and StringEnd(" b"), with a StringPart before each further `${`
+	StringPart = "StringPart"
+	StringEnd  = "StringEnd"
+
 	// Ops
 	Assign   = "="
 	Plus     = "+"
@@ -23,15 +32,45 @@ const (
 	Bang     = "!"
 	Asterisk = "*"
 	Slash    = "/"
+	Percent  = "%"
+	Power    = "**"
 	Lt       = "<"
 	Gt       = ">"
+	Le       = "<="
+	Ge       = ">="
 	Eq       = "=="
 	NEq      = "!="
+	And      = "&&"
+	Or       = "||"
+	Pipe     = "|>"
+
+	// Bitwise ops
+	BitAnd = "&"
+	BitOr  = "|"
+	BitXor = "^"
+	Shl    = "<<"
+	Shr    = ">>"
+
+	// Assignment ops
+	PlusAssign     = "+="
+	MinusAssign    = "-="
+	AsteriskAssign = "*="
+	SlashAssign    = "/="
+	PercentAssign  = "%="
+	PowerAssign    = "**="
+	BitAndAssign   = "&="
+	BitOrAssign    = "|="
+	BitXorAssign   = "^="
+	ShlAssign      = "<<="
+	ShrAssign      = ">>="
 
 	// Delims
-	Comma     = "," // var delimiter
-	Semicolon = ";" // line end (along with \n)
-	Colon     = ":" // separator for maps
+	Comma     = ","   // var delimiter
+	Semicolon = ";"   // line end (also inserted by the lexer at newlines)
+	Colon     = ":"   // separator for maps
+	Ellipsis  = "..." // rest of a destructured array
+	Arrow     = "=>"  // separates a match pattern from its result
+	Dot       = "."   // reads an export of a module, `m.name`
 
 	// Scopes
 	LParen   = "("
@@ -49,16 +88,42 @@ const (
 	True     = "True"
 	False    = "False"
 	Return   = "Return"
+	While    = "While"
+	For      = "For"
+	In       = "In"
+	Break    = "Break"
+	Continue = "Continue"
+	Match    = "Match"
+	Try      = "Try"
+	Catch    = "Catch"
+	Finally  = "Finally"
+	Throw    = "Throw"
+	Import   = "Import"
+	As       = "As"
+	Export   = "Export"
 )
 
 var keywords = map[string]TokenType{
-	"fn":     Function,
-	"let":    Let,
-	"if":     If,
-	"else":   Else,
-	"true":   True,
-	"false":  False,
-	"return": Return,
+	"fn":       Function,
+	"let":      Let,
+	"if":       If,
+	"else":     Else,
+	"true":     True,
+	"false":    False,
+	"return":   Return,
+	"while":    While,
+	"for":      For,
+	"in":       In,
+	"break":    Break,
+	"continue": Continue,
+	"match":    Match,
+	"try":      Try,
+	"catch":    Catch,
+	"finally":  Finally,
+	"throw":    Throw,
+	"import":   Import,
+	"as":       As,
+	"export":   Export,
 }
 
 func LookupIdent(ident string) TokenType {
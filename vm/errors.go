@@ -0,0 +1,90 @@
+package vm
+
+import (
+	"bytes"
+	"fmt"
+
+	"crabscript.rs/code"
+	"crabscript.rs/token"
+)
+
+// RuntimeError is returned by Run when an instruction fails to execute
+type RuntimeError struct {
+	Op    code.Opcode    // instruction that failed
+	Fn    string         // function the instruction belongs to
+	Pos   token.Position // source position of the instruction, if known
+	Err   error          // underlying cause
+	Stack []StackFrame   // active calls when the error happened, innermost first
+}
+
+// StackFrame describes one call active when a RuntimeError happened
+type StackFrame struct {
+	Fn     string         // name of the function
+	Offset int            // offset of the executing instruction in the function
+	Pos    token.Position // source position of the instruction, if known
+}
+
+func (e *RuntimeError) Error() string {
+	msg := fmt.Sprintf("%s (%s in %s)", e.Err, opName(e.Op), e.Fn)
+	if e.Pos.IsValid() {
+		return fmt.Sprintf("%s: %s", e.Pos, msg)
+	}
+	return msg
+}
+
+func (e *RuntimeError) Unwrap() error {
+	return e.Err
+}
+
+// StackTrace formats the error and its stack the way go prints a panic:
+//
+//	runtime error: division by zero
+//
+//	div(...)
+//		script.crab:2:14 +0x5
+//	<main>(...)
+//		script.crab:4:4 +0x1c
+func (e *RuntimeError) StackTrace() string {
+	var out bytes.Buffer
+
+	fmt.Fprintf(&out, "runtime error: %s\n", e.Err)
+	if len(e.Stack) > 0 {
+		out.WriteString("\n")
+	}
+	for _, f := range e.Stack {
+		fmt.Fprintf(&out, "%s(...)\n\t%s +0x%x\n", f.Fn, f.Pos, f.Offset)
+	}
+
+	return out.String()
+}
+
+// wraps err with the state of the frames executing when it happened
+func (vm *Vm) runtimeError(err error) *RuntimeError {
+	rtErr := &RuntimeError{Err: err, Fn: "<main>"}
+
+	for i := vm.frameIndex - 1; i >= 0; i-- {
+		frame := vm.frames[i]
+		rtErr.Stack = append(rtErr.Stack, StackFrame{
+			Fn:     frame.fn.Fn.DisplayName(),
+			Offset: frame.opStart(),
+			Pos:    frame.Position(),
+		})
+	}
+
+	if vm.frameIndex < 1 {
+		return rtErr
+	}
+
+	frame := vm.currentFrame()
+	rtErr.Fn = frame.fn.Fn.DisplayName()
+
+	ins := frame.Instructions()
+	if frame.ip >= 0 && frame.ip < len(ins) {
+		// ip may have moved past the opcode onto its operands
+		ip := frame.opStart()
+		rtErr.Op = code.Opcode(ins[ip])
+	}
+	rtErr.Pos = frame.Position()
+
+	return rtErr
+}
@@ -7,6 +7,7 @@ replace (
 	crabscript.rs/code => ../code
 	crabscript.rs/compiler => ../compiler
 	crabscript.rs/lexer => ../lexer
+	crabscript.rs/module => ../module
 	crabscript.rs/object => ../object
 	crabscript.rs/parser => ../parser
 	crabscript.rs/token => ../token
@@ -21,4 +22,6 @@ require (
 	crabscript.rs/parser v0.0.0-00010101000000-000000000000
 )
 
-require crabscript.rs/token v0.0.0-00010101000000-000000000000 // indirect
+require crabscript.rs/token v0.0.0-00010101000000-000000000000
+
+require crabscript.rs/module v0.0.0-00010101000000-000000000000 // indirect
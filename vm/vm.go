@@ -4,7 +4,11 @@ import (
 	"crabscript.rs/code"
 	"crabscript.rs/compiler"
 	"crabscript.rs/object"
+	"crabscript.rs/token"
+	"errors"
 	"fmt"
+	"math"
+	"strings"
 )
 
 const StackSize = 2048
@@ -37,6 +41,13 @@ type Frame struct {
 	fn      *object.Closure // function to exec
 	ip      int             // instruction pointer for the frame
 	basePtr int             // ip at the time of child scope execution for the parent scope
+	tries   []tryState      // tries entered by the call, by slot
+}
+
+// state of a try entered by a frame
+type tryState struct {
+	sp      int           // stack pointer when the try was entered
+	pending *RuntimeError // error that ran the finally block, raised again at its end
 }
 
 func NewFrame(fn *object.Closure, basePtr int) *Frame {
@@ -47,8 +58,45 @@ func (f *Frame) Instructions() code.Instructions {
 	return f.fn.Fn.Instructions
 }
 
+// Position returns the source position of the instruction being executed
+func (f *Frame) Position() token.Position {
+	return f.fn.Fn.SourceMap.Lookup(f.ip)
+}
+
+// lowest stack slot of the values pushed by the frame, above its locals
+func (f *Frame) floor() int {
+	return f.basePtr + f.fn.Fn.LocalVarCount
+}
+
+// offset of the opcode of the instruction being executed, ip may point
+// into its operands
+func (f *Frame) opStart() int {
+	ins := f.Instructions()
+	for i := 0; i < len(ins); {
+		def, err := code.Lookup(ins[i])
+		if err != nil {
+			break
+		}
+
+		next := i + 1
+		for _, w := range def.OperandWidths {
+			next += w
+		}
+		if f.ip < next {
+			return i
+		}
+		i = next
+	}
+	return f.ip
+}
+
 func New(bytecode *compiler.Bytecode) *Vm {
-	mainFn := &object.CompFn{Instructions: bytecode.Instructions}
+	mainFn := &object.CompFn{
+		Instructions: bytecode.Instructions,
+		SourceMap:    bytecode.SourceMap,
+		Handlers:     bytecode.Handlers,
+		Name:         "<main>",
+	}
 	mainCsr := &object.Closure{Fn: mainFn}
 	mainFrame := NewFrame(mainCsr, 0) // bring the top level into a frame
 
@@ -82,7 +130,69 @@ func (vm *Vm) StackTop() object.Object {
 }
 
 // executes bytecode loaded
+// errors are returned as a *RuntimeError, including those of malformed
+// bytecode with truncated operands or popping more than the stack holds,
+// and a call of exit stops the vm, which returns its *object.Exit
 func (vm *Vm) Run() error {
+	for {
+		err := vm.run()
+		if err == nil {
+			return nil
+		}
+		if exit, ok := err.(*object.Exit); ok {
+			return exit
+		}
+
+		// finally blocks raise the error that ran them again as it was
+		rtErr, ok := err.(*RuntimeError)
+		if !ok {
+			rtErr = vm.runtimeError(err)
+		}
+		if !vm.unwind(rtErr) {
+			return rtErr
+		}
+	}
+}
+
+// unwinds the frames to the innermost handler covering the instruction
+// that raised err, reporting whether there was one. A catch block is given
+// the error as a dict, a finally block raises it again at its end.
+func (vm *Vm) unwind(err *RuntimeError) bool {
+	for i := vm.frameIndex - 1; i >= 0; i-- {
+		frame := vm.frames[i]
+		h, ok := frame.fn.Fn.Handlers.Lookup(frame.opStart())
+		if !ok {
+			continue
+		}
+		if h.Slot >= len(frame.tries) {
+			return false
+		}
+
+		vm.frameIndex = i + 1
+		vm.sp = frame.tries[h.Slot].sp
+		frame.ip = h.Target - 1
+
+		if h.Finally {
+			frame.tries[h.Slot].pending = err
+			return true
+		}
+
+		// the calls from where the error was raised up to this frame
+		var trace []token.Position
+		for _, f := range err.Stack[:len(err.Stack)-i] {
+			trace = append(trace, f.Pos)
+		}
+
+		var errObj *object.Error
+		if !errors.As(err.Err, &errObj) {
+			errObj = &object.Error{Message: err.Err.Error()}
+		}
+		return vm.push(errObj.Dict(trace)) == nil
+	}
+	return false
+}
+
+func (vm *Vm) run() error {
 	var ip int
 	var ins code.Instructions
 	var op code.Opcode
@@ -94,18 +204,29 @@ func (vm *Vm) Run() error {
 
 		op = code.Opcode(ins[ip])
 
+		if ip+code.OperandBytes(op) >= len(ins) {
+			return fmt.Errorf("truncated operands of %s", opName(op))
+		}
+		if n := popped(op, ins[ip+1:]); n > vm.sp-vm.currentFrame().floor() {
+			return fmt.Errorf("stack underflow: %s takes %d values, stack holds %d", opName(op), n, vm.sp-vm.currentFrame().floor())
+		}
+
 		// decoding operations
 		switch op {
 		case code.OpConst:
 			constIdx := code.ReadUint16(ins[ip+1:])
 			vm.currentFrame().ip += 2
+			if int(constIdx) >= len(vm.constants) {
+				return fmt.Errorf("constant %d out of range", constIdx)
+			}
 			// putting new const onto stack
 			err := vm.push(vm.constants[constIdx])
 			if err != nil {
 				return err
 			}
 
-		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv:
+		case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+			code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr:
 			if err := vm.execBinaryOp(op); err != nil {
 				return err
 			}
@@ -122,7 +243,7 @@ func (vm *Vm) Run() error {
 				return err
 			}
 
-		case code.OpNe, code.OpEq, code.OpGt:
+		case code.OpNe, code.OpEq, code.OpGt, code.OpLt, code.OpLe, code.OpGe:
 			if err := vm.execComparison(op); err != nil {
 				return err
 			}
@@ -153,6 +274,19 @@ func (vm *Vm) Run() error {
 				vm.currentFrame().ip = pos - 1
 			}
 
+		case code.OpJmpSet:
+			lclIdx := int(code.ReadUint16(ins[ip+1:]))
+			pos := int(code.ReadUint16(ins[ip+3:]))
+			vm.currentFrame().ip += 4
+
+			slot, err := vm.local(lclIdx)
+			if err != nil {
+				return err
+			}
+			if *slot != nil {
+				vm.currentFrame().ip = pos - 1
+			}
+
 		case code.OpNull:
 			err := vm.push(Null)
 			if err != nil {
@@ -171,11 +305,30 @@ func (vm *Vm) Run() error {
 			globalIndex := code.ReadUint16(ins[ip+1:])
 			vm.currentFrame().ip += 2
 
-			err := vm.push(vm.globals[globalIndex])
+			global := vm.globals[globalIndex]
+			if global == nil {
+				return fmt.Errorf("global %d is not defined", globalIndex)
+			}
+
+			err := vm.push(global)
 			if err != nil {
 				return err
 			}
 
+		case code.OpGetExp:
+			globalIndex := code.ReadUint16(ins[ip+1:])
+			nameIdx := int(code.ReadUint16(ins[ip+3:]))
+			vm.currentFrame().ip += 4
+
+			global := vm.globals[globalIndex]
+			if global == nil {
+				return vm.exportError(nameIdx)
+			}
+
+			if err := vm.push(global); err != nil {
+				return err
+			}
+
 			// initialising array
 		case code.OpArray:
 			numElem := int(code.ReadUint16(ins[ip+1:]))
@@ -188,6 +341,17 @@ func (vm *Vm) Run() error {
 				return err
 			}
 
+		case code.OpConcat:
+			numElem := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			str := vm.buildString(vm.sp-numElem, vm.sp)
+			vm.sp = vm.sp - numElem
+
+			if err := vm.push(str); err != nil {
+				return err
+			}
+
 		case code.OpDict:
 			// get operand
 			numElem := int(code.ReadUint16(ins[ip+1:]))
@@ -212,6 +376,97 @@ func (vm *Vm) Run() error {
 				return err
 			}
 
+		case code.OpSlice:
+			step := vm.pop()
+			end := vm.pop()
+			start := vm.pop()
+			left := vm.pop()
+			sliced, err := object.Slice(left, start, end, step)
+			if err != nil {
+				return err
+			}
+			if err := vm.push(sliced); err != nil {
+				return err
+			}
+
+		case code.OpUnpkArr:
+			numElem := int(code.ReadUint16(ins[ip+1:]))
+			rest := code.ReadUint8(ins[ip+3:]) == 1
+			vm.currentFrame().ip += 3
+
+			if err := vm.unpackArray(vm.pop(), numElem, rest); err != nil {
+				return err
+			}
+
+		case code.OpUnpkDct:
+			numKeys := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			keys := make([]object.Object, numKeys)
+			copy(keys, vm.stack[vm.sp-numKeys:vm.sp])
+			vm.sp = vm.sp - numKeys
+
+			if err := vm.unpackDict(vm.pop(), keys); err != nil {
+				return err
+			}
+
+		case code.OpMatchEq:
+			right := vm.pop()
+			left := vm.pop()
+			if err := vm.push(boolToObject(object.Equal(left, right))); err != nil {
+				return err
+			}
+
+		case code.OpIsArr:
+			numElem := int(code.ReadUint16(ins[ip+1:]))
+			atLeast := code.ReadUint8(ins[ip+3:]) == 1
+			vm.currentFrame().ip += 3
+
+			arr, ok := vm.pop().(*object.Array)
+			matched := ok && (len(arr.Elements) == numElem || atLeast && len(arr.Elements) > numElem)
+			if err := vm.push(boolToObject(matched)); err != nil {
+				return err
+			}
+
+		case code.OpHasKeys:
+			numKeys := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			keys := vm.stack[vm.sp-numKeys : vm.sp]
+			dict := vm.stack[vm.sp-numKeys-1]
+			matched := hasKeys(dict, keys)
+			vm.sp = vm.sp - numKeys - 1
+			if err := vm.push(boolToObject(matched)); err != nil {
+				return err
+			}
+
+		case code.OpNoMatch:
+			return fmt.Errorf("no match arm for %s", vm.pop().Inspect())
+
+		case code.OpTry:
+			slot := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			frame := vm.currentFrame()
+			for len(frame.tries) <= slot {
+				frame.tries = append(frame.tries, tryState{})
+			}
+			frame.tries[slot] = tryState{sp: vm.sp}
+
+		case code.OpThrow:
+			return object.Thrown(vm.pop())
+
+		case code.OpEndFin:
+			slot := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			frame := vm.currentFrame()
+			if slot < len(frame.tries) && frame.tries[slot].pending != nil {
+				err := frame.tries[slot].pending
+				frame.tries[slot].pending = nil
+				return err
+			}
+
 		case code.OpCall:
 			numArgs := int(code.ReadUint8(ins[ip+1:]))
 			vm.currentFrame().ip++ // skipping num of args for now
@@ -219,10 +474,24 @@ func (vm *Vm) Run() error {
 				return err
 			}
 
+		case code.OpCallKw:
+			numArgs := int(code.ReadUint8(ins[ip+1:]))
+			numNamed := int(code.ReadUint8(ins[ip+2:]))
+			vm.currentFrame().ip += 2
+			if err := vm.execCallKw(numArgs, numNamed); err != nil {
+				return err
+			}
+
 		case code.OpRetVal:
 			// retrieve val from fn
 			retVal := vm.pop()
 
+			// returning from the top level ends the program
+			if vm.frameIndex == 1 {
+				vm.stack[vm.sp] = retVal
+				return nil
+			}
+
 			frame := vm.popFrame()
 			vm.sp = frame.basePtr - 1
 
@@ -231,6 +500,11 @@ func (vm *Vm) Run() error {
 			}
 
 		case code.OpRet:
+			if vm.frameIndex == 1 {
+				vm.stack[vm.sp] = Null
+				return nil
+			}
+
 			// remove frame from stack
 			frame := vm.popFrame()
 			vm.sp = frame.basePtr - 1
@@ -243,25 +517,42 @@ func (vm *Vm) Run() error {
 			lclIdx := code.ReadUint16(ins[ip+1:])
 			vm.currentFrame().ip += 2
 
-			basePtr := vm.currentFrame().basePtr
-
-			err := vm.push(vm.stack[basePtr+int(lclIdx)])
+			slot, err := vm.local(int(lclIdx))
 			if err != nil {
 				return err
 			}
 
+			// locals captured by a closure live in a cell
+			local := *slot
+			if cell, ok := local.(*object.Cell); ok {
+				local = cell.Value
+			}
+
+			if err := vm.push(local); err != nil {
+				return err
+			}
+
 		case code.OpSetLcl:
 			lclIdx := code.ReadUint16(ins[ip+1:])
 			vm.currentFrame().ip += 2
 
-			basePtr := vm.currentFrame().basePtr
-
-			vm.stack[basePtr+int(lclIdx)] = vm.pop()
+			slot, err := vm.local(int(lclIdx))
+			if err != nil {
+				return err
+			}
+			if cell, ok := (*slot).(*object.Cell); ok {
+				cell.Value = vm.pop()
+			} else {
+				*slot = vm.pop()
+			}
 
 		case code.OpGetBIn:
 			bindex := code.ReadUint8(ins[ip+1:])
 			vm.currentFrame().ip += 1
 
+			if int(bindex) >= len(object.Builtins) {
+				return fmt.Errorf("builtin %d not defined", bindex)
+			}
 			def := object.Builtins[bindex]
 			if err := vm.push(def.Builtin); err != nil {
 				return err
@@ -281,10 +572,109 @@ func (vm *Vm) Run() error {
 			vm.currentFrame().ip += 1
 
 			curCsr := vm.currentFrame().fn
+			if int(fIdx) >= len(curCsr.Free) {
+				return fmt.Errorf("free variable %d out of range", fIdx)
+			}
+
+			if err := vm.push(curCsr.Free[fIdx].Value); err != nil {
+				return err
+			}
+
+		case code.OpSetFree:
+			fIdx := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			curCsr := vm.currentFrame().fn
+			if int(fIdx) >= len(curCsr.Free) {
+				return fmt.Errorf("free variable %d out of range", fIdx)
+			}
+			curCsr.Free[fIdx].Value = vm.pop()
+
+		case code.OpBoxLcl:
+			lclIdx := code.ReadUint16(ins[ip+1:])
+			vm.currentFrame().ip += 2
+
+			// move the local into a cell the first time it is captured
+			slot, err := vm.local(int(lclIdx))
+			if err != nil {
+				return err
+			}
+			cell, ok := (*slot).(*object.Cell)
+			if !ok {
+				cell = &object.Cell{Value: *slot}
+				*slot = cell
+			}
+
+			if err := vm.push(cell); err != nil {
+				return err
+			}
+
+		case code.OpBoxFree:
+			fIdx := code.ReadUint8(ins[ip+1:])
+			vm.currentFrame().ip += 1
+
+			curCsr := vm.currentFrame().fn
+			if int(fIdx) >= len(curCsr.Free) {
+				return fmt.Errorf("free variable %d out of range", fIdx)
+			}
 
 			if err := vm.push(curCsr.Free[fIdx]); err != nil {
 				return err
 			}
+
+		case code.OpSetIdx:
+			val := vm.pop()
+			index := vm.pop()
+			left := vm.pop()
+			if err := vm.execSetIdx(left, index, val); err != nil {
+				return err
+			}
+
+		case code.OpIter:
+			iterable := vm.pop()
+			iter, ok := object.NewIterator(iterable)
+			if !ok {
+				return fmt.Errorf("cannot iterate over %s", iterable.Type())
+			}
+			if err := vm.push(iter); err != nil {
+				return err
+			}
+
+		case code.OpNext:
+			pos := int(code.ReadUint16(ins[ip+1:]))
+			vm.currentFrame().ip += 2
+
+			iter, ok := vm.StackTop().(*object.Iterator)
+			if !ok {
+				return fmt.Errorf("not an iterator: %v", vm.StackTop())
+			}
+
+			elem, ok := iter.Next()
+			if !ok {
+				vm.currentFrame().ip = pos - 1
+				continue
+			}
+			if err := vm.push(elem); err != nil {
+				return err
+			}
+
+		case code.OpDup:
+			n := int(code.ReadUint8(ins[ip+1:]))
+			vm.currentFrame().ip += 1
+
+			start := vm.sp - n
+			for i := 0; i < n; i++ {
+				if err := vm.push(vm.stack[start+i]); err != nil {
+					return err
+				}
+			}
+
+		case code.OpCurCsr:
+			if err := vm.push(vm.currentFrame().fn); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unknown opcode %d", op)
 		}
 	}
 
@@ -300,19 +690,90 @@ func (vm *Vm) execCall(numArgs int) error {
 		return vm.callBIn(fn, numArgs)
 
 	default:
-		return fmt.Errorf("not a function or builtin")
+		return fmt.Errorf("not a function or builtin: %s", fn.Type())
 
 	}
 }
 
+// calls fn with positional arguments followed by pairs of a name and a
+// named argument on the stack
+func (vm *Vm) execCallKw(numArgs int, numNamed int) error {
+	base := vm.sp - numArgs - 2*numNamed
+	fn, ok := vm.stack[base-1].(*object.Closure)
+	if !ok {
+		if _, ok := vm.stack[base-1].(*object.Builtin); ok {
+			return fmt.Errorf("builtins do not take named arguments")
+		}
+		return fmt.Errorf("not a function or builtin: %s", vm.stack[base-1].Type())
+	}
+
+	names := make([]string, numNamed)
+	named := make([]object.Object, numNamed)
+	for i := range names {
+		name, ok := vm.stack[base+numArgs+2*i].(*object.String)
+		if !ok {
+			return fmt.Errorf("argument name must be String, got %s", vm.stack[base+numArgs+2*i].Type())
+		}
+		names[i] = name.Value
+		named[i] = vm.stack[base+numArgs+2*i+1]
+	}
+
+	numArgs, err := vm.bindArgs(fn, base, numArgs, names, named)
+	if err != nil {
+		return err
+	}
+	return vm.enterFn(fn, numArgs)
+}
+
 func (vm *Vm) callFn(fn *object.Closure, numArgs int) error {
-	if numArgs != fn.Fn.ParamCount {
-		return fmt.Errorf("wrong number of arguments: want %d got %d", fn.Fn.ParamCount, numArgs)
+	// fast path for calls that give exactly the params
+	if numArgs != fn.Fn.ParamCount || fn.Fn.Signature.Variadic {
+		var err error
+		numArgs, err = vm.bindArgs(fn, vm.sp-numArgs, numArgs, nil, nil)
+		if err != nil {
+			return err
+		}
+	}
+	return vm.enterFn(fn, numArgs)
+}
+
+// replaces the arguments of a call starting at base with the values of the
+// params of fn, leaving those that take their default nil. Returns the
+// number of values.
+func (vm *Vm) bindArgs(fn *object.Closure, base int, numArgs int, names []string, named []object.Object) (int, error) {
+	args := make([]object.Object, numArgs)
+	copy(args, vm.stack[base:base+numArgs])
+
+	values, err := fn.Fn.Signature.Bind(args, names, named)
+	if err != nil {
+		return 0, err
+	}
+	if base+len(values) >= StackSize {
+		return 0, fmt.Errorf("stack overflow")
+	}
+
+	copy(vm.stack[base:], values)
+	vm.sp = base + len(values)
+	return len(values), nil
+}
+
+// pushes a frame for fn, whose numArgs params are at the top of the stack
+func (vm *Vm) enterFn(fn *object.Closure, numArgs int) error {
+	if vm.frameIndex >= MaxFrames {
+		return fmt.Errorf("stack overflow: more than %d nested calls", MaxFrames)
 	}
+
 	frame := NewFrame(fn, vm.sp-numArgs) // set up new frame at stack pointer
+	if frame.basePtr+fn.Fn.LocalVarCount >= StackSize {
+		return fmt.Errorf("stack overflow")
+	}
 	vm.pushFrame(frame)
 
-	// allocating space for the fn's local bindings on the stack
+	// allocating space for the fn's local bindings on the stack, clearing
+	// cells left behind by earlier calls so they aren't assigned through
+	for i := frame.basePtr + numArgs; i < frame.basePtr+fn.Fn.LocalVarCount; i++ {
+		vm.stack[i] = nil
+	}
 	vm.sp = frame.basePtr + fn.Fn.LocalVarCount
 	return nil
 }
@@ -342,6 +803,39 @@ func (vm *Vm) push(o object.Object) error {
 	return nil
 }
 
+// popped returns the number of values the instruction op with the given
+// operands takes off the stack, which run checks the stack holds
+func popped(op code.Opcode, operands []byte) int {
+	switch op {
+	case code.OpPop, code.OpJmpNt, code.OpSetGbl, code.OpSetLcl, code.OpSetFree,
+		code.OpNeg, code.OpBang, code.OpIter, code.OpNext, code.OpUnpkArr,
+		code.OpIsArr, code.OpNoMatch, code.OpThrow, code.OpRetVal:
+		return 1
+	case code.OpAdd, code.OpSub, code.OpMul, code.OpDiv, code.OpMod, code.OpPow,
+		code.OpBitAnd, code.OpBitOr, code.OpBitXor, code.OpShl, code.OpShr,
+		code.OpEq, code.OpNe, code.OpGt, code.OpLt, code.OpLe, code.OpGe,
+		code.OpIdx, code.OpMatchEq:
+		return 2
+	case code.OpSetIdx:
+		return 3
+	case code.OpSlice:
+		return 4
+	case code.OpArray, code.OpConcat, code.OpDict:
+		return int(code.ReadUint16(operands))
+	case code.OpUnpkDct, code.OpHasKeys:
+		return int(code.ReadUint16(operands)) + 1
+	case code.OpDup:
+		return int(code.ReadUint8(operands))
+	case code.OpCall:
+		return int(code.ReadUint8(operands)) + 1
+	case code.OpCallKw:
+		return int(code.ReadUint8(operands)) + 2*int(code.ReadUint8(operands[1:])) + 1
+	case code.OpClosure:
+		return int(code.ReadUint8(operands[2:]))
+	}
+	return 0
+}
+
 func (vm *Vm) pop() object.Object {
 	o := vm.stack[vm.sp-1]
 	vm.sp--
@@ -363,6 +857,9 @@ func (vm *Vm) execBinaryOp(op code.Opcode) error {
 	case leftType == object.IntegerObj && rightType == object.IntegerObj:
 		return vm.execBinaryIntOp(op, left.(*object.Integer), right.(*object.Integer))
 
+	case isNumber(left) && isNumber(right):
+		return vm.execBinaryFloatOp(op, toFloat(left), toFloat(right))
+
 	case leftType == object.StringObj && rightType == object.StringObj:
 		return vm.execBinaryStringOp(op, left.(*object.String), right.(*object.String))
 	default:
@@ -372,7 +869,7 @@ func (vm *Vm) execBinaryOp(op code.Opcode) error {
 
 func (vm *Vm) execBinaryStringOp(op code.Opcode, left *object.String, right *object.String) error {
 	if op != code.OpAdd {
-		return fmt.Errorf("unknown string operator: %d", op)
+		return fmt.Errorf("unknown string operator: %s", opName(op))
 	}
 
 	return vm.push(&object.String{Value: left.Value + right.Value})
@@ -391,19 +888,96 @@ func (vm *Vm) execBinaryIntOp(op code.Opcode, left *object.Integer, right *objec
 		err = vm.push(&object.Integer{Value: left.Value * right.Value})
 
 	case code.OpDiv:
+		if right.Value == 0 {
+			return fmt.Errorf("division by zero")
+		}
 		err = vm.push(&object.Integer{Value: left.Value / right.Value})
+
+	case code.OpMod:
+		if right.Value == 0 {
+			return fmt.Errorf("division by zero")
+		}
+		err = vm.push(&object.Integer{Value: left.Value % right.Value})
+
+	case code.OpPow:
+		err = vm.push(object.IntPow(left.Value, right.Value))
+
+	case code.OpBitAnd:
+		err = vm.push(&object.Integer{Value: left.Value & right.Value})
+
+	case code.OpBitOr:
+		err = vm.push(&object.Integer{Value: left.Value | right.Value})
+
+	case code.OpBitXor:
+		err = vm.push(&object.Integer{Value: left.Value ^ right.Value})
+
+	case code.OpShl, code.OpShr:
+		if right.Value < 0 {
+			return fmt.Errorf("negative shift count: %d", right.Value)
+		}
+		if op == code.OpShl {
+			err = vm.push(&object.Integer{Value: left.Value << right.Value})
+		} else {
+			err = vm.push(&object.Integer{Value: left.Value >> right.Value})
+		}
 	default:
-		return fmt.Errorf("unknown integer operator: %d", op)
+		return fmt.Errorf("unknown integer operator: %s", opName(op))
 	}
 
 	return err
 }
 
+func (vm *Vm) execBinaryFloatOp(op code.Opcode, left float64, right float64) error {
+	switch op {
+	case code.OpAdd:
+		return vm.push(&object.Float{Value: left + right})
+	case code.OpSub:
+		return vm.push(&object.Float{Value: left - right})
+	case code.OpMul:
+		return vm.push(&object.Float{Value: left * right})
+	case code.OpDiv:
+		return vm.push(&object.Float{Value: left / right})
+	case code.OpMod:
+		return vm.push(&object.Float{Value: math.Mod(left, right)})
+	case code.OpPow:
+		return vm.push(&object.Float{Value: math.Pow(left, right)})
+	default:
+		return fmt.Errorf("unknown float operator: %s", opName(op))
+	}
+}
+
+// opName returns the name of op for error messages
+func opName(op code.Opcode) string {
+	if def, err := code.Lookup(byte(op)); err == nil {
+		return def.Name
+	}
+	return fmt.Sprintf("opcode %d", op)
+}
+
+func isNumber(obj object.Object) bool {
+	return obj.Type() == object.IntegerObj || obj.Type() == object.FloatObj
+}
+
+// widens ints to floats for mixed arithmetic
+func toFloat(obj object.Object) float64 {
+	switch obj := obj.(type) {
+	case *object.Integer:
+		return float64(obj.Value)
+	case *object.Float:
+		return obj.Value
+	}
+	return 0
+}
+
 func (vm *Vm) execComparison(op code.Opcode) error {
 	right := vm.pop()
 	left := vm.pop()
 
-	if left.Type() == object.IntegerObj || right.Type() == object.IntegerObj {
+	if (left.Type() == object.FloatObj || right.Type() == object.FloatObj) && isNumber(left) && isNumber(right) {
+		return vm.execFloatComparison(op, toFloat(left), toFloat(right))
+	}
+
+	if left.Type() == object.IntegerObj && right.Type() == object.IntegerObj {
 		return vm.execIntComparison(op, left, right)
 	}
 
@@ -413,7 +987,7 @@ func (vm *Vm) execComparison(op code.Opcode) error {
 	case code.OpNe:
 		return vm.push(boolToObject(left != right))
 	default:
-		return fmt.Errorf("unknown operator: %d", op)
+		return fmt.Errorf("unsupported types for comparison: %s %s", left.Type(), right.Type())
 	}
 }
 
@@ -426,16 +1000,45 @@ func (vm *Vm) execIntComparison(op code.Opcode, left object.Object, right object
 		return vm.push(boolToObject(leftVal == rightVal))
 	case code.OpNe:
 		return vm.push(boolToObject(leftVal != rightVal))
-	case code.OpGt: // less than is converted to Gt in compiler
+	case code.OpGt:
 		return vm.push(boolToObject(leftVal > rightVal))
+	case code.OpLt:
+		return vm.push(boolToObject(leftVal < rightVal))
+	case code.OpGe:
+		return vm.push(boolToObject(leftVal >= rightVal))
+	case code.OpLe:
+		return vm.push(boolToObject(leftVal <= rightVal))
 	default:
-		return fmt.Errorf("unknown operator: %d", op)
+		return fmt.Errorf("unknown operator: %s", opName(op))
+	}
+}
+
+func (vm *Vm) execFloatComparison(op code.Opcode, left float64, right float64) error {
+	switch op {
+	case code.OpEq:
+		return vm.push(boolToObject(left == right))
+	case code.OpNe:
+		return vm.push(boolToObject(left != right))
+	case code.OpGt:
+		return vm.push(boolToObject(left > right))
+	case code.OpLt:
+		return vm.push(boolToObject(left < right))
+	case code.OpGe:
+		return vm.push(boolToObject(left >= right))
+	case code.OpLe:
+		return vm.push(boolToObject(left <= right))
+	default:
+		return fmt.Errorf("unknown operator: %s", opName(op))
 	}
 }
 
 func (vm *Vm) execNegation() error {
 	right := vm.pop()
 
+	if right.Type() == object.FloatObj {
+		return vm.push(&object.Float{Value: -right.(*object.Float).Value})
+	}
+
 	if right.Type() != object.IntegerObj {
 		return fmt.Errorf("illegal operator - on type %s", right.Type())
 	}
@@ -463,6 +1066,9 @@ func (vm *Vm) execBoolNegation() error {
 			}
 		}
 
+	case *object.Float:
+		return vm.push(boolToObject(right.(*object.Float).Value == 0))
+
 	case *object.Boolean:
 		switch right {
 		case True:
@@ -486,6 +1092,8 @@ func (vm *Vm) execIdxExpression(left object.Object, idx object.Object) error {
 	switch {
 	case left.Type() == object.ArrayObj && idx.Type() == object.IntegerObj:
 		return vm.execArrayIdx(left, idx)
+	case left.Type() == object.ArrayObj:
+		return fmt.Errorf("array index must be Integer, got %s", idx.Type())
 	case left.Type() == object.DictObj:
 		return vm.execDictIdx(left, idx)
 	default:
@@ -511,7 +1119,7 @@ func (vm *Vm) execDictIdx(left object.Object, idx object.Object) error {
 	dictObj := left.(*object.Dict)
 	k, ok := idx.(object.Hashable)
 	if !ok {
-		return fmt.Errorf("illegal key: %v", idx)
+		return fmt.Errorf("illegal key: %s", idx.Type())
 	}
 
 	pair, ok := dictObj.Pairs[k.DictKey()]
@@ -522,6 +1130,101 @@ func (vm *Vm) execDictIdx(left object.Object, idx object.Object) error {
 	return vm.push(pair.Value)
 }
 
+// set element of array or dictionary, leaving the value on the stack
+func (vm *Vm) execSetIdx(left object.Object, idx object.Object, val object.Object) error {
+	switch left := left.(type) {
+	case *object.Array:
+		i, ok := idx.(*object.Integer)
+		if !ok {
+			return fmt.Errorf("array index must be Integer, got %s", idx.Type())
+		}
+		if i.Value < 0 || i.Value >= int64(len(left.Elements)) {
+			return fmt.Errorf("index out of range: %d with length %d", i.Value, len(left.Elements))
+		}
+		left.Elements[i.Value] = val
+
+	case *object.Dict:
+		k, ok := idx.(object.Hashable)
+		if !ok {
+			return fmt.Errorf("illegal key: %s", idx.Type())
+		}
+		left.Pairs[k.DictKey()] = object.DictPair{Key: idx, Value: val}
+
+	default:
+		return fmt.Errorf("index assignment unavailable for type %v", left.Type())
+	}
+
+	return vm.push(val)
+}
+
+// push the first n elements of an array, padded with nulls, followed by an
+// array of the remaining elements when rest is set
+func (vm *Vm) unpackArray(obj object.Object, n int, rest bool) error {
+	arr, ok := obj.(*object.Array)
+	if !ok {
+		return fmt.Errorf("cannot destructure %s as an array", obj.Type())
+	}
+
+	for i := 0; i < n; i++ {
+		el := object.Object(Null)
+		if i < len(arr.Elements) {
+			el = arr.Elements[i]
+		}
+		if err := vm.push(el); err != nil {
+			return err
+		}
+	}
+
+	if !rest {
+		return nil
+	}
+	elements := []object.Object{}
+	if len(arr.Elements) > n {
+		elements = append(elements, arr.Elements[n:]...)
+	}
+	return vm.push(&object.Array{Elements: elements})
+}
+
+// push the value of each key in a dict, or null when it is missing
+func (vm *Vm) unpackDict(obj object.Object, keys []object.Object) error {
+	dict, ok := obj.(*object.Dict)
+	if !ok {
+		return fmt.Errorf("cannot destructure %s as a dict", obj.Type())
+	}
+
+	for _, key := range keys {
+		val := object.Object(Null)
+		if k, ok := key.(object.Hashable); ok {
+			if pair, ok := dict.Pairs[k.DictKey()]; ok {
+				val = pair.Value
+			}
+		}
+		if err := vm.push(val); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// reports whether obj is a dict holding all of the keys
+func hasKeys(obj object.Object, keys []object.Object) bool {
+	dict, ok := obj.(*object.Dict)
+	if !ok {
+		return false
+	}
+
+	for _, key := range keys {
+		k, ok := key.(object.Hashable)
+		if !ok {
+			return false
+		}
+		if _, ok := dict.Pairs[k.DictKey()]; !ok {
+			return false
+		}
+	}
+	return true
+}
+
 func (vm *Vm) buildArray(start int, end int) object.Object {
 	elem := make([]object.Object, end-start)
 
@@ -532,6 +1235,17 @@ func (vm *Vm) buildArray(start int, end int) object.Object {
 	return &object.Array{Elements: elem}
 }
 
+// joins the display forms of the values, as shown by puts
+func (vm *Vm) buildString(start int, end int) object.Object {
+	var out strings.Builder
+
+	for i := start; i < end; i++ {
+		out.WriteString(vm.stack[i].Inspect())
+	}
+
+	return &object.String{Value: out.String()}
+}
+
 // returns Dict, or error if the key is not hashable
 func (vm *Vm) buildDict(startIndex int, endIndex int) (object.Object, error) {
 	dictPairs := make(map[object.DictKey]object.DictPair)
@@ -553,6 +1267,28 @@ func (vm *Vm) buildDict(startIndex int, endIndex int) (object.Object, error) {
 	return &object.Dict{Pairs: dictPairs}, nil
 }
 
+// error reading an export a module left unset, returning before it, as
+// named by the constant idx
+func (vm *Vm) exportError(idx int) error {
+	if idx < len(vm.constants) {
+		if name, ok := vm.constants[idx].(*object.String); ok {
+			if mod, export, ok := strings.Cut(name.Value, "."); ok {
+				return fmt.Errorf("module %s has no export %s", mod, export)
+			}
+		}
+	}
+	return fmt.Errorf("constant %d does not name an export", idx)
+}
+
+// returns the stack slot of local idx of the executing frame
+func (vm *Vm) local(idx int) (*object.Object, error) {
+	frame := vm.currentFrame()
+	if idx >= frame.fn.Fn.LocalVarCount {
+		return nil, fmt.Errorf("local %d out of range", idx)
+	}
+	return &vm.stack[frame.basePtr+idx], nil
+}
+
 // return the executing frame
 func (vm *Vm) currentFrame() *Frame {
 	return vm.frames[vm.frameIndex-1]
@@ -573,6 +1309,14 @@ func (vm *Vm) popFrame() *Frame {
 func (vm *Vm) callBIn(fn *object.Builtin, argNum int) error {
 	args := vm.stack[vm.sp-argNum : vm.sp]
 	res := fn.Fn(args...)
+	vm.sp = vm.sp - argNum - 1 // drop the args and the builtin itself
+
+	if errObj, ok := res.(*object.Error); ok {
+		return errObj
+	}
+	if exit, ok := res.(*object.Exit); ok {
+		return exit
+	}
 
 	if res != nil {
 		err := vm.push(res)
@@ -589,6 +1333,9 @@ func (vm *Vm) callBIn(fn *object.Builtin, argNum int) error {
 }
 
 func (vm *Vm) pushClosure(idx int, numFree int) error {
+	if idx >= len(vm.constants) {
+		return fmt.Errorf("constant %d out of range", idx)
+	}
 	cst := vm.constants[idx]
 	fn, ok := cst.(*object.CompFn)
 
@@ -596,9 +1343,15 @@ func (vm *Vm) pushClosure(idx int, numFree int) error {
 		return fmt.Errorf("not a function: %+v", cst)
 	}
 
-	free := make([]object.Object, numFree)
+	free := make([]*object.Cell, numFree)
 	for i := 0; i < numFree; i++ {
-		free[i] = vm.stack[vm.sp-numFree+i]
+		// captures are cells, except self references which are never assigned
+		captured := vm.stack[vm.sp-numFree+i]
+		cell, ok := captured.(*object.Cell)
+		if !ok {
+			cell = &object.Cell{Value: captured}
+		}
+		free[i] = cell
 	}
 	vm.sp -= numFree
 
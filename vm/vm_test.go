@@ -1,10 +1,14 @@
 package vm
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"strings"
 	"testing"
 
 	"crabscript.rs/ast"
+	"crabscript.rs/code"
 	"crabscript.rs/compiler"
 	"crabscript.rs/lexer"
 	"crabscript.rs/object"
@@ -35,11 +39,58 @@ func TestIntArithmetic(t *testing.T) {
 		{"-10", -10},
 		{"-50 + 100 + -50", 0},
 		{"(5 + 10 * 2 + 15 / 3) * 2 + -10", 50},
+		{"7 % 3", 1},
+		{"-7 % 3", -1},
+		{"2 ** 10", 1024},
+		{"2 ** 3 ** 2", 512},
+		{"-2 ** 2", -4},
+		{"(-2) ** 3", -8},
+		{"2 ** 0", 1},
+		{"2 ** -1", 0.5},
+		{"6 & 3", 2},
+		{"6 | 3", 7},
+		{"6 ^ 3", 5},
+		{"1 << 4", 16},
+		{"-16 >> 2", -4},
+		{"1 | 2 ^ 3 & 4 << 1", 3},
+		{"17 / 5 * 5 + 17 % 5", 17},
 	}
 
 	runVmTests(t, tests)
 }
 
+func TestFloatArithmetic(t *testing.T) {
+	tests := []vmTestCase{
+		{"1.5", 1.5},
+		{"-1.5", -1.5},
+		{"1.5 + 1.5", 3.0},
+		{"1 / 2.0", 0.5},
+		{"7 / 2", 3},
+		{"3 * 0.5 - 1", 0.5},
+		{"2.5e-1 * 4", 1.0},
+		{"float(1) / 4", 0.25},
+		{"int(7.9 / 2)", 3},
+		{"1.5 < 2", true},
+		{"2 > 1.5", true},
+		{"1.0 == 1", true},
+		{"1 != 1.5", true},
+		{"7.5 % 2", 1.5},
+		{"2.0 ** 3", 8.0},
+		{"4 ** 0.5", 2.0},
+		{"1.5 <= 1.5", true},
+		{"2 >= 2.5", false},
+		{"!0.0", true},
+		{"{1.5: 1}[1.5]", 1},
+		{"int(-9.2e18)", -9200000000000000000},
+	}
+
+	runVmTests(t, tests)
+
+	runVmErrTests(t, []vmTestCase{
+		{"1.5 & 2.0", "1:5: unknown float operator: OpBitAnd (OpBitAnd in <main>)"},
+	})
+}
+
 func TestBooleanExpressions(t *testing.T) {
 	tests := []vmTestCase{
 		{"true", true},
@@ -61,6 +112,14 @@ func TestBooleanExpressions(t *testing.T) {
 		{"(1 < 2) == false", false},
 		{"(1 > 2) == true", false},
 		{"(1 > 2) == false", true},
+		{"1 <= 2", true},
+		{"2 <= 2", true},
+		{"3 <= 2", false},
+		{"1 >= 2", false},
+		{"2 >= 2", true},
+		{"3 >= 2", true},
+		{"5 & 1 == 1", true},
+		{"1 + 1 <= 2 == 4 >= 2 * 2", true},
 		{"!(if (false) { 5; })", true},
 	}
 	runVmTests(t, tests)
@@ -82,13 +141,142 @@ func TestConditionals(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestLogicalOperators(t *testing.T) {
+	tests := []vmTestCase{
+		{"true && true", true},
+		{"true && false", false},
+		{"false || true", true},
+		{"false || false", false},
+		{"1 < 2 && 2 < 3", true},
+		{"1 && 2", 2},
+		{"0 || 2", 0},
+		{"if (false) { 1 } || 3", 3},
+		{"if (false) { 1 } && 3", Null},
+		{"let n = 0; let f = fn() { n += 1; true }; false && f(); n", 0},
+		{"let n = 0; let f = fn() { n += 1; true }; true || f(); n", 0},
+		{"let n = 0; let f = fn() { n += 1; true }; true && f() && f(); n", 2},
+		{"let n = 0; let f = fn() { n += 1; false }; f() || f() || true", true},
+	}
+	runVmTests(t, tests)
+}
+
+func TestPipeExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(3)", 7},
+		{"let double = fn(x) { x * 2 }; 3 |> double |> double", 12},
+		{"let add = fn(a, b) { a + b }; 1 + 2 |> add(4) |> add(5)", 12},
+		{"[1, 2, 3] |> push(4) |> len", 4},
+		{"2 |> fn(x) { x * x }", 4},
+		{"let f = fn(x) { x |> fn(y) { y + 1 } }; 1 |> f", 2},
+	}
+	runVmTests(t, tests)
+}
+
 func TestGlobalLetStatements(t *testing.T) {
 	tests := []vmTestCase{
 		{"let one = 1; one", 1},
 		{"let one = 1; let two = 2; one + two", 3},
 		{"let one = 1; let two = one + one; one + two", 3},
+		{"let user_id = 0x10; let v2 = 1_000; user_id + v2", 1016},
+	}
+	runVmTests(t, tests)
+}
+
+func TestDestructuringLetStatements(t *testing.T) {
+	tests := []vmTestCase{
+		{"let [a, b] = [1, 2]; a * 10 + b", 12},
+		{"let [a, b] = [1]; b", Null},
+		{"let [a] = [1, 2, 3]; a", 1},
+		{"let [a, ...rest] = [1, 2, 3]; rest", []int{2, 3}},
+		{"let [a, b, ...rest] = [1]; rest", []int{}},
+		{`let {name, age} = {"name": "crab", "age": 3}; age`, 3},
+		{`let {name, missing} = {"name": "crab"}; missing`, Null},
+		{"let a = 1; let b = 2; let [a, b] = [b, a]; a * 10 + b", 21},
+		{"let f = fn(pair) { let [x, y] = pair; x - y }; f([5, 3])", 2},
+		{"let f = fn(d) { let {k} = d; fn() { k } }; f({\"k\": 7})()", 7},
+	}
+	runVmTests(t, tests)
+
+	errTests := []vmTestCase{
+		{"let [a, b] = 1", "1:1: cannot destructure Integer as an array (OpUnpkArr in <main>)"},
+		{"let {a} = [1]", "1:1: cannot destructure Array as a dict (OpUnpkDct in <main>)"},
+	}
+	runVmErrTests(t, errTests)
+}
+
+func TestMatchExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"match (2) { 1 => 10, 2 => 20, _ => 30 }", 20},
+		{"match (7) { 1 => 10, _ => 30 }", 30},
+		{"match (2.0) { 2 => 1 }", 1},
+		{`match ("b") { "a" => 1, "b" => 2 }`, 2},
+		{"match (if (false) { 1 }) { false => 1, _ => 2 }", 2},
+		{"match (-1) { -1 => 1 }", 1},
+		{"match ([1, 2]) { [a] => a, [a, b] => a * 10 + b }", 12},
+		{"match ([1, 2, 3]) { [1, ...rest] => rest }", []int{2, 3}},
+		{"match ([1]) { [_, _, ..._] => 1, [x, ...rest] => rest }", []int{}},
+		{`match ({"k": [1, 5], "n": 2}) { {"k": [_, v], n} => v * n }`, 10},
+		{`match ({"a": 1}) { {b} => 1, {a} => a + 1 }`, 2},
+		{"match (5) { n if n > 9 => 1, n if n > 4 => 2, _ => 3 }", 2},
+		{"match ([3]) { [x] if x > 3 => 1, [x] => x }", 3},
+		{"match (1) { [] => 1, {} => 2, 1 => 3 }", 3},
+		{"let f = fn(x) { match (x) { [h, ...t] => h + f(t), [] => 0 } }; f([1, 2, 3])", 6},
+		{"match (match (1) { 1 => [2] }) { [x] => match (x) { 2 => 4 } }", 4},
+		{"let n = 0; match (1) { n => n }; n", 0},
+		{"let y = 1; match (5) { y => y * 2 }", 10},
+		{"let y = 1; match (5) { y => y * 2 }; y", 1},
+		{"let x = 0; match ([3]) { [x] if x > 3 => 1, _ => 2 }; x", 0},
+		{"let f = match (2) { n => fn() { n } }; f()", 2},
+		{"let f = fn(x) { let n = 0; match (x) { n => n }; n }; f(4)", 0},
+	}
+	runVmTests(t, tests)
+
+	errTests := []vmTestCase{
+		{"match (3) { 1 => 1, 2 => 2 }", "1:1: no match arm for 3 (OpNoMatch in <main>)"},
+		{`match ([1, "a"]) { [] => 1 }`, "1:1: no match arm for [1, a] (OpNoMatch in <main>)"},
+	}
+	runVmErrTests(t, errTests)
+}
+
+func TestTryExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{`try { 1 } catch (e) { 2 }`, 1},
+		{`try { throw "x"; 1 } catch (e) { 2 }`, 2},
+		{`1 + try { throw "x" } catch (e) { 10 }`, 11},
+		{`try { throw "boom" } catch (e) { e["message"] }`, "boom"},
+		{`try { throw "boom" } catch (e) { e["kind"] }`, "Error"},
+		{`try { throw {"kind": "Bad", "message": "no", "code": 7} } catch (e) { e["kind"] + e["message"] + str(e["code"]) }`, "Badno7"},
+		{`try { len(1) } catch (e) { e["kind"] + ": " + e["message"] }`, "RuntimeError: argument to `len` not supported, got Integer"},
+		{`try { 1 / 0 } catch (e) { e["message"] }`, "division by zero"},
+		{`let f = fn() { throw "deep" }; let g = fn() { f() }; try { g() } catch (e) { len(e["trace"]) }`, 3},
+		{`try { throw 1 } catch (e) { e["trace"][0] }`, "1:7"},
+		{`try { try { throw "a" } catch (e) { throw e } } catch (e) { e["trace"][0] }`, "1:13"},
+		{`try { try { throw "a" } finally { 1 } } catch (e) { e["message"] }`, "a"},
+		{`try { try { throw "a" } catch (e) { throw "b" } finally { 1 } } catch (e) { e["message"] }`, "b"},
+		{`try { try { 1 } finally { throw "c" } } catch (e) { e["message"] }`, "c"},
+		{`let log = []; try { push(log, 1) } finally { push(log, 2) }; 3`, 3},
+		{`let f = fn() { try { return 1 } finally { return 2 } }; f()`, 2},
+		{`let f = fn() { try { throw "x" } finally { return 3 } }; f()`, 3},
+		{`let n = 0; let i = 0; while (i < 5) { i = i + 1; try { if (i == 2) { continue }; if (i == 4) { break } } finally { n = n + i } }; n`, 10},
+		{`let f = fn(xs) { for (x in xs) { try { if (x > 1) { return x } } finally { x * 2 } } }; f([1, 2, 3])`, 2},
+		{`try { let x = 1 } catch (e) { 2 }`, Null},
+		// the catch block has a scope of its own
+		{`let e = "outer"; try { throw "x" } catch (e) { e["message"] }`, "x"},
+		{`let e = "outer"; try { throw "x" } catch (e) { 1 }; e`, "outer"},
+		{`let f = fn() { let e = 1; try { throw 2 } catch (e) { e["message"] }; e }; f()`, 1},
+		{`let e = 1; let f = try { throw "x" } catch (e) { fn() { e["message"] } }; f() + str(e)`, "x1"},
+		{`let n = 0; try { throw 1 } catch (e) { n = 5 }; n`, 5},
+		{`let e = 1; try { try { throw 2 } catch (e) { throw 3 } } catch (err) { str(e) + err["message"] }`, "13"},
 	}
 	runVmTests(t, tests)
+
+	errTests := []vmTestCase{
+		{`throw "oops"`, "1:1: oops (OpThrow in <main>)"},
+		{`throw {"message": "bad"}`, "1:1: bad (OpThrow in <main>)"},
+		{`let f = fn() { throw "in f" }; try { f() } finally { 1 }`, "1:16: in f (OpThrow in f)"},
+		{`try { throw 1 } catch (e) { throw e["message"] + "!" }`, "1:29: 1! (OpThrow in <main>)"},
+	}
+	runVmErrTests(t, errTests)
 }
 
 func TestStringExpressions(t *testing.T) {
@@ -96,6 +284,14 @@ func TestStringExpressions(t *testing.T) {
 		{`"monkey"`, "monkey"},
 		{`"mon" + "key"`, "monkey"},
 		{`"mon" + "key" + "banana"`, "monkeybanana"},
+		{`let name = "crab"; "hi ${name}!"`, "hi crab!"},
+		{`let items = [1, 2]; "has ${len(items)} items: ${items}"`, "has 2 items: [1, 2]"},
+		{`"${1 + 1}${true}${1.5}"`, "2true1.5"},
+		{`"${if (false) { 1 }}"`, "null"},
+		{`"a ${"b ${"c"} d"} e"`, "a b c d e"},
+		{`"${ {"k": "v"}["k"] }"`, "v"},
+		{`"cost: \${5}"`, "cost: ${5}"},
+		{`str(12) + str("ab") + str([1])`, "12ab[1]"},
 	}
 
 	runVmTests(t, tests)
@@ -151,6 +347,25 @@ func TestIndexLiterals(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestSliceExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"[1, 2, 3, 4][1:3]", []int{2, 3}},
+		{"[1, 2, 3, 4][:2]", []int{1, 2}},
+		{"[1, 2, 3, 4][2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][-2:]", []int{3, 4}},
+		{"[1, 2, 3, 4][::-1]", []int{4, 3, 2, 1}},
+		{"[1, 2, 3, 4][3:0:-2]", []int{4, 2}},
+		{"[1, 2, 3, 4][-100:100]", []int{1, 2, 3, 4}},
+		{"[1, 2, 3, 4][3:1]", []int{}},
+		{"let a = [1, 2, 3]; let b = a[:]; b[0] = 5; a[0]", 1},
+		{"let f = fn(xs, n) { xs[n:][0] }; f([1, 2, 3], -1)", 3},
+		{`"hello"[1:4]`, "ell"},
+		{`"héllo🦀"[-2:]`, "o🦀"},
+		{`"héllo"[::-1]`, "olléh"},
+	}
+	runVmTests(t, tests)
+}
+
 func TestCallFnNoArgs(t *testing.T) {
 	tests := []vmTestCase{
 		{
@@ -400,63 +615,100 @@ func TestCallFnWithWrongArgs(t *testing.T) {
 	tests := []vmTestCase{
 		{
 			input:    `fn() { 1; }(1);`,
-			expected: `wrong number of arguments: want 0 got 1`,
+			expected: `1:12: wrong number of arguments: want 0 got 1 (OpCall in <main>)`,
 		},
 		{
 			input:    `fn(a) { a; }();`,
-			expected: `wrong number of arguments: want 1 got 0`,
+			expected: `1:13: wrong number of arguments: want 1 got 0 (OpCall in <main>)`,
 		},
 		{
 			input:    `fn(a, b) { a + b; }(1);`,
-			expected: `wrong number of arguments: want 2 got 1`,
+			expected: `1:20: wrong number of arguments: want 2 got 1 (OpCall in <main>)`,
 		},
 	}
 	runVmErrTests(t, tests)
 }
 
-func TestBuiltinFns(t *testing.T) {
+func TestFnParameters(t *testing.T) {
 	tests := []vmTestCase{
-		{`len([])`, 0},
-		{`len("four")`, 4},
-		{`len(1)`,
+		{"let f = fn(x, y = 10) { x + y }; f(1)", 11},
+		{"let f = fn(x, y = 10) { x + y }; f(1, 2)", 3},
+		{"let f = fn(x, y = x * 2) { y }; f(4)", 8},
+		{"let n = 0; let f = fn(x = n) { x }; n = 5; f()", 5},
+		{"let f = fn(first, ...rest) { rest }; f(1, 2, 3)", []int{2, 3}},
+		{"let f = fn(first, ...rest) { rest }; f(1)", []int{}},
+		{"let f = fn(...rest) { rest[1] }; f(1, 2)", 2},
+		{"let f = fn(a, b) { a - b }; f(b: 1, a: 5)", 4},
+		{"let f = fn(a, b = 2, c = 3) { a * 100 + b * 10 + c }; f(1, c: 5)", 125},
+		{"let f = fn(a, b = 2, ...r) { let s = a + b; s + len(r) }; f(1, 2, 3, 4)", 5},
+		{"let sub = fn(a, b) { a - b }; 10 |> sub(b: 3)", 7},
+		{"let f = fn(a, b = fn() { a }) { a = 2; b() }; f(1)", 2},
+		{"let f = fn(n, acc = 1) { if (n == 0) { return acc }; f(n - 1, acc: acc * n) }; f(5)", 120},
+	}
+	runVmTests(t, tests)
 
-			&object.Error{
-				Message: "argument to `len` not supported, got Integer",
-			},
+	errTests := []vmTestCase{
+		{"fn(a, b = 1) { a }(1, 2, 3)", "1:19: wrong number of arguments: want 1 to 2 got 3 (OpCall in <main>)"},
+		{"fn(a, ...r) { a }()", "1:18: wrong number of arguments: want at least 1 got 0 (OpCall in <main>)"},
+		{"fn(a, b) { a }(b: 1)", "1:15: missing argument a (OpCallKw in <main>)"},
+		{"fn(a) { a }(b: 1)", "1:12: unknown argument b (OpCallKw in <main>)"},
+		{"fn(a) { a }(1, a: 2)", "1:12: argument a given twice (OpCallKw in <main>)"},
+		{"fn(a = 1 + true) { a }()", "1:10: unsupported types for binary operation: Integer Boolean (OpAdd in <anonymous>)"},
+		{"len(x: 1)", "1:4: builtins do not take named arguments (OpCallKw in <main>)"},
+		{"1(x: 1)", "1:2: not a function or builtin: Integer (OpCallKw in <main>)"},
+	}
+	runVmErrTests(t, errTests)
+}
+
+func TestRuntimeErrorPositions(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input:    "let f = fn(a) { a };\nf();",
+			expected: `2:2: wrong number of arguments: want 1 got 0 (OpCall in <main>)`,
 		},
 		{
-			`len("one", "two")`,
-			&object.Error{
-				Message: "wrong number of arguments. got 2, want 1",
-			},
+			input:    "let g = fn() {\n  1 + \"a\"\n};\ng();",
+			expected: `2:5: unsupported types for binary operation: Integer String (OpAdd in g)`,
 		},
+	}
+	runVmErrTests(t, tests)
+}
+
+func TestBuiltinFns(t *testing.T) {
+	tests := []vmTestCase{
+		{`len([])`, 0},
+		{`len("four")`, 4},
 		{`len([1, 2, 3])`, 3},
 		{`len([])`, 0},
 		{`puts("hello", "world!")`, Null},
 		{`first([1, 2, 3])`, 1},
 		{`first([])`, Null},
-		{`first(1)`,
-			&object.Error{
-				Message: "argument to `first` is invalid, got Integer",
-			},
-		},
 		{`last([1, 2, 3])`, 3},
 		{`last([])`, Null},
-		{`last(1)`,
-			&object.Error{
-				Message: "argument to `last` is invalid, got Integer",
-			},
-		},
 		{`tail([1, 2, 3])`, []int{2, 3}},
 		{`tail([])`, Null},
 		{`push([], 1)`, []int{1}},
-		{`push(1, 1)`,
-			&object.Error{
-				Message: "argument to `push` must be Array, got Integer",
-			},
-		},
+		{`len(tail(push([1], 2)))`, 1},
+		{`let f = fn(a) { a * 2 }; f(first([21, 1]))`, 42},
 	}
 	runVmTests(t, tests)
+
+	// errors returned by builtins are raised, so they can be caught
+	errTests := []vmTestCase{
+		{`len(1)`, "1:4: argument to `len` not supported, got Integer (OpCall in <main>)"},
+		{`len("one", "two")`, "1:4: wrong number of arguments. got 2, want 1 (OpCall in <main>)"},
+		{`first(1)`, "1:6: argument to `first` is invalid, got Integer (OpCall in <main>)"},
+		{`last(1)`, "1:5: argument to `last` is invalid, got Integer (OpCall in <main>)"},
+		{`push(1, 1)`, "1:5: argument to `push` must be Array, got Integer (OpCall in <main>)"},
+		{`int(1e20)`, "1:4: cannot convert 1e+20 to Integer (OpCall in <main>)"},
+		{`int(-1e19)`, "1:4: cannot convert -1e+19 to Integer (OpCall in <main>)"},
+		{`exit("1")`, "1:5: argument to `exit` not supported, got String (OpCall in <main>)"},
+		{`exit(-1)`, "1:5: exit status -1 out of range 0 to 255 (OpCall in <main>)"},
+		// an exit stops the vm, catch and all
+		{`exit(); 1`, "exit status 0"},
+		{`let f = fn() { try { exit(3) } catch (e) { 4 } }; f(); 5`, "exit status 3"},
+	}
+	runVmErrTests(t, errTests)
 }
 
 func TestClosures(t *testing.T) {
@@ -559,6 +811,505 @@ func TestRecursiveFibonacci(t *testing.T) {
 	runVmTests(t, tests)
 }
 
+func TestRecursiveFns(t *testing.T) {
+	tests := []vmTestCase{
+		{
+			input: `
+        let countDown = fn(x) {
+            if (x == 0) {
+                return 0;
+            } else {
+                countDown(x - 1);
+            }
+        };
+        countDown(1);
+        `,
+			expected: 0,
+		},
+		{
+			input: `
+        let wrapper = fn() {
+            let countDown = fn(x) {
+                if (x == 0) {
+                    return 0;
+                } else {
+                    countDown(x - 1);
+                }
+            };
+            countDown(1);
+        };
+        wrapper();
+        `,
+			expected: 0,
+		},
+		{
+			input: `
+        let wrapper = fn() {
+            let countDown = fn(x) {
+                let step = fn() { countDown(x - 1) };
+                if (x == 0) { return 0; } else { step(); }
+            };
+            countDown(5);
+        };
+        wrapper();
+        `,
+			expected: 0,
+		},
+		{
+			input: `
+        let fib = fn() {
+            let go = fn(n) { if (n < 2) { n } else { go(n - 1) + go(n - 2) } };
+            go
+        };
+        fib()(10);
+        `,
+			expected: 55,
+		},
+	}
+	runVmTests(t, tests)
+}
+
+func TestAssignExpressions(t *testing.T) {
+	tests := []vmTestCase{
+		{"let a = 5; a = 6; a;", 6},
+		{"let a = 5; a = a + 1;", 6},
+		{"let a = 1; let b = 2; a = b = 3; a + b;", 6},
+		{"let a = 5; a += 2; a;", 7},
+		{"let a = 5; a -= 2; a;", 3},
+		{"let a = 5; a *= 2; a;", 10},
+		{"let a = 6; a /= 2; a;", 3},
+		{"let a = 1.5; a += 1; a;", 2.5},
+		{"let a = 7; a %= 4; a;", 3},
+		{"let a = 2; a **= 10; a;", 1024},
+		{"let a = 6; a &= 3; a;", 2},
+		{"let a = 6; a |= 3; a;", 7},
+		{"let a = 6; a ^= 3; a;", 5},
+		{"let a = 1; a <<= 4; a;", 16},
+		{"let a = -16; a >>= 2; a;", -4},
+		{"let a = [1, 2]; a[1] **= 3; a[1];", 8},
+		{"let f = fn() { let a = 12; a %= 5; a }; f();", 2},
+		{`let s = "crab"; s += "script"; s;`, "crabscript"},
+		{"let a = [1, 2, 3]; a[1] = 5; a[1];", 5},
+		{"let a = [1, 2, 3]; a[2] *= 10; a[2];", 30},
+		{`let d = {"k": 1}; d["k"] = 2; d["k"];`, 2},
+		{`let d = {}; d["new"] = 3; d["new"];`, 3},
+		{`let d = {"k": 1}; d["k"] += 1; d["k"];`, 2},
+		{"let a = 1; let set = fn() { a = 2; }; set(); a;", 2},
+		{"let f = fn() { let a = 1; a = a * 5; a }; f();", 5},
+		{"let a = 1; let f = fn(a) { a = 10; a }; f(0) + a;", 11},
+		{"let a = [1]; let b = a; b[0] = 2; a[0];", 2},
+	}
+	runVmTests(t, tests)
+}
+
+func TestMutableClosures(t *testing.T) {
+	tests := []vmTestCase{
+		{"let f = fn() { let a = 1; let g = fn() { a += 1; }; g(); g(); a }; f();", 3},
+		{
+			input: `
+			let counter = fn() { let n = 0; fn() { n += 1; n } };
+			let c = counter();
+			c(); c(); c();`,
+			expected: 3,
+		},
+		{
+			// separate calls get separate cells
+			input: `
+			let counter = fn() { let n = 0; fn() { n += 1; n } };
+			let a = counter();
+			let b = counter();
+			a(); a(); b();`,
+			expected: 1,
+		},
+		{
+			input: `
+			let make = fn() {
+				let n = 0;
+				[fn() { n += 1 }, fn() { n }]
+			};
+			let pair = make();
+			pair[0](); pair[0]();
+			pair[1]();`,
+			expected: 2,
+		},
+		{
+			// captured through an intermediate closure
+			input: `
+			let outer = fn() {
+				let total = 0;
+				let middle = fn() { fn(x) { total += x } };
+				let add = middle();
+				add(3); add(4);
+				total
+			};
+			outer();`,
+			expected: 7,
+		},
+		{
+			// captured parameters
+			input: `
+			let acc = fn(sum) { fn(x) { sum += x; sum } };
+			let a = acc(10);
+			a(1); a(2);`,
+			expected: 13,
+		},
+		{
+			// a recursive fn sharing state with its caller
+			input: `
+			let count = fn(n) {
+				let calls = 0;
+				let go = fn(i) { calls += 1; if (i > 0) { go(i - 1) } };
+				go(n);
+				calls
+			};
+			count(4);`,
+			expected: 5,
+		},
+	}
+	runVmTests(t, tests)
+}
+
+func TestLoops(t *testing.T) {
+	tests := []vmTestCase{
+		{"let i = 0; while (i < 5) { i += 1; } i;", 5},
+		{"let i = 0; while (false) { i += 1; } i;", 0},
+		{"let i = 0\nwhile (i < 5) {\n  i += 1\n}\ni", 5},
+		{"let f = fn() {\n  for (x in [1, 2]) {\n    return\n  }\n  1\n}\nf()", Null},
+		{"let i = 0; while (true) { i += 1; if (i == 3) { break; } } i;", 3},
+		{
+			"let i = 0; let n = 0; while (i < 10) { i += 1; if (i > 5) { continue; } n += 1; } n;",
+			5,
+		},
+		{"let sum = 0; for (x in [1, 2, 3]) { sum += x; } sum;", 6},
+		{"let n = 0; for (x in []) { n += 1; } n;", 0},
+		{`let s = ""; for (c in "héllo") { s = c + s; } s;`, "olléh"},
+		{`let s = ""; for (k in {"b": 2, "a": 1, "c": 3}) { s += k; } s;`, "abc"},
+		{"let keys = 0; for (k in {2: 0, 1: 0, 3: 0}) { keys = keys * 10 + k; } keys;", 123},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { break; } sum += x; } sum;", 3},
+		{"let sum = 0; for (x in [1, 2, 3, 4]) { if (x == 3) { continue; } sum += x; } sum;", 7},
+		{
+			`let n = 0;
+			for (x in [1, 2, 3]) { for (y in [1, 2, 3]) { if (y == 2) { break; } n += 1; } }
+			n;`,
+			3,
+		},
+		{"let f = fn() { for (x in [1, 2, 3]) { if (x == 2) { return x * 10; } } }; f();", 20},
+		{"let f = fn() { let i = 0; while (true) { i += 1; if (i == 4) { return i; } } }; f();", 4},
+		{"let f = fn(xs) { let n = 0; for (x in xs) { n += x; } n }; f([1, 2]) + f([3]);", 6},
+		{"let xs = [1, 2]; for (x in xs) { xs = push(xs, x); } len(xs);", 4},
+		{"let i = 0; while (i < 100000) { i += 1; } i;", 100000},
+		{"for (x in [1, 2]) { } x;", 2},
+		{
+			// the stack is balanced however the loop is left
+			`let f = fn() {
+				let n = 0;
+				for (x in [1, 2, 3]) { for (y in [1, 2]) { if (y == 2) { break; } n += x; } }
+				n
+			};
+			f() + f();`,
+			12,
+		},
+	}
+	runVmTests(t, tests)
+}
+
+func TestStatementBlocksAreNull(t *testing.T) {
+	tests := []vmTestCase{
+		{"fn() { let a = 1; }()", Null},
+		{"fn() { while (false) { } }()", Null},
+		{"fn() { for (x in [1]) { x } }()", Null},
+		{"if (true) { let a = 1; }", Null},
+		{"let a = if (true) { let b = 1; }; a", Null},
+		// the last value popped, shown by the repl, is not the loop's
+		// condition or iterator
+		{"let i = 0; while (i < 2) { i += 1; }", Null},
+		{"while (true) { break; }", Null},
+		{"for (x in [1, 2]) { }", Null},
+		{"for (x in [1, 2]) { break; }", Null},
+	}
+	runVmTests(t, tests)
+}
+
+func TestRunSerialisedBytecode(t *testing.T) {
+	input := `
+let newAdder = fn(a) { fn(b) { a + b } };
+let greeting = "crab" + "script";
+len(greeting) + newAdder(1)(2);
+`
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	var buf bytes.Buffer
+	if _, err := comp.Bytecode().WriteTo(&buf); err != nil {
+		t.Fatalf("write error: %s", err)
+	}
+
+	bytecode, err := compiler.ReadBytecode(&buf)
+	if err != nil {
+		t.Fatalf("read error: %s", err)
+	}
+
+	vm := New(bytecode)
+	if err := vm.Run(); err != nil {
+		t.Fatalf("vm error: %s", err)
+	}
+	testExpectedObj(t, 13, vm.LastPoppedStackElem())
+}
+
+func TestRuntimeErrors(t *testing.T) {
+	tests := []vmTestCase{
+		{"1 / 0", "1:3: division by zero (OpDiv in <main>)"},
+		{"let f = fn(a) { 10 / a }; f(0)", "1:20: division by zero (OpDiv in f)"},
+		{"1 > true", "1:3: unsupported types for comparison: Integer Boolean (OpGt in <main>)"},
+		{`"a" < "b"`, `1:5: unsupported types for comparison: String String (OpLt in <main>)`},
+		{`"a" >= "b"`, `1:5: unsupported types for comparison: String String (OpGe in <main>)`},
+		{"7 % 0", "1:3: division by zero (OpMod in <main>)"},
+		{"1 << -1", "1:3: negative shift count: -1 (OpShl in <main>)"},
+		{"1(2)", "1:2: not a function or builtin: Integer (OpCall in <main>)"},
+		{`[1, 2]["a"]`, "1:7: array index must be Integer, got String (OpIdx in <main>)"},
+		{`let d = {}; d[fn() {}]`, "1:14: illegal key: ClosureObj (OpIdx in <main>)"},
+		{`{fn() {}: 1}`, "1:1: unhashable key ClosureObj (OpDict in <main>)"},
+		{`-"a"`, "1:1: illegal operator - on type String (OpNeg in <main>)"},
+		{`!"a"`, "1:1: illegal operator ! for type: String (OpBang in <main>)"},
+		{"let f = fn() { f() }; f()", "1:17: stack overflow: more than 2048 nested calls (OpCall in f)"},
+		{"let a = [1]; a[1] = 2", "1:19: index out of range: 1 with length 1 (OpSetIdx in <main>)"},
+		{`let a = [1]; a["x"] = 2`, "1:21: array index must be Integer, got String (OpSetIdx in <main>)"},
+		{"let a = 1; a[0] = 2", "1:17: index assignment unavailable for type Integer (OpSetIdx in <main>)"},
+		{`let d = {}; d[fn() {}] = 1`, "1:24: illegal key: ClosureObj (OpSetIdx in <main>)"},
+		{"for (x in 5) { }", "1:1: cannot iterate over Integer (OpIter in <main>)"},
+		{"[1, 2][::0]", "1:7: slice step cannot be zero (OpSlice in <main>)"},
+		{`[1, 2]["a":]`, "1:7: slice indices must be Integer, got String (OpSlice in <main>)"},
+		{"1[0:1]", "1:2: slice operator not supported: Integer (OpSlice in <main>)"},
+	}
+	runVmErrTests(t, tests)
+}
+
+func TestRuntimeErrorStackTrace(t *testing.T) {
+	input := `let div = fn(a, b) { a / b };
+let half = fn(x) { div(x, 0) };
+let run = fn() { [1, half(4)] };
+run();`
+
+	comp := compiler.New()
+	if err := comp.Compile(parse(input)); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	err := New(comp.Bytecode()).Run()
+
+	var rtErr *RuntimeError
+	if !errors.As(err, &rtErr) {
+		t.Fatalf("expected *RuntimeError, got %T (%v)", err, err)
+	}
+
+	expected := []struct {
+		fn  string
+		pos string
+	}{
+		{"div", "1:24"},
+		{"half", "2:23"},
+		{"run", "3:26"},
+		{"<main>", "4:4"},
+	}
+
+	if len(rtErr.Stack) != len(expected) {
+		t.Fatalf("wrong stack depth, got %d want %d\n%s", len(rtErr.Stack), len(expected), rtErr.StackTrace())
+	}
+	for i, want := range expected {
+		got := rtErr.Stack[i]
+		if got.Fn != want.fn || got.Pos.String() != want.pos {
+			t.Errorf("frame %d wrong, got %s at %s want %s at %s", i, got.Fn, got.Pos, want.fn, want.pos)
+		}
+	}
+
+	trace := rtErr.StackTrace()
+	if !strings.HasPrefix(trace, "runtime error: division by zero\n\ndiv(...)\n\t1:24 +0x") {
+		t.Errorf("wrong stack trace format, got\n%s", trace)
+	}
+}
+
+func TestRuntimeErrorIsStructured(t *testing.T) {
+	comp := compiler.New()
+	if err := comp.Compile(parse("let x = 1;\nx / 0")); err != nil {
+		t.Fatalf("compiler error: %s", err)
+	}
+
+	err := New(comp.Bytecode()).Run()
+
+	var rtErr *RuntimeError
+	if !errors.As(err, &rtErr) {
+		t.Fatalf("expected *RuntimeError, got %T (%v)", err, err)
+	}
+	if rtErr.Op != code.OpDiv {
+		t.Errorf("wrong opcode, got %d want %d", rtErr.Op, code.OpDiv)
+	}
+	if rtErr.Fn != "<main>" {
+		t.Errorf("wrong function, got %q", rtErr.Fn)
+	}
+	if rtErr.Pos.Line != 2 || rtErr.Pos.Column != 3 {
+		t.Errorf("wrong position, got %s", rtErr.Pos)
+	}
+	if rtErr.Err.Error() != "division by zero" {
+		t.Errorf("wrong cause, got %q", rtErr.Err)
+	}
+}
+
+func TestComparisonsAcrossTypes(t *testing.T) {
+	tests := []vmTestCase{
+		{"1 == true", false},
+		{"1 != true", true},
+		{`"a" == 1`, false},
+		{"true == true", true},
+		{"return 5; 10", 5},
+	}
+	runVmTests(t, tests)
+}
+
+// hostile programs may fail but must never take down the host
+var hostilePrograms = []string{
+	"1 / 0",
+	"1 == true",
+	"true > 1",
+	"let x = x; x",
+	"[][0][0]",
+	"{}[{}]",
+	`len(len)`,
+	`first(first)`,
+	`push(1)`,
+	"fn() { return; }()",
+	"return 1; 2",
+	"return;",
+	"fn(a, b) { a }(1, 2, 3, 4, 5)",
+	"let f = fn(n) { f(n + 1) + 1 }; f(0)",
+	"let f = fn(a) { fn() { a + f(a) } }; f(1)()",
+	"if (1 / 0) { 1 }",
+	"-fn() {}",
+	"!fn() {}",
+	"[1, 2] + [3]",
+	"{1: 2} + {3: 4}",
+	"puts(puts)",
+	"1.5 / 0",
+	"int(1.0 / 0)",
+	`int("9223372036854775808")`,
+	"-9223372036854775807 - 1 / -1",
+	"let a = [1]; a[0] = a; a[0][0][0] += 1",
+	"let f = fn(x) { let g = fn() { x = g }; g(); x }; f(1)()",
+	"for (x in fn() {}) { }",
+	"for (x in [1]) { for (y in x) { } }",
+	"let f = fn() { for (x in [1]) { return x; } }; [f(), f(), f()]",
+	"while (true) { if (true) { break; } else { continue; } }",
+}
+
+func TestHostileProgramsDoNotPanic(t *testing.T) {
+	for _, input := range hostilePrograms {
+		runWithoutPanic(t, input)
+	}
+}
+
+func FuzzRun(f *testing.F) {
+	for _, input := range hostilePrograms {
+		f.Add(input)
+	}
+
+	f.Fuzz(func(t *testing.T, input string) {
+		runWithoutPanic(t, input)
+	})
+}
+
+// compiles and runs input, failing the test only if the vm panics
+func runWithoutPanic(t *testing.T, input string) {
+	t.Helper()
+
+	p := parser.New(lexer.New(input))
+	program := p.ParseProgram()
+	if len(p.Errors()) > 0 {
+		return
+	}
+
+	comp := compiler.New()
+	if err := comp.Compile(program); err != nil {
+		return
+	}
+
+	defer func() {
+		if r := recover(); r != nil {
+			t.Errorf("vm panicked on %q: %v", input, r)
+		}
+	}()
+	_ = New(comp.Bytecode()).Run()
+}
+
+func TestMalformedBytecode(t *testing.T) {
+	tests := []struct {
+		name     string
+		bytecode *compiler.Bytecode
+		expected string
+	}{
+		{
+			"unknown opcode",
+			&compiler.Bytecode{Instructions: code.Instructions{255}},
+			"unknown opcode 255",
+		},
+		{
+			"constant out of range",
+			&compiler.Bytecode{Instructions: code.Make(code.OpConst, 3)},
+			"constant 3 out of range",
+		},
+		{
+			"stack underflow",
+			&compiler.Bytecode{Instructions: code.Make(code.OpAdd)},
+			"stack underflow: OpAdd takes 2 values, stack holds 0",
+		},
+		{
+			"undefined global",
+			&compiler.Bytecode{Instructions: code.Make(code.OpGetGbl, 7)},
+			"global 7 is not defined",
+		},
+		{
+			"truncated operand",
+			&compiler.Bytecode{Instructions: code.Instructions{byte(code.OpConst)}},
+			"truncated operands of OpConst",
+		},
+		{
+			"truncated second operand",
+			&compiler.Bytecode{Instructions: code.Make(code.OpClosure, 0, 0)[:3]},
+			"truncated operands of OpClosure",
+		},
+		{
+			"underflow of a counted operand",
+			&compiler.Bytecode{Instructions: append(code.Make(code.OpTrue), code.Make(code.OpArray, 2)...)},
+			"stack underflow: OpArray takes 2 values, stack holds 1",
+		},
+		{
+			"local out of range",
+			&compiler.Bytecode{Instructions: code.Make(code.OpGetLcl, 65535)},
+			"local 65535 out of range",
+		},
+	}
+
+	for _, tt := range tests {
+		var err error
+		func() {
+			defer func() {
+				if r := recover(); r != nil {
+					t.Errorf("%s: vm panicked: %v", tt.name, r)
+				}
+			}()
+			err = New(tt.bytecode).Run()
+		}()
+
+		if err == nil {
+			t.Errorf("%s: expected error", tt.name)
+			continue
+		}
+		if !strings.Contains(err.Error(), tt.expected) {
+			t.Errorf("%s: wrong error, got %q want %q", tt.name, err, tt.expected)
+		}
+	}
+}
+
 func runVmErrTests(t *testing.T, tests []vmTestCase) {
 	t.Helper()
 
@@ -587,6 +1338,11 @@ func testExpectedObj(t *testing.T, expected interface{}, actual object.Object) {
 		if err != nil {
 			t.Errorf("testIntegerObject failed: %s", err)
 		}
+	case float64:
+		err := testFloatObject(expected, actual)
+		if err != nil {
+			t.Errorf("testFloatObject failed: %s", err)
+		}
 	case bool:
 		err := testBooleanObject(bool(expected), actual)
 		if err != nil {
@@ -684,6 +1440,17 @@ func parse(input string) *ast.Program {
 	return p.ParseProgram()
 }
 
+func testFloatObject(expected float64, actual object.Object) error {
+	result, ok := actual.(*object.Float)
+	if !ok {
+		return fmt.Errorf("object is not Float. got %T (%+v)", actual, actual)
+	}
+	if result.Value != expected {
+		return fmt.Errorf("object has wrong value. got %v, want %v", result.Value, expected)
+	}
+	return nil
+}
+
 func testIntegerObject(expected int64, actual object.Object) error {
 	result, ok := actual.(*object.Integer)
 	if !ok {
@@ -694,3 +1461,24 @@ func testIntegerObject(expected int64, actual object.Object) error {
 	}
 	return nil
 }
+
+func TestImports(t *testing.T) {
+	tests := []vmTestCase{
+		{`import "testdata/greet.crab" as g; g.greet("bob")`, "hello bob"},
+		{`import "testdata/greet.crab" as g; g.x`, 3},
+		{`import "testdata/greet.crab" as g; g.first + len(g.rest)`, 3},
+		{`let hidden = 10; import "testdata/greet.crab" as g; g.x + hidden`, 13},
+		{`import "testdata/counter.crab" as c; import "testdata/greet.crab" as g; g.greet("a"); g.greet("b"); c.count()`, 2},
+		{`import "testdata/counter.crab" as a; import "testdata/counter.crab" as b; a.bump(); b.count()`, 1},
+		{`import "testdata/counter.crab" as c; let f = fn() { c.bump() }; f(); f()`, 2},
+		{`import "testdata/early.crab" as e; e.a`, 1},
+		{`import "testdata/greet.crab" as g; let g = 1; g`, 1},
+	}
+
+	runVmTests(t, tests)
+
+	runVmErrTests(t, []vmTestCase{
+		{`import "testdata/fails.crab" as f; f.f()`, "testdata/fails.crab:1:25: division by zero (OpDiv in f)"},
+		{`import "testdata/early.crab" as e; e.b`, "1:37: module e has no export b (OpGetExp in <main>)"},
+	})
+}